@@ -0,0 +1,10 @@
+package main
+
+// Gateway adapters living outside package gateway register themselves with
+// gateway.Register when imported; config.yaml selects them by type.
+import (
+	_ "Payment-Gateway/internal/gateway/grpcgw"
+	_ "Payment-Gateway/internal/gateway/iso8583"
+	_ "Payment-Gateway/internal/gateway/plugin"
+	_ "Payment-Gateway/internal/gateway/rest"
+)
@@ -4,16 +4,21 @@ import (
 	"Payment-Gateway/internal/cache"
 	cfg "Payment-Gateway/internal/config"
 	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/iso8583"
 	"Payment-Gateway/internal/handler"
 	"Payment-Gateway/internal/middleware"
 	"Payment-Gateway/internal/repository"
+	"Payment-Gateway/internal/routing"
 	"Payment-Gateway/internal/service"
+	"Payment-Gateway/internal/webhook"
 	"Payment-Gateway/pkg/logger"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
@@ -21,13 +26,6 @@ import (
 	"go.uber.org/zap"
 )
 
-// Registry for gateway constructors
-var gatewayRegistry = map[string]func(url, gatewayName string, cfg *cfg.ResilienceConfig) gateway.PaymentGateway{
-	"gatewayA": gateway.NewGatewayA,
-	"gatewayB": gateway.NewGatewayB,
-	// Add more gateway constructors here as needed
-}
-
 func initializeMiddlewares(router *mux.Router) {
 	router.Use(middleware.ContextMiddleware)
 	router.Use(middleware.TimeoutMiddleware(10 * time.Second))
@@ -36,6 +34,57 @@ func initializeMiddlewares(router *mux.Router) {
 	router.Use(middleware.RecoveryMiddleware)
 }
 
+// gatewayClosers are the gateways holding resources to release on shutdown,
+// such as plugin processes.
+var gatewayClosers []io.Closer
+
+// trackGateway remembers gw for closeGateways when it needs closing.
+func trackGateway(gw gateway.PaymentGateway) {
+	if c, ok := gw.(io.Closer); ok {
+		gatewayClosers = append(gatewayClosers, c)
+	}
+}
+
+func closeGateways() {
+	for _, c := range gatewayClosers {
+		if err := c.Close(); err != nil {
+			logger.GetLogger().Error("Gateway close error", zap.Error(err))
+		}
+	}
+	gatewayClosers = nil
+}
+
+// httpDispatcher delivers webhooks until shutdownWebhooks drains it; nil when
+// webhooks are disabled.
+var httpDispatcher *webhook.HTTPDispatcher
+
+func shutdownWebhooks(ctx context.Context) {
+	if httpDispatcher == nil {
+		return
+	}
+	if err := httpDispatcher.Shutdown(ctx); err != nil {
+		logger.GetLogger().Error("Webhook dispatcher shutdown error", zap.Error(err))
+	}
+	httpDispatcher = nil
+}
+
+// stopHealthChecks ends the gateway probes started by initializeHandlers.
+var stopHealthChecks = func() {}
+
+// stopRateLimiter ends the rate limiter's janitor started by NewRouter.
+var stopRateLimiter = func() {}
+
+// teardown releases what NewRouter started, dependents first: queued webhooks
+// are delivered, then the probes stop before the gateways they call are closed.
+func teardown(ctx context.Context) {
+	stopRateLimiter()
+	stopRateLimiter = func() {}
+	shutdownWebhooks(ctx)
+	stopHealthChecks()
+	stopHealthChecks = func() {}
+	closeGateways()
+}
+
 func initializeHandlers() (*handler.Handlers, error) {
 	cfg := cfg.GetConfig()
 
@@ -48,62 +97,330 @@ func initializeHandlers() (*handler.Handlers, error) {
 	bufferSize := cfg.WorkerPool.BufferSize
 	workerPool := service.NewWorkerPool(numWorkers, bufferSize)
 
+	// Gateways are pooled in name order so routing tie-breaks are stable
+	gatewayNames := make([]string, 0, len(cfg.Gateways))
+	for name := range cfg.Gateways {
+		gatewayNames = append(gatewayNames, name)
+	}
+	sort.Strings(gatewayNames)
+
 	var gateways []gateway.PaymentGateway
-	for name, gwCfg := range cfg.Gateways {
-		if gwCfg.Enabled {
-			if constructor, ok := gatewayRegistry[name]; ok {
-				gateways = append(gateways, constructor(gwCfg.URL, gwCfg.Name, &cfg.Resilience))
+	healthMonitor := service.NewHealthMonitor()
+	gatewayMaintenance := service.NewGatewayMaintenance()
+	routingOpts := routing.Options{
+		Stats:    routing.NewStats(time.Duration(cfg.Routing.Adaptive.WindowSeconds)*time.Second, cfg.Routing.Adaptive.MaxSamples),
+		Adaptive: cfg.Routing.Adaptive,
+	}
+	canaries := make(map[gateway.PaymentGateway]*routing.Canary)
+	var shadowTraffic *service.ShadowTraffic
+	for _, name := range gatewayNames {
+		gwCfg := cfg.Gateways[name]
+		if !gwCfg.Enabled {
+			continue
+		}
+		// Shadow gateways only receive mirrored traffic at their sandbox
+		if gwCfg.Shadow.Enabled {
+			if gwCfg.Canary.Enabled {
+				return nil, fmt.Errorf("gateway %s: canary and shadow are mutually exclusive", name)
 			}
+			sandboxCfg := gwCfg
+			sandboxCfg.URL = gwCfg.Shadow.URL
+			gw, err := gateway.New(name, sandboxCfg)
+			if err != nil {
+				return nil, err
+			}
+			trackGateway(gw)
+			if shadowTraffic == nil {
+				shadowTraffic = service.NewShadowTraffic()
+			}
+			shadowTraffic.Add(name, gw, gwCfg.Shadow)
+			continue
+		}
+		gw, err := gateway.New(name, gwCfg)
+		if err != nil {
+			return nil, err
 		}
+		trackGateway(gw)
+		gateways = append(gateways, gw)
+		routingOpts.AddGateway(name, gw, gwCfg.Routing)
+		if gwCfg.Canary.Enabled {
+			canaries[gw] = routing.NewCanary(name, gwCfg.Canary)
+		}
+		if err := gatewayMaintenance.Add(name, gw, gwCfg.Maintenance); err != nil {
+			return nil, err
+		}
+		if gwCfg.HealthCheck.Enabled {
+			probe := gateway.ProbeFor(gw, &http.Client{}, gwCfg.URL+gwCfg.HealthCheck.Path)
+			healthMonitor.Watch(name, gw, probe, gwCfg.HealthCheck)
+		}
+	}
+	stopHealthChecks = healthMonitor.Start()
+
+	routingTable, err := initializeRouting(routingOpts)
+	if err != nil {
+		return nil, err
 	}
+	routingTable.Canaries = canaries
+
+	deliveryRepo := repository.NewInMemoryWebhookDeliveryRepository()
+	var webhookDispatcher webhook.Dispatcher
+	if cfg.Webhooks.Enabled {
+		httpDispatcher = webhook.NewHTTPDispatcher(&cfg.Webhooks, cfg.Merchants, deliveryRepo)
+		webhookDispatcher = httpDispatcher
+	}
+	webhookDeliveryService := service.NewWebhookDeliveryService(deliveryRepo, webhookDispatcher)
 
 	transactionRepo := repository.NewInMemoryTransactionRepository()
-	gatewayPool := service.NewGatewayPool(gateways)
+	gatewayPool := service.NewGatewayPool(gateways, healthMonitor, gatewayMaintenance, routingTable)
 	gatewayTimeout := time.Duration(cfg.Static.GatewayTimeoutSeconds) * time.Second
-	transactionService := service.NewTransactionService(transactionRepo, gatewayPool, workerPool, gatewayTimeout)
+	transactionService := service.NewTransactionService(transactionRepo, gatewayPool, workerPool, gatewayTimeout, webhookDispatcher, cfg.Failover, shadowTraffic)
+
+	callbackTolerance := time.Duration(cfg.Callback.TimestampToleranceSeconds) * time.Second
+	gatewayACallbackService := service.NewGatewayACallbackService(transactionService, callbackTolerance)
+	gatewayBCallbackService := service.NewGatewayBCallbackService(transactionService, callbackTolerance)
 
-	gatewayACallbackService := service.NewGatewayACallbackService(transactionService)
-	gatewayBCallbackService := service.NewGatewayBCallbackService(transactionService)
+	merchantIDs := make([]string, 0, len(cfg.Merchants))
+	for id := range cfg.Merchants {
+		merchantIDs = append(merchantIDs, id)
+	}
+	transactionValidator, err := handler.NewTransactionValidator(cfg.Validation, merchantIDs)
+	if err != nil {
+		return nil, err
+	}
 
 	return &handler.Handlers{
-		TransactionHandler: handler.NewTransactionHandler(transactionService),
+		TransactionHandler: handler.NewTransactionHandler(transactionService, transactionValidator),
 		GatewayACallback:   handler.NewGatewayACallback(gatewayACallbackService, callbackCache),
 		GatewayBCallback:   handler.NewGatewayBCallback(gatewayBCallbackService, callbackCache),
+		WebhookAdmin:       handler.NewWebhookAdminHandler(webhookDeliveryService),
+		RoutingAdmin:       handler.NewRoutingAdminHandler(gatewayPool, shadowTraffic, transactionValidator),
+		GatewayAdmin:       handler.NewGatewayAdminHandler(gatewayMaintenance),
 	}, nil
 }
 
+// initializeRouting builds one instance of each configured strategy, maps
+// every merchant to its own strategy or routing.defaultStrategy and builds the
+// routing rules in configuration order.
+func initializeRouting(opts routing.Options) (routing.Table, error) {
+	cfg := cfg.GetConfig()
+	strategies := make(map[string]routing.Strategy)
+	strategyNamed := func(name string) (routing.Strategy, error) {
+		if s, ok := strategies[name]; ok {
+			return s, nil
+		}
+		s, err := routing.NewStrategy(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		strategies[name] = s
+		return s, nil
+	}
+
+	table := routing.Table{Merchants: make(map[string]routing.Strategy), Stats: opts.Stats}
+	var err error
+	if table.Default, err = strategyNamed(cfg.Routing.DefaultStrategy); err != nil {
+		return routing.Table{}, err
+	}
+	for id, m := range cfg.Merchants {
+		if m.RoutingStrategy == "" {
+			continue
+		}
+		if table.Merchants[id], err = strategyNamed(m.RoutingStrategy); err != nil {
+			return routing.Table{}, fmt.Errorf("merchant %s: %w", id, err)
+		}
+	}
+	for _, ruleCfg := range cfg.Routing.Rules {
+		rule, err := routing.NewRule(ruleCfg, opts)
+		if err != nil {
+			return routing.Table{}, err
+		}
+		table.Rules = append(table.Rules, rule)
+	}
+	return table, nil
+}
+
+// initializeCallbackAllowlists builds a source IP allowlist per configured gateway.
+func initializeCallbackAllowlists() (map[string]*middleware.IPAllowlist, error) {
+	cfg := cfg.GetConfig()
+	allowlists := make(map[string]*middleware.IPAllowlist, len(cfg.Gateways))
+	for name, gwCfg := range cfg.Gateways {
+		allowlist, err := middleware.NewIPAllowlist(name, gwCfg.AllowedCIDRs, cfg.TrustedProxies)
+		if err != nil {
+			return nil, err
+		}
+		allowlists[name] = allowlist
+	}
+	return allowlists, nil
+}
+
+// initializeRateLimiter returns nil when rate limiting is disabled.
+func initializeRateLimiter() (*middleware.RateLimiter, error) {
+	cfg := cfg.GetConfig()
+	if !cfg.RateLimit.Enabled {
+		return nil, nil
+	}
+	return middleware.NewRateLimiter(
+		toRateLimitRule(cfg.RateLimit.PerAPIKey),
+		toRateLimitRule(cfg.RateLimit.PerAccount),
+		toRateLimitRule(cfg.RateLimit.PerIP),
+		cfg.TrustedProxies,
+		time.Duration(cfg.RateLimit.IdleTTLSeconds)*time.Second,
+		cfg.RateLimit.MaxBuckets,
+		cfg.Validation.MaxBodyBytes,
+	)
+}
+
+func toRateLimitRule(r cfg.RateLimitRule) middleware.RateLimitRule {
+	return middleware.RateLimitRule{RequestsPerSecond: r.RequestsPerSecond, Burst: r.Burst}
+}
+
+// initializeAPIVersions returns the policy of every enabled API version, sorted
+// by version. Without an apiVersions section only static.apiVersion is mounted.
+func initializeAPIVersions() ([]*middleware.VersionPolicy, error) {
+	cfg := cfg.GetConfig()
+	var names []string
+	for name, v := range cfg.APIVersions {
+		if v.Enabled {
+			names = append(names, name)
+		}
+	}
+	if len(cfg.APIVersions) == 0 {
+		names = append(names, cfg.Static.APIVersion)
+	}
+	sort.Strings(names)
+
+	policies := make([]*middleware.VersionPolicy, 0, len(names))
+	for _, name := range names {
+		if _, ok := versionRoutes[name]; !ok {
+			return nil, fmt.Errorf("api version %s has no registered routes", name)
+		}
+		v := cfg.APIVersions[name]
+		policy, err := middleware.NewVersionPolicy(name, v.DeprecatedAt, v.SunsetAt, v.Link)
+		if err != nil {
+			return nil, err
+		}
+		if policy.Deprecated() {
+			logger.GetLogger().Warn("Serving deprecated API version",
+				zap.String("version", name), zap.String("sunset_at", v.SunsetAt))
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// initializeUnversionedRoutes returns the policy of the unversioned aliases,
+// or nil when they are disabled. The aliased version must be mounted.
+func initializeUnversionedRoutes(apiVersions []*middleware.VersionPolicy) (*middleware.VersionPolicy, error) {
+	u := cfg.GetConfig().Unversioned
+	if !u.Enabled {
+		return nil, nil
+	}
+	if u.DeprecatedAt == "" {
+		return nil, fmt.Errorf("unversionedRoutes: deprecatedAt is required")
+	}
+	mounted := false
+	for _, policy := range apiVersions {
+		mounted = mounted || policy.Version() == u.AliasOf
+	}
+	if !mounted {
+		return nil, fmt.Errorf("unversionedRoutes: aliasOf %q is not an enabled api version", u.AliasOf)
+	}
+	policy, err := middleware.NewVersionPolicy(u.AliasOf, u.DeprecatedAt, u.SunsetAt, u.Link)
+	if err != nil {
+		return nil, fmt.Errorf("unversionedRoutes: %w", err)
+	}
+	logger.GetLogger().Warn("Serving deprecated unversioned routes",
+		zap.String("alias_of", u.AliasOf), zap.String("sunset_at", u.SunsetAt))
+	return policy, nil
+}
+
+// routeGuards holds middlewares applied to specific groups of routes.
+type routeGuards struct {
+	apiVersions        []*middleware.VersionPolicy
+	unversioned        *middleware.VersionPolicy // nil when the aliases are disabled
+	callbackAllowlists map[string]*middleware.IPAllowlist
+	rateLimiter        *middleware.RateLimiter
+}
+
 func NewRouter() (http.Handler, error) {
 	router := mux.NewRouter()
 	handlers, err := initializeHandlers()
 	if err != nil {
 		return nil, err
 	}
+	allowlists, err := initializeCallbackAllowlists()
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter, err := initializeRateLimiter()
+	if err != nil {
+		return nil, err
+	}
+	stopRateLimiter = rateLimiter.Close
+	apiVersions, err := initializeAPIVersions()
+	if err != nil {
+		return nil, err
+	}
+	unversioned, err := initializeUnversionedRoutes(apiVersions)
+	if err != nil {
+		return nil, err
+	}
 
 	initializeMiddlewares(router)
-	setupRoutes(router, handlers)
+	setupRoutes(router, handlers, routeGuards{
+		apiVersions:        apiVersions,
+		unversioned:        unversioned,
+		callbackAllowlists: allowlists,
+		rateLimiter:        rateLimiter,
+	})
 
 	return router, nil
 }
 
+// startSimulators starts the configured provider simulators and returns a
+// function stopping them. A simulator that cannot listen is logged and
+// skipped: it only stands in for a provider in local runs.
+func startSimulators(sims cfg.SimulatorsConfig) func() {
+	if sims.ISO8583.Address == "" {
+		return func() {}
+	}
+	sim := iso8583.NewSimulator(sims.ISO8583.LengthHeaderBytes)
+	addr, err := sim.Listen(sims.ISO8583.Address)
+	if err != nil {
+		logger.GetLogger().Error("ISO 8583 simulator not started", zap.String("address", sims.ISO8583.Address), zap.Error(err))
+		return func() {}
+	}
+	logger.GetLogger().Info("ISO 8583 simulator listening", zap.String("address", addr))
+	return func() { sim.Close() }
+}
+
 func StartServer() error {
 	cfg := cfg.GetConfig()
 	router, err := NewRouter()
 	if err != nil {
+		teardown(context.Background())
 		return err
 	}
 	addr := fmt.Sprintf("%s:%d", cfg.Static.Host, cfg.Static.Port)
 	srv := &http.Server{
 		Addr:    addr,
-		Handler: router,
+		Handler: serverHandler(router, cfg.Simulators),
 	}
 
 	defer logger.Sync()
+	stopSimulators := startSimulators(cfg.Simulators)
+	defer stopSimulators()
 	logger.GetLogger().Info("Starting server", zap.String("address", addr))
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	// Requests in flight may still queue webhooks and use gateways, so the
+	// rest is torn down once the server has stopped serving them. The
+	// simulators standing in for gateways are stopped last, on return.
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-quit
 		logger.GetLogger().Info("Shutdown signal received")
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -111,13 +428,18 @@ func StartServer() error {
 		if err := srv.Shutdown(ctx); err != nil {
 			logger.GetLogger().Error("Server shutdown error", zap.Error(err))
 		}
+		teardown(ctx)
 	}()
 
 	err = srv.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		logger.GetLogger().Error("Server failed", zap.Error(err))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		teardown(ctx)
 		return err
 	}
+	<-shutdownDone
 
 	logger.GetLogger().Info("Server exited gracefully")
 	return nil
@@ -0,0 +1,72 @@
+// Command mock-gateway-plugin is a gateway plugin for local runs: a mock
+// provider served over the plugin protocol, used by gatewayPlugin in
+// config.yaml. Like the other mocks it declines "nsf..." and "decline..."
+// accounts, leaves "pending..." accounts pending, reports "fault..."
+// accounts unavailable and approves the rest, and it repeats its first answer
+// for a known transaction ID.
+package main
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/plugin"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func main() {
+	err := plugin.Serve(func(params plugin.InitializeParams) (gateway.PaymentGateway, error) {
+		if params.Config["apiKey"] == "" {
+			return nil, errors.New("config.apiKey is required")
+		}
+		logger.GetLogger().Info("Mock gateway plugin initialized", zap.String("gateway", params.Gateway))
+		return &mockGateway{}, nil
+	})
+	if err != nil {
+		logger.GetLogger().Fatal("Mock gateway plugin failed", zap.Error(err))
+	}
+}
+
+type mockGateway struct {
+	payments sync.Map // transaction ID -> *gateway.PaymentResult
+}
+
+func (g *mockGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.process(req, "deposit")
+}
+
+func (g *mockGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.process(req, "withdrawal")
+}
+
+func (g *mockGateway) process(req gateway.PaymentRequest, operation string) (*gateway.PaymentResult, error) {
+	if req.TransactionID == "" || req.Account == "" || req.Amount <= 0 {
+		return nil, gateway.NotProcessed(apperrors.WithMessage(apperrors.ErrInvalidRequest, "transaction_id, account and a positive amount are required"))
+	}
+
+	result := &gateway.PaymentResult{GatewayRef: "PL-" + uuid.NewString()}
+	account := strings.ToLower(req.Account)
+	switch {
+	case strings.HasPrefix(account, "nsf"):
+		result.Outcome, result.Reason, result.GatewayCode = gateway.OutcomeDeclined, constants.ReasonInsufficientFunds, "NSF"
+		result.Message = "Mock plugin declined the " + operation + ": insufficient funds"
+	case strings.HasPrefix(account, "decline"):
+		result.Outcome, result.Reason, result.GatewayCode = gateway.OutcomeDeclined, constants.ReasonDoNotHonor, "DECLINED"
+		result.Message = "Mock plugin declined the " + operation
+	case strings.HasPrefix(account, "pending"):
+		result.Outcome, result.Message = gateway.OutcomePending, "Mock plugin is processing the "+operation
+	case strings.HasPrefix(account, "fault"):
+		return nil, gateway.NotProcessed(apperrors.WithMessage(apperrors.ErrGatewayNotAvailable, "mock plugin provider is unavailable"))
+	default:
+		result.Outcome, result.Message = gateway.OutcomeApproved, "Mock plugin approved the "+operation
+	}
+	first, _ := g.payments.LoadOrStore(req.TransactionID, result)
+	return first.(*gateway.PaymentResult), nil
+}
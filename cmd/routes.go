@@ -1,24 +1,109 @@
 package main
 
 import (
+	cfg "Payment-Gateway/internal/config"
 	"Payment-Gateway/internal/handler"
 	mockgateway "Payment-Gateway/internal/handler/mock_gateway"
+	"Payment-Gateway/internal/middleware"
+	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
-func setupRoutes(router *mux.Router, handlers *handler.Handlers) {
-	// Payment routes
-	router.HandleFunc("/deposit", handlers.TransactionHandler.Deposit).Methods("POST")
-	router.HandleFunc("/withdrawal", handlers.TransactionHandler.Withdrawal).Methods("POST")
+// versionRoutes registers the public routes of each API version. Versions are
+// mounted side by side under /<version> and may share handlers where the
+// behaviour is unchanged.
+var versionRoutes = map[string]func(router *mux.Router, handlers *handler.Handlers, guards routeGuards){
+	"v1": setupV1Routes,
+}
+
+func setupRoutes(router *mux.Router, handlers *handler.Handlers, guards routeGuards) {
+	// Public routes, one subrouter per enabled API version
+	for _, policy := range guards.apiVersions {
+		versioned := router.PathPrefix("/" + policy.Version()).Subrouter()
+		versioned.Use(policy.Middleware)
+		versionRoutes[policy.Version()](versioned, handlers, guards)
+	}
 
-	// Callback routes
-	router.HandleFunc("/callback/gateway-a", handlers.GatewayACallback.ServeHTTP).Methods("POST")
-	router.HandleFunc("/callback/gateway-b", handlers.GatewayBCallback.ServeHTTP).Methods("POST")
+	// Admin routes for webhook delivery inspection and replay
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminAuthMiddleware(cfg.GetConfig().Admin.APIKey))
+	admin.HandleFunc("/webhooks/deliveries", handlers.WebhookAdmin.ListDeliveries).Methods("GET")
+	admin.HandleFunc("/webhooks/deliveries/replay", handlers.WebhookAdmin.ReplayMatching).Methods("POST")
+	admin.HandleFunc("/webhooks/deliveries/{event_id}", handlers.WebhookAdmin.GetDelivery).Methods("GET")
+	admin.HandleFunc("/webhooks/deliveries/{event_id}/replay", handlers.WebhookAdmin.Replay).Methods("POST")
+	admin.HandleFunc("/routing/dry-run", handlers.RoutingAdmin.DryRun).Methods("POST")
+	admin.HandleFunc("/routing/canaries", handlers.RoutingAdmin.ListCanaries).Methods("GET")
+	admin.HandleFunc("/routing/canaries/{gateway}/reset", handlers.RoutingAdmin.ResetCanary).Methods("POST")
+	admin.HandleFunc("/routing/shadow", handlers.RoutingAdmin.ShadowReport).Methods("GET")
+	admin.HandleFunc("/gateways", handlers.GatewayAdmin.List).Methods("GET")
+	admin.HandleFunc("/gateways/{gateway}", handlers.GatewayAdmin.Get).Methods("GET")
+	admin.HandleFunc("/gateways/{gateway}/drain", handlers.GatewayAdmin.Drain).Methods("POST")
+	admin.HandleFunc("/gateways/{gateway}/disable", handlers.GatewayAdmin.Disable).Methods("POST")
+	admin.HandleFunc("/gateways/{gateway}/enable", handlers.GatewayAdmin.Enable).Methods("POST")
 
-	// Mock gateway simulation routes (match config base + /deposit or /withdrawal)
+	setupMockGatewayRoutes(router)
+
+	if guards.unversioned != nil {
+		setupUnversionedRoutes(router, guards.unversioned, handlers, guards)
+	}
+}
+
+// setupMockGatewayRoutes serves the mock providers at the urls config.yaml
+// gives their gateways: the url plus each endpoint's path and healthCheck.path.
+func setupMockGatewayRoutes(router *mux.Router) {
 	router.HandleFunc("/mock-gateway-a/deposit", mockgateway.GatewayAMockDepositHandler).Methods("POST")
 	router.HandleFunc("/mock-gateway-a/withdrawal", mockgateway.GatewayAMockWithdrawalHandler).Methods("POST")
 	router.HandleFunc("/mock-gateway-b/deposit", mockgateway.GatewayBMockDepositHandler).Methods("POST")
 	router.HandleFunc("/mock-gateway-b/withdrawal", mockgateway.GatewayBMockWithdrawalHandler).Methods("POST")
+	router.HandleFunc("/mock-gateway-rest/v2/payins", mockgateway.RESTMockPayinHandler).Methods("POST")
+	router.HandleFunc("/mock-gateway-rest/v2/payouts", mockgateway.RESTMockPayoutHandler).Methods("POST")
+	router.HandleFunc("/mock-gateway-a/health", mockgateway.HealthHandler).Methods("GET")
+	router.HandleFunc("/mock-gateway-b/health", mockgateway.HealthHandler).Methods("GET")
+	router.HandleFunc("/mock-gateway-rest/v2/health", mockgateway.HealthHandler).Methods("GET")
+}
+
+// setupUnversionedRoutes also serves the public routes of alias's version at
+// the root, where providers configured before versioning send callbacks. The
+// alias's policy marks every response deprecated.
+func setupUnversionedRoutes(router *mux.Router, alias *middleware.VersionPolicy, handlers *handler.Handlers, guards routeGuards) {
+	unversioned := router.NewRoute().Subrouter()
+	unversioned.Use(alias.Middleware)
+	versionRoutes[alias.Version()](unversioned, handlers, guards)
+}
+
+// serverHandler wraps router with the simulators served on the server's own
+// port.
+func serverHandler(router http.Handler, sims cfg.SimulatorsConfig) http.Handler {
+	if !sims.GRPC.Enabled {
+		return router
+	}
+	return withMockGRPC(router)
+}
+
+// withMockGRPC serves gRPC calls with the mock gRPC provider and every other
+// request with next. h2c lets gRPC clients speak cleartext HTTP/2 to the
+// server's port, so the mock needs no listener of its own.
+func withMockGRPC(next http.Handler) http.Handler {
+	grpcServer := mockgateway.NewGRPCServer()
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
+
+func setupV1Routes(router *mux.Router, handlers *handler.Handlers, guards routeGuards) {
+	// Payment routes, rate limited per API key, account and source IP
+	router.Handle("/deposit", guards.rateLimiter.Middleware(http.HandlerFunc(handlers.TransactionHandler.Deposit))).Methods("POST")
+	router.Handle("/withdrawal", guards.rateLimiter.Middleware(http.HandlerFunc(handlers.TransactionHandler.Withdrawal))).Methods("POST")
+
+	// Callback routes, restricted to each gateway's source IP allowlist
+	router.Handle("/callback/gateway-a", guards.callbackAllowlists["gatewayA"].Middleware(http.HandlerFunc(handlers.GatewayACallback.ServeHTTP))).Methods("POST")
+	router.Handle("/callback/gateway-b", guards.callbackAllowlists["gatewayB"].Middleware(http.HandlerFunc(handlers.GatewayBCallback.ServeHTTP))).Methods("POST")
 }
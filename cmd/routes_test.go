@@ -0,0 +1,141 @@
+package main
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/handler"
+	"Payment-Gateway/internal/middleware"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/pkg/mocks"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetupUnversionedRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTransaction := mocks.NewMockTransaction(ctrl)
+	mockTransaction.EXPECT().CreateAndProcessDeposit(gomock.Any()).Return(&models.Transaction{ID: "tx1", Status: constants.StatusSuccess}, nil)
+	mockCallback := mocks.NewMockCallback(ctrl)
+	mockCallback.EXPECT().HandleCallback(gomock.Any()).Return(&dtos.HandleCallbackResponse{Status: "success"}, nil)
+	mockCache := mocks.NewMockCacheStore(ctrl)
+	mockCache.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, false)
+	mockCache.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	validator, err := handler.NewTransactionValidator(config.ValidationConfig{}, nil)
+	if err != nil {
+		t.Fatalf("NewTransactionValidator: %v", err)
+	}
+	handlers := &handler.Handlers{
+		TransactionHandler: handler.NewTransactionHandler(mockTransaction, validator),
+		GatewayACallback:   handler.NewGatewayACallback(mockCallback, mockCache),
+	}
+	alias, err := middleware.NewVersionPolicy("v1", "2026-10-19T00:00:00Z", "2027-04-19T00:00:00Z", "https://example.com/migrate")
+	if err != nil {
+		t.Fatalf("NewVersionPolicy: %v", err)
+	}
+	router := mux.NewRouter()
+	setupUnversionedRoutes(router, alias, handlers, routeGuards{})
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/deposit", `{"account_id":"acc1","amount":10}`},
+		{"/callback/gateway-a", `{"transaction_id":"tx1","gateway_ref":"ref1","status":"success","amount":10,"currency":"USD"}`},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", tt.path, w.Code, w.Body)
+		}
+		h := w.Header()
+		if h.Get("API-Version") != "v1" || h.Get("Deprecation") != "@1792368000" || h.Get("Sunset") == "" || len(h.Values("Link")) != 2 {
+			t.Errorf("%s: expected deprecation headers, got %v", tt.path, h)
+		}
+	}
+}
+
+func TestServerHandler_MockGRPCOnlyWhenEnabled(t *testing.T) {
+	router := mux.NewRouter()
+	for _, enabled := range []bool{false, true} {
+		srv := httptest.NewServer(serverHandler(router, config.SimulatorsConfig{GRPC: config.GRPCSimulatorConfig{Enabled: enabled}}))
+		conn, err := grpc.NewClient(strings.TrimPrefix(srv.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("grpc.NewClient: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+		cancel()
+		conn.Close()
+		srv.Close()
+		if enabled && err != nil {
+			t.Errorf("expected the mock gRPC provider to answer when enabled, got %v", err)
+		}
+		if !enabled && err == nil {
+			t.Error("expected no gRPC service on the server's port when the simulator is disabled")
+		}
+	}
+}
+
+// TestShippedGatewaysPassHealthChecks probes every gateway config.yaml points
+// at the server's own mock providers, enabled or not, as the health monitor
+// would once it is enabled.
+func TestShippedGatewaysPassHealthChecks(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "internal", "config", "config.yaml"))
+	if err != nil {
+		t.Fatalf("read config.yaml: %v", err)
+	}
+	var shipped config.Config
+	if err := yaml.Unmarshal(data, &shipped); err != nil {
+		t.Fatalf("parse config.yaml: %v", err)
+	}
+
+	router := mux.NewRouter()
+	setupMockGatewayRoutes(router)
+	srv := httptest.NewServer(serverHandler(router, config.SimulatorsConfig{GRPC: config.GRPCSimulatorConfig{Enabled: true}}))
+	defer srv.Close()
+	self := strings.TrimPrefix(srv.URL, "http://")
+
+	probed := 0
+	for name, gwCfg := range shipped.Gateways {
+		if !strings.Contains(gwCfg.URL, "{host}:{port}") || !gwCfg.HealthCheck.Enabled {
+			continue
+		}
+		gwCfg.URL = strings.ReplaceAll(gwCfg.URL, "{host}:{port}", self)
+		gw, err := gateway.New(name, gwCfg)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if err := gateway.ProbeFor(gw, srv.Client(), gwCfg.URL+gwCfg.HealthCheck.Path)(ctx); err != nil {
+			t.Errorf("%s: health check of %s failed: %v", name, gwCfg.URL+gwCfg.HealthCheck.Path, err)
+		}
+		cancel()
+		if c, ok := gw.(io.Closer); ok {
+			c.Close()
+		}
+		probed++
+	}
+	if probed == 0 {
+		t.Fatal("expected config.yaml to point gateways at the mock providers")
+	}
+}
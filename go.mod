@@ -9,7 +9,15 @@ require (
 	github.com/gorilla/mux v1.8.1
 	github.com/sony/gobreaker v1.0.0
 	go.uber.org/zap v1.27.0
+	golang.org/x/net v0.34.0
+	google.golang.org/grpc v1.71.1
+	google.golang.org/protobuf v1.36.4
 	gopkg.in/yaml.v3 v3.0.1
 )
 
-require go.uber.org/multierr v1.10.0 // indirect
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250115164207-1a7da9e5054f // indirect
+)
@@ -20,17 +20,95 @@ type CircuitBreakerConfig struct {
 }
 
 type ResilienceConfig struct {
-	HTTPTimeoutSeconds   int                  `yaml:"httpTimeoutSeconds"`
-	MaxRetries           int                  `yaml:"maxRetries"`
-	InitialBackoffMillis int                  `yaml:"initialBackoffMillis"`
-	MaxBackoffMillis     int                  `yaml:"maxBackoffMillis"`
-	CircuitBreaker       CircuitBreakerConfig `yaml:"circuitBreaker"`
+	HTTPTimeoutSeconds   int `yaml:"httpTimeoutSeconds"`
+	MaxRetries           int `yaml:"maxRetries"`
+	InitialBackoffMillis int `yaml:"initialBackoffMillis"`
+	MaxBackoffMillis     int `yaml:"maxBackoffMillis"`
+	// MaxConcurrent caps in-flight calls to one gateway; 0 means unlimited.
+	MaxConcurrent  int                  `yaml:"maxConcurrent"`
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuitBreaker"`
+}
+
+// HealthCheckConfig configures the active probe of one gateway. Zero values
+// fall back to the monitor's defaults.
+type HealthCheckConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	Path               string `yaml:"path"`
+	IntervalSeconds    int    `yaml:"intervalSeconds"`
+	TimeoutSeconds     int    `yaml:"timeoutSeconds"`
+	HealthyThreshold   int    `yaml:"healthyThreshold"`
+	UnhealthyThreshold int    `yaml:"unhealthyThreshold"`
+}
+
+// FeeSchedule is what a gateway charges per transaction.
+type FeeSchedule struct {
+	Fixed   float64 `yaml:"fixed"`
+	Percent float64 `yaml:"percent"`
+}
+
+// Fee returns the charge for a transaction of amount.
+func (f FeeSchedule) Fee(amount float64) float64 {
+	return f.Fixed + amount*f.Percent/100
+}
+
+// GatewayRoutingConfig holds the gateway's inputs to the routing strategies.
+type GatewayRoutingConfig struct {
+	Weight int         `yaml:"weight"`
+	Fees   FeeSchedule `yaml:"fees"`
 }
 
 type GatewayConfig struct {
+	// Type names the registered adapter that builds the gateway; it defaults
+	// to the gateway's key.
+	Type    string `yaml:"type,omitempty"`
 	URL     string `yaml:"url"`
 	Enabled bool   `yaml:"enabled"`
 	Name    string `yaml:"name,omitempty"` // Optional name for the gateway
+	// AllowedCIDRs lists the gateway's published egress ranges for callbacks; empty allows any source.
+	AllowedCIDRs []string `yaml:"allowedCIDRs,omitempty"`
+	// ResilienceOverrides holds the keys of the gateway's own resilience block;
+	// Resilience is the global block with those keys applied on top.
+	ResilienceOverrides yaml.Node            `yaml:"resilience,omitempty"`
+	Resilience          ResilienceConfig     `yaml:"-"`
+	HealthCheck         HealthCheckConfig    `yaml:"healthCheck,omitempty"`
+	Routing             GatewayRoutingConfig `yaml:"routing,omitempty"`
+	Canary              CanaryConfig         `yaml:"canary,omitempty"`
+	Shadow              ShadowConfig         `yaml:"shadow,omitempty"`
+	// Maintenance lists the provider's announced maintenance windows.
+	Maintenance []MaintenanceWindowConfig `yaml:"maintenance,omitempty"`
+	// Settings holds adapter-specific keys, decoded and validated by the
+	// adapter named by Type.
+	Settings yaml.Node `yaml:"settings,omitempty"`
+}
+
+// MaintenanceWindowConfig takes a gateway out of rotation between Start and
+// End, both RFC 3339 timestamps.
+type MaintenanceWindowConfig struct {
+	Start  string `yaml:"start"`
+	End    string `yaml:"end"`
+	Reason string `yaml:"reason"`
+}
+
+// CanaryConfig limits a newly added gateway to Percent of the transactions it
+// is eligible for, and rolls it back out of rotation once its error rate over
+// the window exceeds MaxErrorRate with at least MinSamples outcomes.
+type CanaryConfig struct {
+	Enabled       bool    `yaml:"enabled"`
+	Percent       float64 `yaml:"percent"`
+	MinSamples    int     `yaml:"minSamples"`
+	MaxErrorRate  float64 `yaml:"maxErrorRate"`
+	WindowSeconds int     `yaml:"windowSeconds"`
+}
+
+// ShadowConfig keeps a gateway out of rotation and instead mirrors Percent of
+// transactions to its sandbox at URL, comparing its answers with the real ones.
+type ShadowConfig struct {
+	Enabled bool    `yaml:"enabled"`
+	URL     string  `yaml:"url"`
+	Percent float64 `yaml:"percent"`
+	// MaxInFlight caps concurrent mirrored calls; samples beyond it are dropped.
+	MaxInFlight    int `yaml:"maxInFlight"`
+	TimeoutSeconds int `yaml:"timeoutSeconds"`
 }
 
 type CacheConfig struct {
@@ -38,6 +116,146 @@ type CacheConfig struct {
 	TTLSeconds                  int `yaml:"ttlSeconds"`
 }
 
+// CallbackConfig controls replay protection for inbound gateway callbacks.
+type CallbackConfig struct {
+	TimestampToleranceSeconds int `yaml:"timestampToleranceSeconds"`
+}
+
+type RateLimitRule struct {
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+}
+
+// RateLimitConfig sets token-bucket limits on the merchant API; a rule with a
+// zero rate disables that dimension. MaxBuckets caps the buckets kept in memory.
+type RateLimitConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	IdleTTLSeconds int           `yaml:"idleTTLSeconds"`
+	MaxBuckets     int           `yaml:"maxBuckets"`
+	PerAPIKey      RateLimitRule `yaml:"perAPIKey"`
+	PerAccount     RateLimitRule `yaml:"perAccount"`
+	PerIP          RateLimitRule `yaml:"perIP"`
+}
+
+// WebhookConfig controls outbound merchant webhook delivery.
+type WebhookConfig struct {
+	Enabled              bool `yaml:"enabled"`
+	NumWorkers           int  `yaml:"numWorkers"`
+	BufferSize           int  `yaml:"bufferSize"`
+	HTTPTimeoutSeconds   int  `yaml:"httpTimeoutSeconds"`
+	MaxRetries           int  `yaml:"maxRetries"`
+	InitialBackoffMillis int  `yaml:"initialBackoffMillis"`
+	MaxBackoffMillis     int  `yaml:"maxBackoffMillis"`
+}
+
+// SimulatorsConfig starts local stand-ins for providers that are not served
+// over HTTP by the mock gateway routes.
+type SimulatorsConfig struct {
+	ISO8583 ISO8583SimulatorConfig `yaml:"iso8583"`
+	GRPC    GRPCSimulatorConfig    `yaml:"grpc"`
+}
+
+// ISO8583SimulatorConfig runs the ISO 8583 acquirer simulator on Address; an
+// empty address disables it.
+type ISO8583SimulatorConfig struct {
+	Address           string `yaml:"address"`
+	LengthHeaderBytes int    `yaml:"lengthHeaderBytes"`
+}
+
+// GRPCSimulatorConfig serves the mock gRPC provider on the server's own port
+// when Enabled. It answers every gRPC request reaching that port.
+type GRPCSimulatorConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AdminConfig protects the operational /admin endpoints; an empty key disables them.
+type AdminConfig struct {
+	APIKey string `yaml:"apiKey"`
+}
+
+type MerchantConfig struct {
+	WebhookURL    string `yaml:"webhookURL"`
+	WebhookSecret string `yaml:"webhookSecret"`
+	// RoutingStrategy overrides routing.defaultStrategy for this merchant.
+	RoutingStrategy string `yaml:"routingStrategy,omitempty"`
+}
+
+// APIVersionConfig controls whether an API version is mounted and the
+// deprecation headers sent with it. Dates are RFC 3339.
+type APIVersionConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	DeprecatedAt string `yaml:"deprecatedAt"`
+	SunsetAt     string `yaml:"sunsetAt"`
+	Link         string `yaml:"link"`
+}
+
+// UnversionedRoutesConfig keeps the payment and callback routes of AliasOf
+// served without a version prefix, for clients and providers configured before
+// routes were versioned. DeprecatedAt is required; dates are RFC 3339.
+type UnversionedRoutesConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	AliasOf      string `yaml:"aliasOf"`
+	DeprecatedAt string `yaml:"deprecatedAt"`
+	SunsetAt     string `yaml:"sunsetAt"`
+	Link         string `yaml:"link"`
+}
+
+// ValidationConfig bounds merchant deposit and withdrawal requests.
+type ValidationConfig struct {
+	MaxBodyBytes   int64   `yaml:"maxBodyBytes"`
+	MinAmount      float64 `yaml:"minAmount"`
+	MaxAmount      float64 `yaml:"maxAmount"`
+	AmountDecimals int     `yaml:"amountDecimals"`
+	AccountPattern string  `yaml:"accountPattern"`
+}
+
+// AdaptiveRoutingConfig tunes the adaptive strategy and the outcome window it reads.
+type AdaptiveRoutingConfig struct {
+	WindowSeconds int     `yaml:"windowSeconds"`
+	MaxSamples    int     `yaml:"maxSamples"`
+	MinSamples    int     `yaml:"minSamples"`
+	RateTolerance float64 `yaml:"rateTolerance"`
+}
+
+// RoutingRuleMatch lists the conditions a transaction must meet for a rule
+// to apply. Empty conditions match any transaction; a zero MaxAmount means no
+// upper bound. Amount bounds are inclusive.
+type RoutingRuleMatch struct {
+	Types           []string `yaml:"types"`
+	Currencies      []string `yaml:"currencies"`
+	MinAmount       float64  `yaml:"minAmount"`
+	MaxAmount       float64  `yaml:"maxAmount"`
+	AccountPrefixes []string `yaml:"accountPrefixes"`
+	Countries       []string `yaml:"countries"`
+	Merchants       []string `yaml:"merchants"`
+}
+
+// RoutingRule restricts matching transactions to Gateways, named by their key
+// under gateways and listed in order of preference. Strategy, when set,
+// replaces the merchant's strategy for those transactions.
+type RoutingRule struct {
+	Name     string           `yaml:"name"`
+	Match    RoutingRuleMatch `yaml:"match"`
+	Gateways []string         `yaml:"gateways"`
+	Strategy string           `yaml:"strategy,omitempty"`
+}
+
+// RoutingConfig names the strategy used for merchants without their own and
+// the rules evaluated, in order, before any strategy runs.
+type RoutingConfig struct {
+	DefaultStrategy string                `yaml:"defaultStrategy"`
+	Adaptive        AdaptiveRoutingConfig `yaml:"adaptive"`
+	Rules           []RoutingRule         `yaml:"rules"`
+}
+
+// FailoverConfig controls re-sending a transaction to another gateway when
+// the first one certainly did not process it.
+type FailoverConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxAttempts caps the gateways tried per transaction, the first included.
+	MaxAttempts int `yaml:"maxAttempts"`
+}
+
 type WorkerPoolConfig struct {
 	NumWorkers int `yaml:"numWorkers"`
 	BufferSize int `yaml:"bufferSize"`
@@ -54,9 +272,22 @@ type Config struct {
 		Host                  string `yaml:"host"`
 		Port                  int    `yaml:"port"`
 	} `yaml:"static"`
-	Resilience ResilienceConfig `yaml:"resilience"`
-	Cache      CacheConfig      `yaml:"cache"`
-	WorkerPool WorkerPoolConfig `yaml:"workerPool"`
+	Resilience  ResilienceConfig            `yaml:"resilience"`
+	Failover    FailoverConfig              `yaml:"failover"`
+	Routing     RoutingConfig               `yaml:"routing"`
+	Cache       CacheConfig                 `yaml:"cache"`
+	WorkerPool  WorkerPoolConfig            `yaml:"workerPool"`
+	Callback    CallbackConfig              `yaml:"callback"`
+	Webhooks    WebhookConfig               `yaml:"webhooks"`
+	Merchants   map[string]MerchantConfig   `yaml:"merchants"`
+	Admin       AdminConfig                 `yaml:"admin"`
+	RateLimit   RateLimitConfig             `yaml:"rateLimit"`
+	Validation  ValidationConfig            `yaml:"validation"`
+	APIVersions map[string]APIVersionConfig `yaml:"apiVersions"`
+	Unversioned UnversionedRoutesConfig     `yaml:"unversionedRoutes"`
+	Simulators  SimulatorsConfig            `yaml:"simulators"`
+	// TrustedProxies lists CIDRs of proxies whose X-Forwarded-For header is honoured.
+	TrustedProxies []string `yaml:"trustedProxies"`
 }
 
 var (
@@ -75,17 +306,27 @@ func GetConfig() *Config {
 		if err := yaml.Unmarshal(data, cfg); err != nil {
 			log.Fatalf("failed to unmarshal config: %v", err)
 		}
-		// Dynamically update gateway URLs
+		// Dynamically update gateway URLs and layer per-gateway resilience overrides
 		host := cfg.Static.Host
 		port := cfg.Static.Port
 		for name, gw := range cfg.Gateways {
-			url := gw.URL
-			url = strings.ReplaceAll(url, "{host}", host)
-			url = strings.ReplaceAll(url, "{port}", fmt.Sprintf("%d", port))
-			gw.URL = url
+			gw.URL = expandURL(gw.URL, host, port)
+			gw.Shadow.URL = expandURL(gw.Shadow.URL, host, port)
+			gw.Resilience = cfg.Resilience
+			if !gw.ResilienceOverrides.IsZero() {
+				if err := gw.ResilienceOverrides.Decode(&gw.Resilience); err != nil {
+					log.Fatalf("failed to decode resilience overrides for gateway %s: %v", name, err)
+				}
+			}
 			cfg.Gateways[name] = gw
 		}
 		config = cfg
 	})
 	return config
 }
+
+// expandURL fills the {host} and {port} placeholders of a gateway URL.
+func expandURL(url, host string, port int) string {
+	url = strings.ReplaceAll(url, "{host}", host)
+	return strings.ReplaceAll(url, "{port}", fmt.Sprintf("%d", port))
+}
@@ -14,3 +14,50 @@ const (
 	TypeDeposit    TransactionType = "DEPOSIT"
 	TypeWithdrawal TransactionType = "WITHDRAWAL"
 )
+
+// DefaultMerchantID is used when a request does not name a merchant.
+const DefaultMerchantID = "default"
+
+// DefaultCurrency is used when a request does not name a currency.
+const DefaultCurrency = "USD"
+
+type WebhookEventType string
+
+const (
+	EventTransactionCreated       WebhookEventType = "transaction.created"
+	EventTransactionStatusChanged WebhookEventType = "transaction.status_changed"
+)
+
+// GatewayState is an operator's runtime setting for a gateway.
+type GatewayState string
+
+const (
+	GatewayEnabled  GatewayState = "enabled"
+	GatewayDraining GatewayState = "draining" // no new transactions; disabled once in-flight calls finish
+	GatewayDisabled GatewayState = "disabled"
+)
+
+type WebhookDeliveryState string
+
+const (
+	DeliveryPending      WebhookDeliveryState = "pending"
+	DeliveryDelivered    WebhookDeliveryState = "delivered"
+	DeliveryDeadLettered WebhookDeliveryState = "dead_lettered"
+)
+
+// ReasonCode is the gateway-independent reason a transaction did not succeed.
+type ReasonCode string
+
+const (
+	ReasonInsufficientFunds  ReasonCode = "insufficient_funds"
+	ReasonInvalidAccount     ReasonCode = "invalid_account"
+	ReasonInvalidAmount      ReasonCode = "invalid_amount"
+	ReasonDoNotHonor         ReasonCode = "do_not_honor"
+	ReasonLimitExceeded      ReasonCode = "limit_exceeded"
+	ReasonSuspectedFraud     ReasonCode = "suspected_fraud"
+	ReasonDuplicate          ReasonCode = "duplicate_transaction"
+	ReasonDeclined           ReasonCode = "declined" // declined with a code we have no mapping for
+	ReasonGatewayUnavailable ReasonCode = "gateway_unavailable"
+	ReasonTimeout            ReasonCode = "timeout"
+	ReasonGatewayError       ReasonCode = "gateway_error"
+)
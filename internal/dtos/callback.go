@@ -1,6 +1,9 @@
 package dtos
 
-import errors "Payment-Gateway/pkg/error"
+import (
+	errors "Payment-Gateway/pkg/error"
+	"time"
+)
 
 type HandleCallbackRequest struct {
 	TransactionID string                 `json:"transaction_id" xml:"TransactionID"`
@@ -29,3 +32,15 @@ func (r *HandleCallbackRequest) Validate() error {
 	}
 	return nil
 }
+
+// ParseTimestamp parses the gateway-supplied callback timestamp (RFC 3339).
+func (r *HandleCallbackRequest) ParseTimestamp() (time.Time, error) {
+	if r.Timestamp == "" {
+		return time.Time{}, errors.ErrMissingCallbackTimestamp
+	}
+	ts, err := time.Parse(time.RFC3339, r.Timestamp)
+	if err != nil {
+		return time.Time{}, errors.ErrInvalidCallbackTimestamp
+	}
+	return ts, nil
+}
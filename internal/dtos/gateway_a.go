@@ -3,8 +3,10 @@ package dtos
 import errors "Payment-Gateway/pkg/error"
 
 type GatewayADepositRequest struct {
-	Account string  `json:"account"`
-	Amount  float64 `json:"amount"`
+	TransactionID string  `json:"transaction_id"`
+	Account       string  `json:"account"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
 }
 
 func (r *GatewayADepositRequest) Validate() error {
@@ -18,8 +20,10 @@ func (r *GatewayADepositRequest) Validate() error {
 }
 
 type GatewayAWithdrawalRequest struct {
-	Account string  `json:"account"`
-	Amount  float64 `json:"amount"`
+	TransactionID string  `json:"transaction_id"`
+	Account       string  `json:"account"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
 }
 
 func (r *GatewayAWithdrawalRequest) Validate() error {
@@ -31,3 +35,10 @@ func (r *GatewayAWithdrawalRequest) Validate() error {
 	}
 	return nil
 }
+
+type GatewayAResponse struct {
+	Status    string `json:"status"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	Reference string `json:"reference,omitempty"`
+}
@@ -5,22 +5,166 @@ import (
 	"encoding/xml"
 )
 
+// SOAP envelope namespaces; the envelope's namespace selects the SOAP version.
+const (
+	SOAP11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	SOAP12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// GatewayBNamespace is the namespace of GatewayB's payment messages.
+const GatewayBNamespace = "urn:gatewayb:payments"
+
+// WS-Security 1.0 namespaces and UsernameToken value types.
+const (
+	WSSENamespace        = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	WSUNamespace         = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+	WSSPasswordText      = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	WSSPasswordDigest    = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	WSSBase64EncodingURI = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// SOAPEnvelope is a SOAP 1.1 or 1.2 message. When decoding, XMLName.Space
+// reports the version the peer used; when encoding it selects the version,
+// defaulting to SOAP 1.1.
 type SOAPEnvelope struct {
-	XMLName xml.Name `xml:"Envelope"`
-	Body    SOAPBody `xml:"Body"`
+	XMLName xml.Name    `xml:"Envelope"`
+	Header  *SOAPHeader `xml:"Header,omitempty"`
+	Body    SOAPBody    `xml:"Body"`
+}
+
+type SOAPHeader struct {
+	Security *WSSecurity `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security,omitempty"`
 }
 
 type SOAPBody struct {
-	DepositRequest     *SOAPDepositRequest     `xml:"DepositRequest,omitempty"`
-	WithdrawalRequest  *SOAPWithdrawalRequest  `xml:"WithdrawalRequest,omitempty"`
-	DepositResponse    *SOAPDepositResponse    `xml:"DepositResponse,omitempty"`
-	WithdrawalResponse *SOAPWithdrawalResponse `xml:"WithdrawalResponse,omitempty"`
+	DepositRequest    *SOAPDepositRequest    `xml:"DepositRequest,omitempty"`
+	WithdrawalRequest *SOAPWithdrawalRequest `xml:"WithdrawalRequest,omitempty"`
+	Response          *SOAPResponse          `xml:"Response,omitempty"`
+	Fault             *SOAPFault             `xml:"Fault,omitempty"`
+}
+
+// Namespace returns the envelope namespace of the message's SOAP version.
+func (e SOAPEnvelope) Namespace() string {
+	if e.XMLName.Space == "" {
+		return SOAP11Namespace
+	}
+	return e.XMLName.Space
+}
+
+// MarshalXML writes the envelope, header, body and fault under a soap prefix
+// bound to the version's namespace, leaving SOAP 1.1 fault children
+// unqualified as the specification requires.
+func (e SOAPEnvelope) MarshalXML(enc *xml.Encoder, _ xml.StartElement) error {
+	envelope := xml.StartElement{
+		Name: xml.Name{Local: "soap:Envelope"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:soap"}, Value: e.Namespace()}},
+	}
+	if err := enc.EncodeToken(envelope); err != nil {
+		return err
+	}
+	if e.Header != nil {
+		if err := enc.EncodeElement(e.Header, xml.StartElement{Name: xml.Name{Local: "soap:Header"}}); err != nil {
+			return err
+		}
+	}
+	body := xml.StartElement{Name: xml.Name{Local: "soap:Body"}}
+	if err := enc.EncodeToken(body); err != nil {
+		return err
+	}
+	var content any
+	switch {
+	case e.Body.DepositRequest != nil:
+		content = e.Body.DepositRequest
+	case e.Body.WithdrawalRequest != nil:
+		content = e.Body.WithdrawalRequest
+	case e.Body.Response != nil:
+		content = e.Body.Response
+	}
+	if content != nil {
+		if err := enc.Encode(content); err != nil {
+			return err
+		}
+	}
+	if e.Body.Fault != nil {
+		if err := enc.EncodeElement(e.Body.Fault, xml.StartElement{Name: xml.Name{Local: "soap:Fault"}}); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(body.End()); err != nil {
+		return err
+	}
+	return enc.EncodeToken(envelope.End())
+}
+
+// WSSecurity is the WS-Security header carrying a UsernameToken.
+type WSSecurity struct {
+	// MustUnderstand is written with the envelope's soap prefix.
+	MustUnderstand string            `xml:"soap:mustUnderstand,attr,omitempty"`
+	UsernameToken  *WSSUsernameToken `xml:"UsernameToken"`
+}
+
+type WSSUsernameToken struct {
+	Username string      `xml:"Username"`
+	Password WSSPassword `xml:"Password"`
+	Nonce    *WSSNonce   `xml:"Nonce,omitempty"`
+	Created  string      `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created,omitempty"`
+}
+
+type WSSPassword struct {
+	Type  string `xml:"Type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type WSSNonce struct {
+	EncodingType string `xml:"EncodingType,attr"`
+	Value        string `xml:",chardata"`
+}
+
+// SOAPFault holds a fault of either SOAP version: faultcode, faultstring and
+// detail for 1.1, Code, Reason and Detail for 1.2.
+type SOAPFault struct {
+	FaultCode   string           `xml:"faultcode,omitempty"`
+	FaultString string           `xml:"faultstring,omitempty"`
+	FaultActor  string           `xml:"faultactor,omitempty"`
+	FaultDetail *SOAPFaultDetail `xml:"detail,omitempty"`
+
+	Code   *SOAPFaultCode   `xml:"http://www.w3.org/2003/05/soap-envelope Code,omitempty"`
+	Reason *SOAPFaultReason `xml:"http://www.w3.org/2003/05/soap-envelope Reason,omitempty"`
+	Detail *SOAPFaultDetail `xml:"http://www.w3.org/2003/05/soap-envelope Detail,omitempty"`
+}
+
+type SOAPFaultCode struct {
+	Value   string         `xml:"Value"`
+	Subcode *SOAPFaultCode `xml:"Subcode,omitempty"`
+}
+
+type SOAPFaultReason struct {
+	Text []SOAPFaultText `xml:"Text"`
+}
+
+type SOAPFaultText struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// SOAPFaultDetail is the application part of a fault.
+type SOAPFaultDetail struct {
+	PaymentFault *GatewayBPaymentFault `xml:"PaymentFault,omitempty"`
+}
+
+// GatewayBPaymentFault is GatewayB's fault detail, carrying its result code.
+type GatewayBPaymentFault struct {
+	XMLName xml.Name `xml:"urn:gatewayb:payments PaymentFault"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message,omitempty"`
 }
 
 type SOAPDepositRequest struct {
-	XMLName xml.Name `xml:"DepositRequest"`
-	Account string   `xml:"Account"`
-	Amount  float64  `xml:"Amount"`
+	XMLName       xml.Name `xml:"urn:gatewayb:payments DepositRequest"`
+	TransactionID string   `xml:"TransactionID"`
+	Account       string   `xml:"Account"`
+	Amount        float64  `xml:"Amount"`
+	Currency      string   `xml:"Currency"`
 }
 
 func (r *SOAPDepositRequest) Validate() error {
@@ -34,9 +178,11 @@ func (r *SOAPDepositRequest) Validate() error {
 }
 
 type SOAPWithdrawalRequest struct {
-	XMLName xml.Name `xml:"WithdrawalRequest"`
-	Account string   `xml:"Account"`
-	Amount  float64  `xml:"Amount"`
+	XMLName       xml.Name `xml:"urn:gatewayb:payments WithdrawalRequest"`
+	TransactionID string   `xml:"TransactionID"`
+	Account       string   `xml:"Account"`
+	Amount        float64  `xml:"Amount"`
+	Currency      string   `xml:"Currency"`
 }
 
 func (r *SOAPWithdrawalRequest) Validate() error {
@@ -49,12 +195,11 @@ func (r *SOAPWithdrawalRequest) Validate() error {
 	return nil
 }
 
-type SOAPDepositResponse struct {
-	XMLName xml.Name `xml:"DepositResponse"`
-	Result  string   `xml:"Result"`
-}
-
-type SOAPWithdrawalResponse struct {
-	XMLName xml.Name `xml:"WithdrawalResponse"`
-	Result  string   `xml:"Result"`
+// SOAPResponse is GatewayB's answer to both deposits and withdrawals.
+type SOAPResponse struct {
+	XMLName   xml.Name `xml:"urn:gatewayb:payments Response"`
+	Status    string   `xml:"Status"`
+	Code      string   `xml:"Code,omitempty"`
+	Message   string   `xml:"Message"`
+	Reference string   `xml:"Reference,omitempty"`
 }
@@ -0,0 +1,64 @@
+package dtos
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/models"
+	"time"
+)
+
+// RoutingDryRunRequest is a sample transaction to explain the routing of.
+type RoutingDryRunRequest struct {
+	Type constants.TransactionType `json:"type"`
+	TransactionRequest
+}
+
+type RoutingRuleResult struct {
+	Rule    string `json:"rule"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type RoutingCandidate struct {
+	Gateway   string `json:"gateway"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// RoutingDryRunResponse lists the rules evaluated in order up to the first
+// match and the candidate gateways in preference order. Strategy is set when
+// the matched rule overrides the merchant's routing strategy.
+type RoutingDryRunResponse struct {
+	Request     RoutingDryRunRequest `json:"request"`
+	MatchedRule string               `json:"matched_rule,omitempty"`
+	Strategy    string               `json:"strategy,omitempty"`
+	Explanation string               `json:"explanation"`
+	Rules       []RoutingRuleResult  `json:"rules"`
+	Candidates  []RoutingCandidate   `json:"candidates"`
+}
+
+type RoutingCanary struct {
+	Gateway      string     `json:"gateway"`
+	Percent      float64    `json:"percent"`
+	Samples      int        `json:"samples"`
+	ErrorRate    float64    `json:"error_rate"`
+	MaxErrorRate float64    `json:"max_error_rate"`
+	RolledBack   bool       `json:"rolled_back"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty"`
+}
+
+type RoutingCanaryListResponse struct {
+	Canaries []RoutingCanary `json:"canaries"`
+}
+
+type ShadowReportListResponse struct {
+	Shadows []models.ShadowReport `json:"shadows"`
+}
+
+// GatewayStateChangeRequest is the optional body of the drain and disable endpoints.
+type GatewayStateChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+type GatewayStatusListResponse struct {
+	Gateways []models.GatewayStatus `json:"gateways"`
+}
@@ -1,11 +1,24 @@
 package dtos
 
+import (
+	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
+)
+
 type TransactionRequest struct {
-	AccountID string  `json:"account_id"`
-	Amount    float64 `json:"amount"`
+	AccountID  string  `json:"account_id"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	MerchantID string  `json:"merchant_id,omitempty"`
 }
 
 type TransactionResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
+	Success       bool   `json:"success"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Status        string `json:"status,omitempty"`
+	Message       string `json:"message,omitempty"`
+	// Gateway carries the gateway's reference, normalized reason code and raw code and message.
+	Gateway *models.GatewayResult `json:"gateway,omitempty"`
+	Error   *errors.APIError      `json:"error,omitempty"`
 }
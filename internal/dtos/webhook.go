@@ -0,0 +1,17 @@
+package dtos
+
+import "Payment-Gateway/internal/models"
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+}
+
+type WebhookDeliveryDetailResponse struct {
+	Delivery *models.WebhookDelivery          `json:"delivery"`
+	Attempts []*models.WebhookDeliveryAttempt `json:"attempts"`
+}
+
+type WebhookReplayResponse struct {
+	Status   string   `json:"status"`
+	EventIDs []string `json:"event_ids"`
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNotProcessed marks gateway failures that prove the payment did not reach
+// the provider: the call was never sent, or every attempt was turned away
+// with 429 or 503. Only these are safe to send to another gateway; timeouts
+// and other failures may have been processed and must not fail over.
+var ErrNotProcessed = errors.New("payment not processed by gateway")
+
+// notProcessedError tags err with ErrNotProcessed without changing its text.
+type notProcessedError struct {
+	err error
+}
+
+func (e *notProcessedError) Error() string        { return e.err.Error() }
+func (e *notProcessedError) Unwrap() error        { return e.err }
+func (e *notProcessedError) Is(target error) bool { return target == ErrNotProcessed }
+
+// NotProcessed tags err with ErrNotProcessed. Adapters use it for failures
+// they know the provider turned away, such as a final 429 or 503 answer.
+func NotProcessed(err error) error {
+	return &notProcessedError{err: err}
+}
+
+// SafeToFailover reports whether a PaymentGateway error proves the payment was
+// not processed, so the transaction may be retried on another gateway.
+func SafeToFailover(err error) bool {
+	return errors.Is(err, ErrNotProcessed)
+}
+
+// Named is implemented by gateways that know their configured name.
+type Named interface {
+	Name() string
+}
+
+// NameOf returns gw's configured name, or its type for unnamed gateways.
+func NameOf(gw PaymentGateway) string {
+	if n, ok := gw.(Named); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", gw)
+}
+
+// isDialError reports whether err happened while connecting, i.e. before any
+// byte of the request was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -1,196 +1,146 @@
 package gateway
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
-	"time"
 
 	"Payment-Gateway/internal/dtos"
+	apperrors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/logger"
 
 	"go.uber.org/zap"
 
 	"Payment-Gateway/internal/config"
-
-	"Payment-Gateway/internal/models"
-
-	"github.com/cenkalti/backoff/v4"
-	"github.com/sony/gobreaker"
+	"Payment-Gateway/internal/resilience"
 )
 
+// GatewayA integrates a JSON-over-HTTP payment provider.
 type GatewayA struct {
-	URL              string
-	Client           *http.Client
-	CircuitBreaker   *gobreaker.CircuitBreaker
-	ResilienceConfig *config.ResilienceConfig
+	name       string
+	URL        string
+	Client     *http.Client
+	Resilience *resilience.Executor
 }
 
-func NewGatewayA(url, gatewayName string, cfg *config.ResilienceConfig) PaymentGateway {
-	var cb *gobreaker.CircuitBreaker
-	if cfg.CircuitBreaker.Enabled {
-		cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:        gatewayName,
-			MaxRequests: cfg.CircuitBreaker.MaxRequests,
-			Interval:    time.Duration(cfg.CircuitBreaker.Interval) * time.Second,
-			Timeout:     time.Duration(cfg.CircuitBreaker.Timeout) * time.Second,
-			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				failRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-				return counts.Requests >= 3 && failRatio >= cfg.CircuitBreaker.FailureRatio
-			},
-		})
-	}
-	return &GatewayA{
-		URL:              url,
-		Client:           &http.Client{Timeout: time.Duration(cfg.HTTPTimeoutSeconds) * time.Second},
-		CircuitBreaker:   cb,
-		ResilienceConfig: cfg,
-	}
-}
+// GatewayASettings is empty: GatewayA has no adapter-specific options, so any
+// key under its settings block is rejected as a likely typo.
+type GatewayASettings struct{}
 
-func (g *GatewayA) doWithResilience(req *http.Request) (*http.Response, error) {
-	operation := func() (interface{}, error) {
-		if g.CircuitBreaker != nil {
-			resp, err := g.CircuitBreaker.Execute(func() (interface{}, error) {
-				return g.Client.Do(req)
-			})
-			if err != nil {
-				return nil, err
-			}
-			return resp.(*http.Response), nil
+func init() {
+	Register("gatewayA", WithSettings(nil, func(key string, cfg config.GatewayConfig, settings GatewayASettings) (PaymentGateway, error) {
+		if cfg.URL == "" {
+			return nil, errors.New("url is required")
 		}
-		return g.Client.Do(req)
-	}
-
-	b := backoff.NewExponentialBackOff()
-	b.InitialInterval = time.Duration(g.ResilienceConfig.InitialBackoffMillis) * time.Millisecond
-	b.MaxInterval = time.Duration(g.ResilienceConfig.MaxBackoffMillis) * time.Millisecond
-	b.MaxElapsedTime = time.Duration(g.ResilienceConfig.HTTPTimeoutSeconds*g.ResilienceConfig.MaxRetries) * time.Second
-
-	var resp *http.Response
-	err := backoff.Retry(func() error {
-		r, err := operation()
-		if err != nil {
-			return err
-		}
-		resp = r.(*http.Response)
-		return nil
-	}, backoff.WithMaxRetries(b, uint64(g.ResilienceConfig.MaxRetries)))
-	return resp, err
+		return NewGatewayA(cfg.URL, cfg.Name, &cfg.Resilience), nil
+	}))
 }
 
-// ProcessDeposit simulates HTTP JSON request/response for GatewayA, handling success, failure, and timeout.
-func (g *GatewayA) ProcessDeposit(r *http.Request) (interface{}, error) {
-	log := logger.GetLogger().With(
-		zap.String("func", "GatewayA.ProcessDeposit"),
-		zap.String("url", g.URL),
-	)
-	var modelReq models.DepositRequest
-	var req dtos.GatewayADepositRequest
-	if r != nil {
-		if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
-			log.Warn("Failed to decode deposit request", zap.Error(err))
-			return nil, err
-		}
-		req = dtos.GatewayADepositRequest{
-			Account: modelReq.Account,
-			Amount:  modelReq.Amount,
-		}
-	} else {
-		req = dtos.GatewayADepositRequest{Account: "demo", Amount: 100}
+func NewGatewayA(url, gatewayName string, cfg *config.ResilienceConfig) PaymentGateway {
+	return &GatewayA{
+		name:       gatewayName,
+		URL:        url,
+		Client:     &http.Client{},
+		Resilience: resilience.NewExecutor(gatewayName, *cfg),
 	}
+}
 
-	payload, _ := json.Marshal(req)
-	// Use context from incoming request
-	ctx := context.Background()
-	if r != nil {
-		ctx = r.Context()
+// ProcessDeposit sends a JSON deposit request to GatewayA, handling success, failure, and timeout.
+func (g *GatewayA) ProcessDeposit(ctx context.Context, req PaymentRequest) (*PaymentResult, error) {
+	payload, err := json.Marshal(dtos.GatewayADepositRequest{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return g.send(ctx, "deposit", req, payload)
+}
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", g.URL+"/deposit", bytes.NewBuffer(payload))
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	log.Info("Sending deposit request to gateway")
-	resp, err := g.doWithResilience(httpReq)
+// ProcessWithdrawal sends a JSON withdrawal request to GatewayA, handling success, failure, and timeout.
+func (g *GatewayA) ProcessWithdrawal(ctx context.Context, req PaymentRequest) (*PaymentResult, error) {
+	payload, err := json.Marshal(dtos.GatewayAWithdrawalRequest{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+	})
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			log.Error("GatewayA deposit timeout", zap.Error(err))
-			return nil, errors.New("gateway A timeout")
-		}
-		log.Error("GatewayA deposit error", zap.Error(err))
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return g.send(ctx, "withdrawal", req, payload)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error("GatewayA deposit failed", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.New("gateway A failure")
-	}
+// Name returns the gateway's configured name.
+func (g *GatewayA) Name() string {
+	return g.name
+}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		log.Error("Failed to decode gateway response", zap.Error(err))
-		return nil, err
-	}
-	log.Info("GatewayA deposit successful", zap.Any("response", result))
-	return result, nil
+// Available reports whether GatewayA's circuit breaker lets calls through.
+func (g *GatewayA) Available() bool {
+	return g.Resilience.Available()
 }
 
-// ProcessWithdrawal simulates HTTP JSON request/response for GatewayA, handling success, failure, and timeout.
-func (g *GatewayA) ProcessWithdrawal(r *http.Request) (interface{}, error) {
+// send posts payload to the operation's endpoint and converts the reply into a PaymentResult.
+func (g *GatewayA) send(ctx context.Context, operation string, req PaymentRequest, payload []byte) (*PaymentResult, error) {
 	log := logger.GetLogger().With(
-		zap.String("func", "GatewayA.ProcessWithdrawal"),
+		zap.String("func", "GatewayA.send"),
+		zap.String("operation", operation),
+		zap.String("transaction_id", req.TransactionID),
 		zap.String("url", g.URL),
 	)
-	var modelReq models.WithdrawalRequest
-	var req dtos.GatewayAWithdrawalRequest
-	if r != nil {
-		if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
-			log.Warn("Failed to decode withdrawal request", zap.Error(err))
-			return nil, err
-		}
-		req = dtos.GatewayAWithdrawalRequest{
-			Account: modelReq.Account,
-			Amount:  modelReq.Amount,
-		}
-	} else {
-		req = dtos.GatewayAWithdrawalRequest{Account: "demo", Amount: 100}
-	}
-
-	payload, _ := json.Marshal(req)
-	// Use context from incoming request
-	ctx := context.Background()
-	if r != nil {
-		ctx = r.Context()
-	}
-
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", g.URL+"/withdrawal", bytes.NewBuffer(payload))
-	httpReq.Header.Set("Content-Type", "application/json")
 
-	log.Info("Sending withdrawal request to gateway")
-	resp, err := g.doWithResilience(httpReq)
+	log.Info("Sending request to gateway")
+	resp, err := post(ctx, g.Client, g.Resilience, g.URL+"/"+operation, "application/json", req.TransactionID, payload)
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			log.Error("GatewayA withdrawal timeout", zap.Error(err))
-			return nil, errors.New("gateway A timeout")
+			log.Error("GatewayA request timeout", zap.Error(err))
+			return nil, apperrors.WithMessage(apperrors.ErrGatewayTimeout, "gateway A timeout")
 		}
-		log.Error("GatewayA withdrawal error", zap.Error(err))
-		return nil, err
+		log.Error("GatewayA request error", zap.Error(err))
+		if errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Error("GatewayA withdrawal failed", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.New("gateway A failure")
+		log.Error("GatewayA request failed", zap.Int("status_code", resp.StatusCode))
+		err := apperrors.WithMessage(apperrors.ErrProcessingFailed, "gateway A failure")
+		if resp.Refused {
+			return nil, NotProcessed(err)
+		}
+		return nil, err
 	}
-
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var reply dtos.GatewayAResponse
+	if err := json.Unmarshal(resp.Body, &reply); err != nil {
 		log.Error("Failed to decode gateway response", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err)
+	}
+	outcome, ok := OutcomeFromStatus(reply.Status)
+	if !ok {
+		log.Error("Unrecognized gateway status", zap.String("gateway_status", reply.Status))
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("gateway A returned unknown status %q", reply.Status))
+	}
+	log.Info("GatewayA request completed",
+		zap.String("gateway_status", reply.Status),
+		zap.String("gateway_code", reply.Code),
+		zap.String("gateway_ref", reply.Reference),
+	)
+	result := &PaymentResult{
+		GatewayRef:  reply.Reference,
+		Outcome:     outcome,
+		GatewayCode: reply.Code,
+		Message:     reply.Message,
+		StatusCode:  resp.StatusCode,
+		RawResponse: resp.Body,
+	}
+	if outcome == OutcomeDeclined {
+		result.Reason = reasonForCode(gatewayAReasonCodes, reply.Code)
 	}
-	log.Info("GatewayA withdrawal successful", zap.Any("response", result))
 	return result, nil
 }
@@ -2,6 +2,8 @@ package gateway
 
 import (
 	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/dtos"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -25,21 +27,42 @@ func getTestResilienceConfig() *config.ResilienceConfig {
 	}
 }
 
+func testPaymentRequest() PaymentRequest {
+	return PaymentRequest{TransactionID: "tx-1", Account: "acc1", Amount: 100, Currency: "USD"}
+}
+
+func TestGatewayA_ProcessDeposit_SendsTransactionFields(t *testing.T) {
+	var got dtos.GatewayADepositRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "success"})
+	}))
+	defer ts.Close()
+
+	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
+	if _, err := g.ProcessDeposit(context.Background(), testPaymentRequest()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := dtos.GatewayADepositRequest{TransactionID: "tx-1", Account: "acc1", Amount: 100, Currency: "USD"}
+	if got != want {
+		t.Errorf("gateway received %+v, want %+v", got, want)
+	}
+}
+
 func TestGatewayA_ProcessDeposit_Success(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{"result": "ok"})
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "message": "ok", "reference": "ref-1"})
 	}))
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	resp, err := g.ProcessDeposit(nil)
+	resp, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	m, ok := resp.(map[string]interface{})
-	if !ok || m["result"] != "ok" {
-		t.Errorf("unexpected response: %v", resp)
+	if resp.Outcome != OutcomeApproved || resp.GatewayRef != "ref-1" || resp.Message != "ok" || len(resp.RawResponse) == 0 {
+		t.Errorf("unexpected response: %+v", resp)
 	}
 }
 
@@ -50,7 +73,7 @@ func TestGatewayA_ProcessDeposit_Failure(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	_, err := g.ProcessDeposit(nil)
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway A failure" {
 		t.Errorf("expected gateway A failure error, got %v", err)
 	}
@@ -63,7 +86,7 @@ func TestGatewayA_ProcessDeposit_Timeout(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	_, err := g.ProcessDeposit(nil)
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway A timeout" {
 		t.Errorf("expected gateway A timeout error, got %v", err)
 	}
@@ -72,18 +95,17 @@ func TestGatewayA_ProcessDeposit_Timeout(t *testing.T) {
 func TestGatewayA_ProcessWithdrawal_Success(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]interface{}{"result": "ok"})
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "message": "ok", "reference": "ref-1"})
 	}))
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	resp, err := g.ProcessWithdrawal(nil)
+	resp, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	m, ok := resp.(map[string]interface{})
-	if !ok || m["result"] != "ok" {
-		t.Errorf("unexpected response: %v", resp)
+	if resp.Outcome != OutcomeApproved || resp.GatewayRef != "ref-1" || resp.Message != "ok" || len(resp.RawResponse) == 0 {
+		t.Errorf("unexpected response: %+v", resp)
 	}
 }
 
@@ -94,7 +116,7 @@ func TestGatewayA_ProcessWithdrawal_Failure(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	_, err := g.ProcessWithdrawal(nil)
+	_, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway A failure" {
 		t.Errorf("expected gateway A failure error, got %v", err)
 	}
@@ -107,7 +129,7 @@ func TestGatewayA_ProcessWithdrawal_Timeout(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayA(ts.URL, "gatewayA", getTestResilienceConfig())
-	_, err := g.ProcessWithdrawal(nil)
+	_, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway A timeout" {
 		t.Errorf("expected gateway A timeout error, got %v", err)
 	}
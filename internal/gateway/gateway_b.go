@@ -1,207 +1,239 @@
 package gateway
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"encoding/xml"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"Payment-Gateway/internal/dtos"
-	"Payment-Gateway/internal/models"
+	apperrors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/logger"
 
 	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/resilience"
 
-	"github.com/cenkalti/backoff/v4"
-	"github.com/sony/gobreaker"
 	"go.uber.org/zap"
 )
 
-// GatewayB is a skeleton for a SOAP-based gateway.
+// GatewayB integrates a SOAP/XML payment provider over SOAP 1.1 or 1.2.
 type GatewayB struct {
-	URL              string
-	Client           *http.Client
-	CircuitBreaker   *gobreaker.CircuitBreaker
-	ResilienceConfig *config.ResilienceConfig
+	name       string
+	URL        string
+	Client     *http.Client
+	Resilience *resilience.Executor
+	settings   GatewayBSettings
 }
 
-func NewGatewayB(url, gatewayName string, cfg *config.ResilienceConfig) PaymentGateway {
-	var cb *gobreaker.CircuitBreaker
-	if cfg.CircuitBreaker.Enabled {
-		cb = gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:        gatewayName,
-			MaxRequests: cfg.CircuitBreaker.MaxRequests,
-			Interval:    time.Duration(cfg.CircuitBreaker.Interval) * time.Second,
-			Timeout:     time.Duration(cfg.CircuitBreaker.Timeout) * time.Second,
-			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				failRatio := float64(counts.TotalFailures) / float64(counts.Requests)
-				return counts.Requests >= 3 && failRatio >= cfg.CircuitBreaker.FailureRatio
-			},
-		})
-	}
-	return &GatewayB{
-		URL:              url,
-		Client:           &http.Client{Timeout: time.Duration(cfg.HTTPTimeoutSeconds) * time.Second},
-		CircuitBreaker:   cb,
-		ResilienceConfig: cfg,
-	}
+// GatewayBSettings configure GatewayB's SOAP binding under its settings block.
+type GatewayBSettings struct {
+	// SOAPVersion is 1.1 (default) or 1.2.
+	SOAPVersion string             `yaml:"soapVersion"`
+	Security    WSSecuritySettings `yaml:"security"`
 }
 
-func (g *GatewayB) doWithResilience(req *http.Request) (*http.Response, error) {
-	operation := func() (interface{}, error) {
-		if g.CircuitBreaker != nil {
-			resp, err := g.CircuitBreaker.Execute(func() (interface{}, error) {
-				return g.Client.Do(req)
-			})
-			if err != nil {
-				return nil, err
-			}
-			return resp.(*http.Response), nil
-		}
-		return g.Client.Do(req)
+// Validate checks the settings decoded from configuration.
+func (s GatewayBSettings) Validate() error {
+	switch s.SOAPVersion {
+	case "", SOAP11, SOAP12:
+	default:
+		return fmt.Errorf("soapVersion %q must be %s or %s", s.SOAPVersion, SOAP11, SOAP12)
 	}
+	return s.Security.validate()
+}
 
-	b := backoff.NewExponentialBackOff()
-	b.InitialInterval = time.Duration(g.ResilienceConfig.InitialBackoffMillis) * time.Millisecond
-	b.MaxInterval = time.Duration(g.ResilienceConfig.MaxBackoffMillis) * time.Millisecond
-	b.MaxElapsedTime = time.Duration(g.ResilienceConfig.HTTPTimeoutSeconds*g.ResilienceConfig.MaxRetries) * time.Second
+// gatewayBActions are the SOAP actions of GatewayB's operations.
+var gatewayBActions = map[string]string{
+	"deposit":    dtos.GatewayBNamespace + "/Deposit",
+	"withdrawal": dtos.GatewayBNamespace + "/Withdrawal",
+}
 
-	var resp *http.Response
-	err := backoff.Retry(func() error {
-		r, err := operation()
-		if err != nil {
-			return err
+func init() {
+	Register("gatewayB", WithSettings(nil, func(key string, cfg config.GatewayConfig, settings GatewayBSettings) (PaymentGateway, error) {
+		if cfg.URL == "" {
+			return nil, errors.New("url is required")
 		}
-		resp = r.(*http.Response)
-		return nil
-	}, backoff.WithMaxRetries(b, uint64(g.ResilienceConfig.MaxRetries)))
-	return resp, err
+		settings.Security.Password = os.ExpandEnv(settings.Security.Password)
+		return NewGatewayBWithSettings(cfg.URL, cfg.Name, &cfg.Resilience, settings), nil
+	}))
 }
 
-// ProcessDeposit simulates a SOAP request/response for GatewayB.
-func (g *GatewayB) ProcessDeposit(r *http.Request) (interface{}, error) {
-	log := logger.GetLogger().With(
-		zap.String("func", "GatewayB.ProcessDeposit"),
-		zap.String("url", g.URL),
-	)
-	var modelReq models.DepositRequest
-	var depositReq dtos.SOAPDepositRequest
-	if r != nil {
-		if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
-			log.Warn("Failed to decode deposit request", zap.Error(err))
-			return nil, err
-		}
-		depositReq = dtos.SOAPDepositRequest{
-			Account: modelReq.Account,
-			Amount:  modelReq.Amount,
-		}
-	} else {
-		depositReq = dtos.SOAPDepositRequest{Account: "demo", Amount: 100}
-	}
-	req := &dtos.SOAPEnvelope{
-		Body: dtos.SOAPBody{
-			DepositRequest: &depositReq,
-		},
+// NewGatewayB speaks SOAP 1.1 without WS-Security.
+func NewGatewayB(url, gatewayName string, cfg *config.ResilienceConfig) PaymentGateway {
+	return NewGatewayBWithSettings(url, gatewayName, cfg, GatewayBSettings{})
+}
+
+func NewGatewayBWithSettings(url, gatewayName string, cfg *config.ResilienceConfig, settings GatewayBSettings) PaymentGateway {
+	if settings.SOAPVersion == "" {
+		settings.SOAPVersion = SOAP11
 	}
-	payload, _ := xml.Marshal(req)
-	// Use context from incoming request
-	ctx := context.Background()
-	if r != nil {
-		ctx = r.Context()
+	return &GatewayB{
+		name:       gatewayName,
+		URL:        url,
+		Client:     &http.Client{},
+		Resilience: resilience.NewExecutor(gatewayName, *cfg),
+		settings:   settings,
 	}
+}
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", g.URL+"/deposit", bytes.NewBuffer(payload))
-	httpReq.Header.Set("Content-Type", "application/xml")
+// ProcessDeposit sends a SOAP deposit request to GatewayB.
+func (g *GatewayB) ProcessDeposit(ctx context.Context, req PaymentRequest) (*PaymentResult, error) {
+	return g.send(ctx, "deposit", req, dtos.SOAPBody{
+		DepositRequest: &dtos.SOAPDepositRequest{
+			TransactionID: req.TransactionID,
+			Account:       req.Account,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+		},
+	})
+}
 
-	log.Info("Sending deposit request to gateway")
-	resp, err := g.doWithResilience(httpReq)
-	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
-			log.Error("GatewayB deposit timeout", zap.Error(err))
-			return nil, errors.New("gateway B timeout")
-		}
-		log.Error("GatewayB deposit error", zap.Error(err))
-		return nil, err
-	}
-	defer resp.Body.Close()
+// ProcessWithdrawal sends a SOAP withdrawal request to GatewayB.
+func (g *GatewayB) ProcessWithdrawal(ctx context.Context, req PaymentRequest) (*PaymentResult, error) {
+	return g.send(ctx, "withdrawal", req, dtos.SOAPBody{
+		WithdrawalRequest: &dtos.SOAPWithdrawalRequest{
+			TransactionID: req.TransactionID,
+			Account:       req.Account,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+		},
+	})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Error("GatewayB deposit failed", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.New("gateway B failure")
-	}
+// Name returns the gateway's configured name.
+func (g *GatewayB) Name() string {
+	return g.name
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	var envelope dtos.SOAPEnvelope
-	if err := xml.Unmarshal(body, &envelope); err != nil {
-		log.Error("Failed to decode gateway response", zap.Error(err))
-		return nil, err
-	}
-	log.Info("GatewayB deposit successful", zap.Any("response", envelope))
-	return envelope, nil
+// Available reports whether GatewayB's circuit breaker lets calls through.
+func (g *GatewayB) Available() bool {
+	return g.Resilience.Available()
 }
 
-// ProcessWithdrawal simulates a SOAP request/response for GatewayB.
-func (g *GatewayB) ProcessWithdrawal(r *http.Request) (interface{}, error) {
-	log := logger.GetLogger().With(
-		zap.String("func", "GatewayB.ProcessWithdrawal"),
-		zap.String("url", g.URL),
-	)
-	var modelReq models.WithdrawalRequest
-	var withdrawalReq dtos.SOAPWithdrawalRequest
-	if r != nil {
-		if err := json.NewDecoder(r.Body).Decode(&modelReq); err != nil {
-			log.Warn("Failed to decode withdrawal request", zap.Error(err))
+// envelope wraps body in an envelope of the configured SOAP version, with a
+// WS-Security header when credentials are configured.
+func (g *GatewayB) envelope(body dtos.SOAPBody) ([]byte, error) {
+	envelope := dtos.SOAPEnvelope{
+		XMLName: xml.Name{Space: soapNamespace(g.settings.SOAPVersion)},
+		Body:    body,
+	}
+	if g.settings.Security.Username != "" {
+		header, err := usernameToken(g.settings.Security, time.Now())
+		if err != nil {
 			return nil, err
 		}
-		withdrawalReq = dtos.SOAPWithdrawalRequest{
-			Account: modelReq.Account,
-			Amount:  modelReq.Amount,
-		}
-	} else {
-		withdrawalReq = dtos.SOAPWithdrawalRequest{Account: "demo", Amount: 100}
-	}
-	req := &dtos.SOAPEnvelope{
-		Body: dtos.SOAPBody{
-			WithdrawalRequest: &withdrawalReq,
-		},
-	}
-	payload, _ := xml.Marshal(req)
-	// Use context from incoming request
-	ctx := context.Background()
-	if r != nil {
-		ctx = r.Context()
+		envelope.Header = header
 	}
+	return xml.Marshal(envelope)
+}
 
-	httpReq, _ := http.NewRequestWithContext(ctx, "POST", g.URL+"/withdrawal", bytes.NewBuffer(payload))
-	httpReq.Header.Set("Content-Type", "application/xml")
+// send posts the SOAP request to the operation's endpoint and converts the reply into a PaymentResult.
+func (g *GatewayB) send(ctx context.Context, operation string, req PaymentRequest, body dtos.SOAPBody) (*PaymentResult, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "GatewayB.send"),
+		zap.String("operation", operation),
+		zap.String("transaction_id", req.TransactionID),
+		zap.String("url", g.URL),
+		zap.String("soap_version", g.settings.SOAPVersion),
+	)
 
-	log.Info("Sending withdrawal request to gateway")
-	resp, err := g.doWithResilience(httpReq)
+	payload, err := g.envelope(body)
+	if err != nil {
+		return nil, err
+	}
+	header := soapHeader(g.settings.SOAPVersion, gatewayBActions[operation])
+	header.Set(IdempotencyKeyHeader, req.TransactionID)
+
+	log.Info("Sending request to gateway")
+	resp, err := SendHTTP(ctx, g.Client, g.Resilience, HTTPRequest{
+		Method: http.MethodPost,
+		URL:    g.URL + "/" + operation,
+		Header: header,
+		Body:   payload,
+		Final:  isSOAPFaultReply,
+	})
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			log.Error("GatewayB withdrawal timeout", zap.Error(err))
-			return nil, errors.New("gateway B timeout")
+			log.Error("GatewayB request timeout", zap.Error(err))
+			return nil, apperrors.WithMessage(apperrors.ErrGatewayTimeout, "gateway B timeout")
 		}
-		log.Error("GatewayB withdrawal error", zap.Error(err))
-		return nil, err
+		log.Error("GatewayB request error", zap.Error(err))
+		if errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
 	}
-	defer resp.Body.Close()
 
+	if fault, ok := parseSOAPFault(resp.Body, resp.StatusCode, gatewayBReasonCodes); ok {
+		log.Error("GatewayB returned a SOAP fault",
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("fault_code", fault.Code),
+			zap.String("fault_subcode", fault.Subcode),
+			zap.String("gateway_code", fault.DetailCode),
+			zap.String("fault_reason", fault.Reason),
+		)
+		switch {
+		case fault.SenderFault() && fault.Declined():
+			// A business decline: the answer, which must not fail over
+			return &PaymentResult{
+				Outcome:     OutcomeDeclined,
+				Reason:      fault.ReasonCode(),
+				GatewayCode: fault.DetailCode,
+				Message:     fault.Reason,
+				StatusCode:  resp.StatusCode,
+				RawResponse: resp.Body,
+			}, nil
+		case fault.SenderFault() || resp.Refused:
+			// The request or its credentials were rejected before processing
+			return nil, NotProcessed(fault)
+		}
+		return nil, fault
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Error("GatewayB withdrawal failed", zap.Int("status_code", resp.StatusCode))
-		return nil, errors.New("gateway B failure")
+		log.Error("GatewayB request failed", zap.Int("status_code", resp.StatusCode))
+		err := apperrors.WithMessage(apperrors.ErrProcessingFailed, "gateway B failure")
+		if resp.Refused {
+			return nil, NotProcessed(err)
+		}
+		return nil, err
 	}
-
-	body, _ := io.ReadAll(resp.Body)
 	var envelope dtos.SOAPEnvelope
-	if err := xml.Unmarshal(body, &envelope); err != nil {
+	if err := xml.Unmarshal(resp.Body, &envelope); err != nil {
 		log.Error("Failed to decode gateway response", zap.Error(err))
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err)
+	}
+	if want := soapNamespace(g.settings.SOAPVersion); envelope.XMLName.Space != want {
+		log.Error("Gateway answered in another SOAP version", zap.String("namespace", envelope.XMLName.Space))
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("gateway B answered with envelope namespace %q, want %q", envelope.XMLName.Space, want))
+	}
+	reply := envelope.Body.Response
+	if reply == nil {
+		log.Error("Gateway response has no Response element")
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, "gateway B returned no response")
+	}
+	outcome, ok := OutcomeFromStatus(reply.Status)
+	if !ok {
+		log.Error("Unrecognized gateway status", zap.String("gateway_status", reply.Status))
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("gateway B returned unknown status %q", reply.Status))
+	}
+	log.Info("GatewayB request completed",
+		zap.String("gateway_status", reply.Status),
+		zap.String("gateway_code", reply.Code),
+		zap.String("gateway_ref", reply.Reference),
+	)
+	result := &PaymentResult{
+		GatewayRef:  reply.Reference,
+		Outcome:     outcome,
+		GatewayCode: reply.Code,
+		Message:     reply.Message,
+		StatusCode:  resp.StatusCode,
+		RawResponse: resp.Body,
+	}
+	if outcome == OutcomeDeclined {
+		result.Reason = reasonForCode(gatewayBReasonCodes, reply.Code)
 	}
-	log.Info("GatewayB withdrawal successful", zap.Any("response", envelope))
-	return envelope, nil
+	return result, nil
 }
@@ -1,8 +1,14 @@
 package gateway
 
 import (
+	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/dtos"
+	mockgateway "Payment-Gateway/internal/handler/mock_gateway"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -14,7 +20,7 @@ func TestGatewayB_ProcessDeposit_Success(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		resp := dtos.SOAPEnvelope{
 			Body: dtos.SOAPBody{
-				DepositResponse: &dtos.SOAPDepositResponse{Result: "ok"},
+				Response: &dtos.SOAPResponse{Status: "success", Message: "ok", Reference: "ref-1"},
 			},
 		}
 		xml.NewEncoder(w).Encode(resp)
@@ -22,13 +28,12 @@ func TestGatewayB_ProcessDeposit_Success(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	resp, err := g.ProcessDeposit(nil)
+	resp, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	_, ok := resp.(dtos.SOAPEnvelope)
-	if !ok {
-		t.Errorf("unexpected response type: %T", resp)
+	if resp.Outcome != OutcomeApproved || resp.GatewayRef != "ref-1" || resp.Message != "ok" || len(resp.RawResponse) == 0 {
+		t.Errorf("unexpected response: %+v", resp)
 	}
 }
 
@@ -39,7 +44,7 @@ func TestGatewayB_ProcessDeposit_Failure(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	_, err := g.ProcessDeposit(nil)
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway B failure" {
 		t.Errorf("expected gateway B failure error, got %v", err)
 	}
@@ -52,7 +57,7 @@ func TestGatewayB_ProcessDeposit_Timeout(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	_, err := g.ProcessDeposit(nil)
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway B timeout" {
 		t.Errorf("expected gateway B timeout error, got %v", err)
 	}
@@ -63,7 +68,7 @@ func TestGatewayB_ProcessWithdrawal_Success(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		resp := dtos.SOAPEnvelope{
 			Body: dtos.SOAPBody{
-				WithdrawalResponse: &dtos.SOAPWithdrawalResponse{Result: "ok"},
+				Response: &dtos.SOAPResponse{Status: "success", Message: "ok", Reference: "ref-1"},
 			},
 		}
 		xml.NewEncoder(w).Encode(resp)
@@ -71,13 +76,12 @@ func TestGatewayB_ProcessWithdrawal_Success(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	resp, err := g.ProcessWithdrawal(nil)
+	resp, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	_, ok := resp.(dtos.SOAPEnvelope)
-	if !ok {
-		t.Errorf("unexpected response type: %T", resp)
+	if resp.Outcome != OutcomeApproved || resp.GatewayRef != "ref-1" || resp.Message != "ok" || len(resp.RawResponse) == 0 {
+		t.Errorf("unexpected response: %+v", resp)
 	}
 }
 
@@ -88,7 +92,7 @@ func TestGatewayB_ProcessWithdrawal_Failure(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	_, err := g.ProcessWithdrawal(nil)
+	_, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway B failure" {
 		t.Errorf("expected gateway B failure error, got %v", err)
 	}
@@ -101,8 +105,236 @@ func TestGatewayB_ProcessWithdrawal_Timeout(t *testing.T) {
 	defer ts.Close()
 
 	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
-	_, err := g.ProcessWithdrawal(nil)
+	_, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest())
 	if err == nil || err.Error() != "gateway B timeout" {
 		t.Errorf("expected gateway B timeout error, got %v", err)
 	}
 }
+
+func TestGatewayB_SOAPBinding(t *testing.T) {
+	tests := []struct {
+		name        string
+		settings    GatewayBSettings
+		wantNS      string
+		contentType string
+		soapAction  string
+	}{
+		{"soap 1.1", GatewayBSettings{}, dtos.SOAP11Namespace, "text/xml; charset=utf-8", `"urn:gatewayb:payments/Deposit"`},
+		{"soap 1.2", GatewayBSettings{SOAPVersion: SOAP12}, dtos.SOAP12Namespace, `application/soap+xml; charset=utf-8; action="urn:gatewayb:payments/Deposit"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got dtos.SOAPEnvelope
+			var header http.Header
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				xml.NewDecoder(r.Body).Decode(&got)
+				xml.NewEncoder(w).Encode(dtos.SOAPEnvelope{
+					XMLName: xml.Name{Space: tt.wantNS},
+					Body:    dtos.SOAPBody{Response: &dtos.SOAPResponse{Status: "success", Reference: "ref-1"}},
+				})
+			}))
+			defer ts.Close()
+
+			g := NewGatewayBWithSettings(ts.URL, "gatewayB", getTestResilienceConfig(), tt.settings)
+			if _, err := g.ProcessDeposit(context.Background(), testPaymentRequest()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.XMLName.Space != tt.wantNS || got.Body.DepositRequest == nil || got.Body.DepositRequest.TransactionID != "tx-1" {
+				t.Errorf("unexpected envelope %+v", got)
+			}
+			if got.Header != nil {
+				t.Errorf("expected no header without credentials, got %+v", got.Header)
+			}
+			if header.Get("Content-Type") != tt.contentType || header.Get("SOAPAction") != tt.soapAction {
+				t.Errorf("unexpected headers %v", header)
+			}
+		})
+	}
+}
+
+func TestGatewayB_AnswerInOtherSOAPVersion(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		xml.NewEncoder(w).Encode(dtos.SOAPEnvelope{
+			XMLName: xml.Name{Space: dtos.SOAP11Namespace},
+			Body:    dtos.SOAPBody{Response: &dtos.SOAPResponse{Status: "success"}},
+		})
+	}))
+	defer ts.Close()
+
+	g := NewGatewayBWithSettings(ts.URL, "gatewayB", getTestResilienceConfig(), GatewayBSettings{SOAPVersion: SOAP12})
+	if _, err := g.ProcessDeposit(context.Background(), testPaymentRequest()); !errors.Is(err, apperrors.ErrProcessingFailed) {
+		t.Errorf("expected ErrProcessingFailed, got %v", err)
+	}
+}
+
+func TestGatewayB_WSSecurity(t *testing.T) {
+	for _, passwordType := range []string{PasswordText, PasswordDigest} {
+		t.Run(passwordType, func(t *testing.T) {
+			var got dtos.SOAPEnvelope
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				xml.NewDecoder(r.Body).Decode(&got)
+				xml.NewEncoder(w).Encode(dtos.SOAPEnvelope{Body: dtos.SOAPBody{Response: &dtos.SOAPResponse{Status: "success"}}})
+			}))
+			defer ts.Close()
+
+			settings := GatewayBSettings{Security: WSSecuritySettings{Username: "merchant", Password: "secret", PasswordType: passwordType}}
+			g := NewGatewayBWithSettings(ts.URL, "gatewayB", getTestResilienceConfig(), settings)
+			if _, err := g.ProcessWithdrawal(context.Background(), testPaymentRequest()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got.Header == nil || got.Header.Security == nil || got.Header.Security.UsernameToken == nil {
+				t.Fatalf("expected a UsernameToken, got %+v", got.Header)
+			}
+			token := got.Header.Security.UsernameToken
+			if token.Username != "merchant" || token.Created == "" || token.Nonce == nil {
+				t.Fatalf("unexpected token %+v", token)
+			}
+			want := dtos.WSSPassword{Type: dtos.WSSPasswordText, Value: "secret"}
+			if passwordType == PasswordDigest {
+				nonce, err := base64.StdEncoding.DecodeString(token.Nonce.Value)
+				if err != nil {
+					t.Fatalf("invalid nonce: %v", err)
+				}
+				want = dtos.WSSPassword{Type: dtos.WSSPasswordDigest, Value: PasswordDigestValue(nonce, token.Created, "secret")}
+			}
+			if token.Password != want {
+				t.Errorf("password %+v, want %+v", token.Password, want)
+			}
+		})
+	}
+}
+
+func TestGatewayB_SOAPFaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantCode     string
+		wantSubcode  string
+		wantReason   constants.ReasonCode
+		wantFailover bool
+	}{
+		{
+			"soap 1.1 server fault", http.StatusInternalServerError,
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+				`<faultcode>soap:Server</faultcode><faultstring>maintenance</faultstring>` +
+				`<detail><b:PaymentFault xmlns:b="urn:gatewayb:payments"><b:Code>B091</b:Code></b:PaymentFault></detail>` +
+				`</soap:Fault></soap:Body></soap:Envelope>`,
+			"Server", "", constants.ReasonGatewayUnavailable, false,
+		},
+		{
+			"soap 1.1 client fault", http.StatusInternalServerError,
+			`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+				`<faultcode>soap:Client</faultcode><faultstring>bad credentials</faultstring></soap:Fault></soap:Body></soap:Envelope>`,
+			"Client", "", constants.ReasonGatewayError, true,
+		},
+		{
+			"soap 1.2 sender fault", http.StatusBadRequest,
+			`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>` +
+				`<env:Code><env:Value>env:Sender</env:Value><env:Subcode><env:Value>wsse:FailedAuthentication</env:Value></env:Subcode></env:Code>` +
+				`<env:Reason><env:Text xml:lang="en">bad credentials</env:Text></env:Reason></env:Fault></env:Body></env:Envelope>`,
+			"Sender", "FailedAuthentication", constants.ReasonGatewayError, true,
+		},
+		{
+			"fault with http 200", http.StatusOK,
+			`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body><env:Fault>` +
+				`<env:Code><env:Value>env:Receiver</env:Value></env:Code><env:Reason><env:Text xml:lang="en">down</env:Text></env:Reason>` +
+				`<env:Detail><PaymentFault xmlns="urn:gatewayb:payments"><Code>B091</Code></PaymentFault></env:Detail></env:Fault></env:Body></env:Envelope>`,
+			"Receiver", "", constants.ReasonGatewayUnavailable, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
+			_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+			var fault *SOAPFaultError
+			if !errors.As(err, &fault) {
+				t.Fatalf("expected a SOAPFaultError, got %v", err)
+			}
+			if fault.Code != tt.wantCode || fault.Subcode != tt.wantSubcode || fault.Reason == "" {
+				t.Errorf("unexpected fault %+v", fault)
+			}
+			if !errors.Is(err, apperrors.ErrProcessingFailed) || ReasonForError(err) != tt.wantReason {
+				t.Errorf("unexpected classification of %v: reason %s", err, ReasonForError(err))
+			}
+			if SafeToFailover(err) != tt.wantFailover {
+				t.Errorf("SafeToFailover = %v, want %v", !tt.wantFailover, tt.wantFailover)
+			}
+			// A fault is the gateway's answer, not a transient failure to retry
+			if calls != 1 {
+				t.Errorf("expected one call, got %d", calls)
+			}
+		})
+	}
+}
+
+func TestGatewayB_SenderFaultWithDeclineCode(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><soap:Fault>` +
+			`<faultcode>soap:Client</faultcode><faultstring>insufficient funds</faultstring>` +
+			`<detail><b:PaymentFault xmlns:b="urn:gatewayb:payments"><b:Code>B051</b:Code></b:PaymentFault></detail>` +
+			`</soap:Fault></soap:Body></soap:Envelope>`))
+	}))
+	defer ts.Close()
+
+	g := NewGatewayB(ts.URL, "gatewayB", getTestResilienceConfig())
+	result, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+	if err != nil {
+		t.Fatalf("expected a declined result rather than a failover, got %v (failover %v)", err, SafeToFailover(err))
+	}
+	if result.Outcome != OutcomeDeclined || result.Reason != constants.ReasonInsufficientFunds || result.GatewayCode != "B051" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestGatewayB_FaultsFromMockGateway(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/deposit", mockgateway.GatewayBMockDepositHandler)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	req := testPaymentRequest()
+	req.Account = "fault-1"
+	for _, version := range []string{SOAP11, SOAP12} {
+		g := NewGatewayBWithSettings(ts.URL, "gatewayB", getTestResilienceConfig(), GatewayBSettings{SOAPVersion: version})
+		_, err := g.ProcessDeposit(context.Background(), req)
+		var fault *SOAPFaultError
+		if !errors.As(err, &fault) || fault.DetailCode != "B091" || ReasonForError(err) != constants.ReasonGatewayUnavailable {
+			t.Errorf("SOAP %s: expected a B091 fault, got %v", version, err)
+		}
+	}
+
+	// A token without a password is rejected before processing
+	g := NewGatewayBWithSettings(ts.URL, "gatewayB", getTestResilienceConfig(), GatewayBSettings{
+		SOAPVersion: SOAP12,
+		Security:    WSSecuritySettings{Username: "merchant"},
+	})
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+	var fault *SOAPFaultError
+	if !errors.As(err, &fault) || fault.Code != "Sender" || !SafeToFailover(err) {
+		t.Errorf("expected a Sender fault, got %v", err)
+	}
+}
+
+func TestGatewayB_InvalidSettings(t *testing.T) {
+	tests := map[string]GatewayBSettings{
+		"version":       {SOAPVersion: "2.0"},
+		"password type": {Security: WSSecuritySettings{Username: "u", Password: "p", PasswordType: "plain"}},
+		"no username":   {Security: WSSecuritySettings{Password: "p"}},
+	}
+	for name, settings := range tests {
+		if err := settings.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
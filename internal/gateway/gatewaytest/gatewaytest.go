@@ -0,0 +1,63 @@
+// Package gatewaytest builds gateways in the tests of the gateway adapters.
+package gatewaytest
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"io"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings parses YAML into a gateway's settings block; an empty string
+// leaves the block unset.
+func Settings(t testing.TB, settings string) yaml.Node {
+	t.Helper()
+	if settings == "" {
+		return yaml.Node{}
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(settings), &doc); err != nil {
+		t.Fatalf("invalid test settings: %v", err)
+	}
+	return *doc.Content[0]
+}
+
+// Config configures a GatewayUnderTest using adapter at url, with settings
+// given as YAML and timeouts and retries short enough for tests.
+func Config(t testing.TB, adapter, url, settings string) config.GatewayConfig {
+	t.Helper()
+	return config.GatewayConfig{
+		Type: adapter,
+		URL:  url,
+		Name: "GatewayUnderTest",
+		Resilience: config.ResilienceConfig{
+			HTTPTimeoutSeconds:   1,
+			MaxRetries:           2,
+			InitialBackoffMillis: 10,
+			MaxBackoffMillis:     20,
+		},
+		Settings: Settings(t, settings),
+	}
+}
+
+// New builds the gateway configured under key, failing the test if it cannot,
+// and closes it when the test ends if it holds resources.
+func New(t testing.TB, key string, cfg config.GatewayConfig) gateway.PaymentGateway {
+	t.Helper()
+	gw, err := gateway.New(key, cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if c, ok := gw.(io.Closer); ok {
+		t.Cleanup(func() { c.Close() })
+	}
+	return gw
+}
+
+// PaymentRequest is a EUR payment of amount from account, whose transaction
+// ID is "tx-" followed by the account.
+func PaymentRequest(account string, amount float64) gateway.PaymentRequest {
+	return gateway.PaymentRequest{TransactionID: "tx-" + account, Account: account, Amount: amount, Currency: "EUR"}
+}
@@ -0,0 +1,328 @@
+// Package grpcgw is a gateway adapter for providers that implement the
+// PaymentService of paymentpb/payments.proto. Gateways use it with
+// type: grpc.
+package grpcgw
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/grpcgw/paymentpb"
+	"Payment-Gateway/internal/resilience"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// IdempotencyKeyMetadata carries the transaction ID with every call.
+const IdempotencyKeyMetadata = "idempotency-key"
+
+// retryPushbackMetadata is the trailer in which a gRPC server asks clients to
+// wait before retrying, in milliseconds.
+const retryPushbackMetadata = "grpc-retry-pushback-ms"
+
+func init() {
+	gateway.Register("grpc", gateway.WithSettings(nil, New))
+}
+
+// Gateway sends payments to a gRPC provider.
+type Gateway struct {
+	name       string
+	conn       *grpc.ClientConn
+	client     paymentpb.PaymentServiceClient
+	Resilience *resilience.Executor
+
+	metadata []string
+	codes    map[string]constants.ReasonCode
+}
+
+// New builds the gateway configured under key from validated settings. The
+// connection is established lazily on the first call.
+func New(key string, cfg config.GatewayConfig, settings Settings) (gateway.PaymentGateway, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	creds, err := transportCredentials(settings.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings: %w", err)
+	}
+	// Retries stay with the resilience executor so the circuit breaker sees
+	// every attempt
+	conn, err := grpc.NewClient(cfg.URL, grpc.WithTransportCredentials(creds), grpc.WithDisableRetry())
+	if err != nil {
+		return nil, err
+	}
+	g := &Gateway{
+		name:       cfg.Name,
+		conn:       conn,
+		client:     paymentpb.NewPaymentServiceClient(conn),
+		Resilience: resilience.NewExecutor(cfg.Name, cfg.Resilience),
+		codes:      make(map[string]constants.ReasonCode, len(settings.DeclineCodes)),
+	}
+	for key, value := range settings.Metadata {
+		g.metadata = append(g.metadata, key, os.ExpandEnv(value))
+	}
+	for code, reason := range settings.DeclineCodes {
+		g.codes[strings.ToUpper(code)] = reason
+	}
+	return g, nil
+}
+
+func transportCredentials(s TLSSettings) (credentials.TransportCredentials, error) {
+	if !s.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	tlsCfg := &tls.Config{ServerName: s.ServerName, MinVersion: tls.VersionTLS12}
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.caFile: %w", err)
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls.caFile: no certificates in %s", s.CAFile)
+		}
+	}
+	if s.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.certFile: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// ProcessDeposit calls the provider's Deposit method.
+func (g *Gateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "deposit", req.TransactionID, func(ctx context.Context, opts ...grpc.CallOption) (*paymentpb.PaymentResponse, error) {
+		return g.client.Deposit(ctx, toProto(req), opts...)
+	})
+}
+
+// ProcessWithdrawal calls the provider's Withdrawal method.
+func (g *Gateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "withdrawal", req.TransactionID, func(ctx context.Context, opts ...grpc.CallOption) (*paymentpb.PaymentResponse, error) {
+		return g.client.Withdrawal(ctx, toProto(req), opts...)
+	})
+}
+
+// PaymentStatus asks the provider for the current answer to a payment sent
+// earlier, such as one left pending.
+func (g *Gateway) PaymentStatus(ctx context.Context, transactionID string) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "status", transactionID, func(ctx context.Context, opts ...grpc.CallOption) (*paymentpb.PaymentResponse, error) {
+		return g.client.GetPaymentStatus(ctx, &paymentpb.PaymentStatusRequest{TransactionId: transactionID}, opts...)
+	})
+}
+
+// HealthCheck asks the provider's standard gRPC health service whether the
+// PaymentService is serving. Like HTTP probes it bypasses the executor.
+func (g *Gateway) HealthCheck(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(g.conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: paymentpb.PaymentService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health check returned %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Name returns the gateway's configured name.
+func (g *Gateway) Name() string {
+	return g.name
+}
+
+// Available reports whether the gateway's circuit breaker lets calls through.
+func (g *Gateway) Available() bool {
+	return g.Resilience.Available()
+}
+
+// Close releases the client connection, so closeGateways releases it on shutdown.
+func (g *Gateway) Close() error {
+	return g.conn.Close()
+}
+
+func toProto(req gateway.PaymentRequest) *paymentpb.PaymentRequest {
+	return &paymentpb.PaymentRequest{
+		TransactionId: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		MerchantId:    req.MerchantID,
+		Metadata:      req.Metadata,
+	}
+}
+
+// rpc is one call of a PaymentService method.
+type rpc func(ctx context.Context, opts ...grpc.CallOption) (*paymentpb.PaymentResponse, error)
+
+// send makes the call through the executor and converts the answer into a
+// PaymentResult. Failures where no attempt can have been processed are tagged
+// with gateway.ErrNotProcessed.
+func (g *Gateway) send(ctx context.Context, operation, transactionID string, call rpc) (*gateway.PaymentResult, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "grpcgw.Gateway.send"),
+		zap.String("gateway", g.name),
+		zap.String("operation", operation),
+		zap.String("transaction_id", transactionID),
+	)
+
+	md := append([]string{IdempotencyKeyMetadata, transactionID}, g.metadata...)
+	ctx = metadata.AppendToOutgoingContext(ctx, md...)
+
+	log.Info("Sending request to gateway")
+	var resp *paymentpb.PaymentResponse
+	refused := true // no attempt so far may have been processed
+	err := g.Resilience.Execute(ctx, func(ctx context.Context) error {
+		var trailer metadata.MD
+		var err error
+		resp, err = call(ctx, grpc.Trailer(&trailer))
+		if err == nil {
+			refused = false
+			return nil
+		}
+		code := status.Code(err)
+		if !refusedCode(code) {
+			refused = false
+		}
+		if !retryableCode(code) {
+			return resilience.Permanent(err)
+		}
+		if after, ok := retryPushback(trailer); ok {
+			return resilience.RetryAfter(err, after)
+		}
+		return err
+	})
+	if err != nil {
+		log.Error("Gateway request error", zap.String("grpc_code", status.Code(err).String()), zap.Error(err))
+		err = g.failure(err)
+		if refused {
+			return nil, gateway.NotProcessed(err)
+		}
+		return nil, err
+	}
+
+	result, err := g.result(resp, transactionID)
+	if err != nil {
+		log.Error("Unusable gateway response", zap.Error(err))
+		return nil, err
+	}
+	log.Info("Gateway request completed",
+		zap.String("gateway_status", resp.GetStatus().String()),
+		zap.String("gateway_code", result.GatewayCode),
+		zap.String("gateway_ref", result.GatewayRef),
+	)
+	return result, nil
+}
+
+// failure converts the executor's last error into the application's gateway errors.
+func (g *Gateway) failure(err error) error {
+	if errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+		// The circuit breaker or bulkhead turned the call away
+		return err
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return apperrors.WithMessage(apperrors.ErrGatewayTimeout, g.name+" timeout")
+	case codes.Unavailable, codes.ResourceExhausted:
+		return fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
+	default:
+		return fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err)
+	}
+}
+
+var outcomes = map[paymentpb.PaymentStatus]gateway.Outcome{
+	paymentpb.PaymentStatus_PAYMENT_STATUS_APPROVED: gateway.OutcomeApproved,
+	paymentpb.PaymentStatus_PAYMENT_STATUS_DECLINED: gateway.OutcomeDeclined,
+	paymentpb.PaymentStatus_PAYMENT_STATUS_PENDING:  gateway.OutcomePending,
+}
+
+func (g *Gateway) result(resp *paymentpb.PaymentResponse, transactionID string) (*gateway.PaymentResult, error) {
+	if id := resp.GetTransactionId(); id != "" && id != transactionID {
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("%s answered for transaction %q", g.name, id))
+	}
+	outcome, ok := outcomes[resp.GetStatus()]
+	if !ok {
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("%s returned unknown status %s", g.name, resp.GetStatus()))
+	}
+	raw, _ := protojson.Marshal(resp)
+	result := &gateway.PaymentResult{
+		Outcome:     outcome,
+		GatewayRef:  resp.GetReference(),
+		GatewayCode: resp.GetCode(),
+		Message:     resp.GetMessage(),
+		RawResponse: raw,
+	}
+	if outcome == gateway.OutcomeDeclined {
+		result.Reason = g.reason(resp.GetCode())
+	}
+	return result, nil
+}
+
+// reason maps a decline code through DeclineCodes, then as a spelled-out
+// normalized reason, and falls back to a generic decline.
+func (g *Gateway) reason(code string) constants.ReasonCode {
+	code = strings.TrimSpace(code)
+	if reason, ok := g.codes[strings.ToUpper(code)]; ok {
+		return reason
+	}
+	if reason := constants.ReasonCode(strings.ToLower(code)); gateway.KnownReason(reason) {
+		return reason
+	}
+	return constants.ReasonDeclined
+}
+
+// retryableCode reports status codes worth another attempt, the gRPC
+// counterparts of the HTTP statuses SendHTTP retries.
+func retryableCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// refusedCode reports status codes with which the provider turned a call
+// away before processing it: it was unreachable or overloaded, or rejected
+// the request itself.
+func refusedCode(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.ResourceExhausted, codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// retryPushback reads the delay a server asked for before the next attempt.
+func retryPushback(trailer metadata.MD) (time.Duration, bool) {
+	values := trailer.Get(retryPushbackMetadata)
+	if len(values) == 0 {
+		return 0, false
+	}
+	ms, err := strconv.Atoi(values[0])
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
@@ -0,0 +1,245 @@
+package grpcgw
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/gatewaytest"
+	"Payment-Gateway/internal/gateway/grpcgw/paymentpb"
+	mockgateway "Payment-Gateway/internal/handler/mock_gateway"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// serve runs srv on a local port until the test ends and returns its address.
+func serve(t *testing.T, srv *grpc.Server) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func newGateway(t *testing.T, url string) *Gateway {
+	t.Helper()
+	t.Setenv("GRPC_TEST_TOKEN", "t-1")
+	return gatewaytest.New(t, "gatewayGrpc", gatewaytest.Config(t, "grpc", url, `metadata: {authorization: "Bearer ${GRPC_TEST_TOKEN}"}`)).(*Gateway)
+}
+
+func TestGateway_MockProvider(t *testing.T) {
+	gw := newGateway(t, serve(t, mockgateway.NewGRPCServer()))
+	tests := []struct {
+		account     string
+		wantOutcome gateway.Outcome
+		wantReason  constants.ReasonCode
+	}{
+		{"acc-1", gateway.OutcomeApproved, ""},
+		{"nsf-1", gateway.OutcomeDeclined, constants.ReasonInsufficientFunds},
+		{"decline-1", gateway.OutcomeDeclined, constants.ReasonDoNotHonor},
+		{"pending-1", gateway.OutcomePending, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.account, func(t *testing.T) {
+			result, err := gw.ProcessWithdrawal(context.Background(), gatewaytest.PaymentRequest(tt.account, 12.34))
+			if err != nil {
+				t.Fatalf("ProcessWithdrawal: %v", err)
+			}
+			if result.Outcome != tt.wantOutcome || result.Reason != tt.wantReason || !strings.HasPrefix(result.GatewayRef, "G-") {
+				t.Errorf("unexpected result %+v", result)
+			}
+			// The provider replays its first answer for the transaction
+			status, err := gw.PaymentStatus(context.Background(), "tx-"+tt.account)
+			if err != nil {
+				t.Fatalf("PaymentStatus: %v", err)
+			}
+			if status.Outcome != result.Outcome || status.GatewayRef != result.GatewayRef {
+				t.Errorf("status %+v does not match result %+v", status, result)
+			}
+		})
+	}
+
+	if err := gateway.ProbeFor(gw, nil, "")(context.Background()); err != nil {
+		t.Errorf("expected the gRPC health check to pass, got %v", err)
+	}
+}
+
+func TestGateway_CloseReleasesConnection(t *testing.T) {
+	gw := newGateway(t, serve(t, mockgateway.NewGRPCServer()))
+	if err := gw.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+
+	var closer io.Closer = gw // closeGateways only closes io.Closers
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := gw.HealthCheck(context.Background()); status.Code(err) != codes.Canceled {
+		t.Errorf("expected calls on a closed connection to be cancelled, got %v", err)
+	}
+}
+
+func TestGateway_MockProviderFailures(t *testing.T) {
+	addr := serve(t, mockgateway.NewGRPCServer())
+
+	_, err := newGateway(t, addr).ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("fault-1", 12.34))
+	if !errors.Is(err, apperrors.ErrGatewayNotAvailable) || !gateway.SafeToFailover(err) {
+		t.Errorf("expected an unavailable, not processed failure, got %v", err)
+	}
+
+	gw := gatewaytest.New(t, "gatewayGrpc", gatewaytest.Config(t, "grpc", addr, ""))
+	_, err = gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-2", 12.34))
+	if status.Code(err) != codes.Unauthenticated || !gateway.SafeToFailover(err) {
+		t.Errorf("expected an unauthenticated, not processed failure, got %v", err)
+	}
+}
+
+// fakeProvider answers every call with reply, counting attempts.
+type fakeProvider struct {
+	paymentpb.UnimplementedPaymentServiceServer
+	calls atomic.Int32
+	key   atomic.Value
+	reply func(call int32) (*paymentpb.PaymentResponse, error)
+}
+
+func (p *fakeProvider) Deposit(ctx context.Context, req *paymentpb.PaymentRequest) (*paymentpb.PaymentResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get(IdempotencyKeyMetadata); len(keys) > 0 {
+		p.key.Store(keys[0])
+	}
+	return p.reply(p.calls.Add(1))
+}
+
+func TestGateway_StatusCodes(t *testing.T) {
+	approved := &paymentpb.PaymentResponse{Status: paymentpb.PaymentStatus_PAYMENT_STATUS_APPROVED, Reference: "ref"}
+	tests := []struct {
+		name         string
+		reply        func(call int32) (*paymentpb.PaymentResponse, error)
+		wantCalls    int32
+		wantErr      error
+		wantFailover bool
+	}{
+		{"retried until approved", func(call int32) (*paymentpb.PaymentResponse, error) {
+			if call < 3 {
+				return nil, status.Error(codes.Unavailable, "busy")
+			}
+			return approved, nil
+		}, 3, nil, false},
+		{"unavailable throughout", func(int32) (*paymentpb.PaymentResponse, error) {
+			return nil, status.Error(codes.ResourceExhausted, "busy")
+		}, 3, apperrors.ErrGatewayNotAvailable, true},
+		{"internal error", func(int32) (*paymentpb.PaymentResponse, error) {
+			return nil, status.Error(codes.Internal, "boom")
+		}, 3, apperrors.ErrProcessingFailed, false},
+		{"invalid argument is not retried", func(int32) (*paymentpb.PaymentResponse, error) {
+			return nil, status.Error(codes.InvalidArgument, "bad amount")
+		}, 1, apperrors.ErrProcessingFailed, true},
+		{"failed precondition", func(int32) (*paymentpb.PaymentResponse, error) {
+			return nil, status.Error(codes.FailedPrecondition, "frozen")
+		}, 1, apperrors.ErrProcessingFailed, false},
+		{"unspecified status", func(int32) (*paymentpb.PaymentResponse, error) {
+			return &paymentpb.PaymentResponse{Reference: "ref"}, nil
+		}, 1, apperrors.ErrProcessingFailed, false},
+		{"other transaction", func(int32) (*paymentpb.PaymentResponse, error) {
+			return &paymentpb.PaymentResponse{TransactionId: "tx-other", Status: paymentpb.PaymentStatus_PAYMENT_STATUS_APPROVED}, nil
+		}, 1, apperrors.ErrProcessingFailed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeProvider{reply: tt.reply}
+			srv := grpc.NewServer()
+			paymentpb.RegisterPaymentServiceServer(srv, provider)
+			gw := newGateway(t, serve(t, srv))
+
+			result, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34))
+			if got := provider.calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, got)
+			}
+			if key, _ := provider.key.Load().(string); key != "tx-acc-1" {
+				t.Errorf("expected the transaction ID as idempotency key, got %q", key)
+			}
+			if tt.wantErr == nil {
+				if err != nil || result.Outcome != gateway.OutcomeApproved || result.GatewayRef != "ref" {
+					t.Fatalf("expected approval, got %+v, %v", result, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if gateway.SafeToFailover(err) != tt.wantFailover {
+				t.Errorf("SafeToFailover = %v, want %v (%v)", !tt.wantFailover, tt.wantFailover, err)
+			}
+		})
+	}
+}
+
+func TestGateway_DeclineCodes(t *testing.T) {
+	provider := &fakeProvider{}
+	srv := grpc.NewServer()
+	paymentpb.RegisterPaymentServiceServer(srv, provider)
+	gw := gatewaytest.New(t, "gatewayGrpc", gatewaytest.Config(t, "grpc", serve(t, srv), `declineCodes: {"05": do_not_honor}`))
+	tests := map[string]constants.ReasonCode{
+		"05":              constants.ReasonDoNotHonor,
+		"limit_exceeded":  constants.ReasonLimitExceeded,
+		"LIMIT_EXCEEDED ": constants.ReasonLimitExceeded,
+		"X99":             constants.ReasonDeclined,
+		"":                constants.ReasonDeclined,
+	}
+	for code, want := range tests {
+		provider.reply = func(int32) (*paymentpb.PaymentResponse, error) {
+			return &paymentpb.PaymentResponse{Status: paymentpb.PaymentStatus_PAYMENT_STATUS_DECLINED, Code: code}, nil
+		}
+		result, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34))
+		if err != nil {
+			t.Fatalf("ProcessDeposit: %v", err)
+		}
+		if result.Reason != want {
+			t.Errorf("code %q: reason %s, want %s", code, result.Reason, want)
+		}
+	}
+}
+
+func TestGateway_UnreachableIsNotProcessed(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	_, err = newGateway(t, addr).ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34))
+	if !errors.Is(err, apperrors.ErrGatewayNotAvailable) || !gateway.SafeToFailover(err) {
+		t.Errorf("expected an unavailable, not processed failure, got %v", err)
+	}
+}
+
+func TestNew_InvalidSettings(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":      `timeout: 3`,
+		"tls disabled":     `tls: {caFile: ca.pem}`,
+		"cert without key": `tls: {enabled: true, certFile: c.pem}`,
+		"missing ca file":  `tls: {enabled: true, caFile: /nonexistent/ca.pem}`,
+		"metadata key":     `metadata: {Authorization: x}`,
+		"decline reason":   `declineCodes: {NSF: broke}`,
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := gateway.New("gatewayGrpc", gatewaytest.Config(t, "grpc", "localhost:1", settings)); err == nil || !strings.Contains(err.Error(), "invalid settings") {
+				t.Errorf("expected an invalid settings error, got %v", err)
+			}
+		})
+	}
+}
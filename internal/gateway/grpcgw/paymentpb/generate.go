@@ -0,0 +1,5 @@
+// Package paymentpb holds the code generated from payments.proto, the
+// service gRPC providers implement for the grpc gateway adapter.
+package paymentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative payments.proto
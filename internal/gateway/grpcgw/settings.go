@@ -0,0 +1,55 @@
+package grpcgw
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+	"strings"
+)
+
+// Settings configure a gRPC provider under a gateway's settings block. The
+// gateway's url is the gRPC target, e.g. "payments.example.com:443".
+type Settings struct {
+	TLS TLSSettings `yaml:"tls"`
+	// Metadata is sent with every call; ${VAR} is replaced from the
+	// environment so credentials stay out of config.yaml.
+	Metadata map[string]string `yaml:"metadata"`
+	// DeclineCodes maps the provider's decline codes, case-insensitively, to
+	// normalized reasons. Codes spelled like a normalized reason, such as
+	// INSUFFICIENT_FUNDS, map to it without an entry; others are declined.
+	DeclineCodes map[string]constants.ReasonCode `yaml:"declineCodes"`
+}
+
+// TLSSettings secure the connection; without them it is plaintext, which is
+// only meant for local providers such as the mock.
+type TLSSettings struct {
+	Enabled bool `yaml:"enabled"`
+	// CAFile verifies the provider against these roots instead of the system's.
+	CAFile string `yaml:"caFile"`
+	// CertFile and KeyFile present a client certificate for mutual TLS.
+	CertFile   string `yaml:"certFile"`
+	KeyFile    string `yaml:"keyFile"`
+	ServerName string `yaml:"serverName"`
+}
+
+// Validate checks the settings after they were decoded.
+func (s Settings) Validate() error {
+	t := s.TLS
+	if !t.Enabled && (t.CAFile != "" || t.CertFile != "" || t.KeyFile != "" || t.ServerName != "") {
+		return fmt.Errorf("tls options require tls.enabled")
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls.certFile and tls.keyFile must be set together")
+	}
+	for key := range s.Metadata {
+		if key == "" || key != strings.ToLower(key) || strings.HasPrefix(key, "grpc-") {
+			return fmt.Errorf("metadata key %q must be lowercase and not start with grpc-", key)
+		}
+	}
+	for code, reason := range s.DeclineCodes {
+		if !gateway.KnownReason(reason) {
+			return fmt.Errorf("declineCodes.%s: unknown reason %q", code, reason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// AvailabilityReporter is implemented by gateways that know when they are
+// refusing calls, e.g. while their circuit breaker is open.
+type AvailabilityReporter interface {
+	Available() bool
+}
+
+// IsAvailable reports whether gw is accepting calls. Gateways that do not
+// implement AvailabilityReporter are always available.
+func IsAvailable(gw PaymentGateway) bool {
+	if r, ok := gw.(AvailabilityReporter); ok {
+		return r.Available()
+	}
+	return true
+}
+
+// Probe checks a gateway's health out of band; a nil error means healthy.
+type Probe func(ctx context.Context) error
+
+// HealthChecker is implemented by gateways whose health is checked over their
+// own protocol rather than with an HTTP GET, such as gRPC providers.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// ProbeFor returns gw's own health check when it has one, and otherwise an
+// HTTP probe of url.
+func ProbeFor(gw PaymentGateway, client *http.Client, url string) Probe {
+	if c, ok := gw.(HealthChecker); ok {
+		return c.HealthCheck
+	}
+	return NewHTTPProbe(client, url)
+}
+
+// NewHTTPProbe returns a Probe that GETs url and treats any 2xx answer as
+// healthy. Probes bypass the resilience executor so they neither count
+// against the circuit breaker nor wait for it to close.
+func NewHTTPProbe(client *http.Client, url string) Probe {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("health check returned status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
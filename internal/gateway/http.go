@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/resilience"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IdempotencyKeyHeader carries a key that is stable across retries of one
+// transaction so the gateway can recognise a replay and not charge twice.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// HTTPRequest is one gateway call; Header is sent as is on every attempt.
+type HTTPRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+	// Final, when set, reports whether a reply with a retryable status is
+	// nonetheless the gateway's final answer, such as a SOAP Fault.
+	Final func(reply *HTTPReply) bool
+}
+
+// HTTPReply is a gateway HTTP response read in full inside one attempt.
+type HTTPReply struct {
+	StatusCode int
+	Body       []byte
+	// Refused is set when every attempt was turned away with 429 or 503, so
+	// the gateway did not process the payment.
+	Refused bool
+}
+
+// retryableStatusError reports a response status worth retrying.
+type retryableStatusError struct {
+	StatusCode int
+}
+
+func (e *retryableStatusError) Error() string {
+	return fmt.Sprintf("gateway returned retryable status %d", e.StatusCode)
+}
+
+// post sends payload to url with the Content-Type and idempotency key headers.
+func post(ctx context.Context, client *http.Client, exec *resilience.Executor, url, contentType, idempotencyKey string, payload []byte) (*HTTPReply, error) {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	if idempotencyKey != "" {
+		header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	return SendHTTP(ctx, client, exec, HTTPRequest{Method: http.MethodPost, URL: url, Header: header, Body: payload})
+}
+
+// SendHTTP sends req through the executor, rebuilding the request for every
+// attempt. Transport errors, 408, 429 and 5xx responses are retried,
+// honouring Retry-After; other statuses are returned to the caller as is. When
+// retries run out on a retryable status the last reply is returned without an
+// error so adapters report it like any other unexpected answer. Failures where
+// no attempt can have been processed are tagged with ErrNotProcessed.
+func SendHTTP(ctx context.Context, client *http.Client, exec *resilience.Executor, req HTTPRequest) (*HTTPReply, error) {
+	var reply *HTTPReply
+	refused := true // no attempt so far may have been processed
+	err := exec.Execute(ctx, func(ctx context.Context) error {
+		httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+		if err != nil {
+			return resilience.Permanent(err)
+		}
+		for key, values := range req.Header {
+			httpReq.Header[key] = values
+		}
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			if !isDialError(err) {
+				refused = false
+			}
+			return err
+		}
+		defer resp.Body.Close()
+		if !isRefusedStatus(resp.StatusCode) {
+			refused = false
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		reply = &HTTPReply{StatusCode: resp.StatusCode, Body: body, Refused: refused}
+
+		if !isRetryableStatus(resp.StatusCode) || (req.Final != nil && req.Final(reply)) {
+			return nil
+		}
+		statusErr := &retryableStatusError{StatusCode: resp.StatusCode}
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return resilience.RetryAfter(statusErr, after)
+		}
+		return statusErr
+	})
+	var statusErr *retryableStatusError
+	if errors.As(err, &statusErr) {
+		return reply, nil
+	}
+	if err != nil && refused {
+		return reply, NotProcessed(err)
+	}
+	return reply, err
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+}
+
+// isRefusedStatus reports statuses with which a gateway declines to process a
+// request at all, as opposed to failing part way through.
+func isRefusedStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After value given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/config"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func retryTestConfig() *config.ResilienceConfig {
+	return &config.ResilienceConfig{
+		HTTPTimeoutSeconds:   2,
+		MaxRetries:           2,
+		InitialBackoffMillis: 1,
+		MaxBackoffMillis:     2,
+	}
+}
+
+// recordingServer answers with statuses in order (the last one repeats) and
+// records every request body and idempotency key it receives.
+type recordingServer struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	bodies   []string
+	keys     []string
+}
+
+func (s *recordingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.bodies = append(s.bodies, string(body))
+	s.keys = append(s.keys, r.Header.Get(IdempotencyKeyHeader))
+	status := s.statuses[0]
+	if len(s.statuses) > 1 {
+		s.statuses = s.statuses[1:]
+	}
+	s.mu.Unlock()
+
+	for k, v := range s.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	}
+}
+
+func TestPost_RetryClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"retries 503 until success", []int{http.StatusServiceUnavailable, http.StatusOK}, 2, false},
+		{"retries 429", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"does not retry 400", []int{http.StatusBadRequest}, 1, true},
+		{"does not retry 422", []int{http.StatusUnprocessableEntity}, 1, true},
+		{"gives up on persistent 500", []int{http.StatusInternalServerError}, 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &recordingServer{statuses: tt.statuses}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+
+			g := NewGatewayA(ts.URL, "gatewayA", retryTestConfig())
+			_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && err.Error() != "gateway A failure" {
+				t.Errorf("expected gateway A failure, got %v", err)
+			}
+			if len(srv.bodies) != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, len(srv.bodies))
+			}
+		})
+	}
+}
+
+func TestPost_ReplaysSameBodyAndIdempotencyKey(t *testing.T) {
+	srv := &recordingServer{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusOK}}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	g := NewGatewayA(ts.URL, "gatewayA", retryTestConfig())
+	if _, err := g.ProcessDeposit(context.Background(), testPaymentRequest()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(srv.bodies) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(srv.bodies))
+	}
+	for i := range srv.bodies {
+		if srv.bodies[i] == "" || srv.bodies[i] != srv.bodies[0] {
+			t.Errorf("attempt %d sent body %q, want %q", i, srv.bodies[i], srv.bodies[0])
+		}
+		if srv.keys[i] != "tx-1" {
+			t.Errorf("attempt %d sent idempotency key %q, want tx-1", i, srv.keys[i])
+		}
+	}
+}
+
+func TestPost_HonoursRetryAfter(t *testing.T) {
+	srv := &recordingServer{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+		header:   http.Header{"Retry-After": []string{"1"}},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	g := NewGatewayA(ts.URL, "gatewayA", retryTestConfig())
+	start := time.Now()
+	if _, err := g.ProcessDeposit(context.Background(), testPaymentRequest()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected retry to wait for Retry-After, took %v", elapsed)
+	}
+}
+
+func TestPost_RetryAfterBeyondDeadlineGivesUp(t *testing.T) {
+	srv := &recordingServer{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusOK},
+		header:   http.Header{"Retry-After": []string{"120"}},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	g := NewGatewayA(ts.URL, "gatewayA", retryTestConfig())
+	_, err := g.ProcessDeposit(ctx, testPaymentRequest())
+	if err == nil || err.Error() != "gateway A failure" {
+		t.Errorf("expected gateway A failure, got %v", err)
+	}
+	if len(srv.bodies) != 1 {
+		t.Errorf("expected a single attempt, got %d", len(srv.bodies))
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNewHTTPProbe(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	probe := NewHTTPProbe(ts.Client(), ts.URL+"/health")
+	if err := probe(context.Background()); err != nil {
+		t.Errorf("expected healthy, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := probe(context.Background()); err == nil {
+		t.Error("expected error for 503")
+	}
+}
+
+func TestPost_MarksFailuresSafeToFailover(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		want     bool
+	}{
+		{"only 503", []int{http.StatusServiceUnavailable}, true},
+		{"only 429", []int{http.StatusTooManyRequests}, true},
+		{"500 may have been processed", []int{http.StatusInternalServerError}, false},
+		{"503 after 500", []int{http.StatusInternalServerError, http.StatusServiceUnavailable}, false},
+		{"400", []int{http.StatusBadRequest}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(&recordingServer{statuses: tt.statuses})
+			defer ts.Close()
+
+			g := NewGatewayA(ts.URL, "gatewayA", retryTestConfig())
+			_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got := SafeToFailover(err); got != tt.want {
+				t.Errorf("SafeToFailover(%v) = %v, want %v", err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPost_ConnectionRefusedIsSafeToFailover(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	g := NewGatewayA(url, "gatewayA", retryTestConfig())
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+	if !SafeToFailover(err) || !errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+		t.Errorf("expected unavailable error safe to fail over, got %v", err)
+	}
+}
+
+func TestPost_TimeoutIsNotSafeToFailover(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	cfg := retryTestConfig()
+	cfg.HTTPTimeoutSeconds = 1
+	cfg.MaxRetries = 0
+	g := NewGatewayA(ts.URL, "gatewayA", cfg)
+	_, err := g.ProcessDeposit(context.Background(), testPaymentRequest())
+	if err == nil || SafeToFailover(err) {
+		t.Errorf("expected timeout not to be safe to fail over, got %v", err)
+	}
+}
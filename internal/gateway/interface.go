@@ -1,11 +1,20 @@
 package gateway
 
 import (
-	"net/http"
+	"context"
 )
 
 // PaymentGateway defines the contract for all payment gateway integrations.
+// Adapters translate a PaymentRequest into their wire format and report the
+// gateway's answer as a PaymentResult. A returned error means no usable answer
+// was obtained (timeout, transport failure, malformed response).
 type PaymentGateway interface {
-	ProcessDeposit(r *http.Request) (interface{}, error)
-	ProcessWithdrawal(r *http.Request) (interface{}, error)
+	ProcessDeposit(ctx context.Context, req PaymentRequest) (*PaymentResult, error)
+	ProcessWithdrawal(ctx context.Context, req PaymentRequest) (*PaymentResult, error)
+}
+
+// StatusChecker is implemented by gateways that can be asked for the current
+// answer to a payment sent earlier, such as one left pending.
+type StatusChecker interface {
+	PaymentStatus(ctx context.Context, transactionID string) (*PaymentResult, error)
 }
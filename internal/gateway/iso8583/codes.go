@@ -0,0 +1,62 @@
+package iso8583
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+)
+
+// Response codes (data element 39) with a meaning of their own to the adapter.
+const (
+	ResponseApproved          = "00"
+	ResponseHonorWithID       = "08"
+	ResponseInProgress        = "09"
+	ResponseVIPApproved       = "11"
+	ResponseOriginalNotFound  = "25"
+	ResponseIssuerUnavailable = "91"
+	ResponseSystemMalfunction = "96"
+)
+
+// outcomes maps the response codes that are not declines.
+var outcomes = map[string]gateway.Outcome{
+	ResponseApproved:    gateway.OutcomeApproved,
+	ResponseHonorWithID: gateway.OutcomeApproved,
+	ResponseVIPApproved: gateway.OutcomeApproved,
+	ResponseInProgress:  gateway.OutcomePending,
+}
+
+// responseReasons maps the common decline response codes to normalized
+// reasons; other codes are generic declines.
+var responseReasons = map[string]constants.ReasonCode{
+	"51":                      constants.ReasonInsufficientFunds,
+	"14":                      constants.ReasonInvalidAccount,
+	"54":                      constants.ReasonInvalidAccount, // expired card
+	"13":                      constants.ReasonInvalidAmount,
+	"05":                      constants.ReasonDoNotHonor,
+	"61":                      constants.ReasonLimitExceeded,
+	"65":                      constants.ReasonLimitExceeded,
+	"34":                      constants.ReasonSuspectedFraud,
+	"59":                      constants.ReasonSuspectedFraud,
+	"94":                      constants.ReasonDuplicate,
+	ResponseIssuerUnavailable: constants.ReasonGatewayUnavailable,
+	ResponseSystemMalfunction: constants.ReasonGatewayError,
+}
+
+// currency is an ISO 4217 currency's numeric code and minor unit exponent.
+type currency struct {
+	code     string
+	exponent int
+}
+
+// currencies lists the currencies the adapter can send in data elements 4
+// and 49.
+var currencies = map[string]currency{
+	"AUD": {"036", 2},
+	"CAD": {"124", 2},
+	"CHF": {"756", 2},
+	"EUR": {"978", 2},
+	"GBP": {"826", 2},
+	"INR": {"356", 2},
+	"JPY": {"392", 0},
+	"SGD": {"702", 2},
+	"USD": {"840", 2},
+}
@@ -0,0 +1,372 @@
+package iso8583
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"Payment-Gateway/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// maxMessageBytes bounds the frames Conn and Simulator accept.
+const maxMessageBytes = 8192
+
+// ErrConnClosed is returned by Exchange after Close.
+var ErrConnClosed = errors.New("iso8583: connection closed")
+
+// notSentError reports a request that never left this side, so the host
+// cannot have processed it.
+type notSentError struct {
+	err error
+}
+
+func (e *notSentError) Error() string { return "not sent: " + e.err.Error() }
+func (e *notSentError) Unwrap() error { return e.err }
+
+// NotSent reports whether an Exchange error proves the request was not sent.
+func NotSent(err error) bool {
+	var n *notSentError
+	return errors.As(err, &n)
+}
+
+// ConnOptions configure a Conn; zero values take the defaults noted.
+type ConnOptions struct {
+	// LengthHeaderBytes is the size of the big-endian binary length that
+	// precedes every message: 2 (default) or 4.
+	LengthHeaderBytes int
+	// DialTimeout defaults to 5s.
+	DialTimeout time.Duration
+	// EchoInterval is how long the connection may stay idle before an echo
+	// test checks it; zero disables keepalive.
+	EchoInterval time.Duration
+}
+
+// Conn manages the connection to one acquirer host. It keeps a single TCP
+// connection, dialed on first use and again after it breaks, frames every
+// message with a length header and multiplexes concurrent requests over it by
+// STAN. While idle the connection is kept alive with echo tests, and a failed
+// echo drops it so the next request reconnects.
+type Conn struct {
+	addr string
+	opts ConnOptions
+
+	dialMu   sync.Mutex // serializes dialing
+	mu       sync.Mutex
+	sess     *session
+	pending  map[string]*waiter // by STAN
+	lastSTAN int
+	closed   bool
+}
+
+// session is one TCP connection; done is closed when it breaks.
+type session struct {
+	nc           net.Conn
+	done         chan struct{}
+	writeMu      sync.Mutex
+	mu           sync.Mutex
+	lastActivity time.Time
+}
+
+type waiter struct {
+	responseMTI string
+	reply       chan *Message
+}
+
+// NewConn returns a Conn to addr (host:port). Nothing is dialed until the
+// first Exchange.
+func NewConn(addr string, opts ConnOptions) *Conn {
+	if opts.LengthHeaderBytes == 0 {
+		opts.LengthHeaderBytes = 2
+	}
+	if opts.DialTimeout == 0 {
+		opts.DialTimeout = 5 * time.Second
+	}
+	return &Conn{addr: addr, opts: opts, pending: make(map[string]*waiter)}
+}
+
+// NextSTAN allocates a systems trace audit number: 000001 to 999999, wrapping
+// around and skipping numbers still awaiting a response.
+func (c *Conn) NextSTAN() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		c.lastSTAN = c.lastSTAN%999999 + 1
+		stan := fmt.Sprintf("%06d", c.lastSTAN)
+		if _, busy := c.pending[stan]; !busy {
+			return stan
+		}
+	}
+}
+
+// Exchange sends req, which must carry a STAN, and waits for the response
+// with the same STAN until ctx is done. Errors from before anything was
+// written satisfy NotSent; after that the host may have processed req.
+func (c *Conn) Exchange(ctx context.Context, req *Message) (*Message, error) {
+	stan := req.Field(FieldSTAN)
+	if stan == "" {
+		return nil, &notSentError{errors.New("request has no STAN")}
+	}
+	frame, err := c.frame(req)
+	if err != nil {
+		return nil, &notSentError{err}
+	}
+	sess, err := c.connect(ctx)
+	if err != nil {
+		return nil, &notSentError{err}
+	}
+
+	w := &waiter{responseMTI: ResponseMTI(req.MTI), reply: make(chan *Message, 1)}
+	c.mu.Lock()
+	if _, busy := c.pending[stan]; busy {
+		c.mu.Unlock()
+		return nil, &notSentError{fmt.Errorf("STAN %s is already awaiting a response", stan)}
+	}
+	c.pending[stan] = w
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		if c.pending[stan] == w {
+			delete(c.pending, stan)
+		}
+		c.mu.Unlock()
+	}()
+
+	if err := sess.write(ctx, frame); err != nil {
+		c.drop(sess, err)
+		return nil, err
+	}
+	select {
+	case resp := <-w.reply:
+		return resp, nil
+	case <-sess.done:
+		return nil, errors.New("iso8583: connection lost while awaiting the response")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Echo runs an echo test: an 0800 network management request answered with
+// response code 00.
+func (c *Conn) Echo(ctx context.Context) error {
+	now := time.Now().UTC()
+	req := NewMessage(MTINetworkRequest).
+		Set(FieldTransmissionDateTime, now.Format("0102150405")).
+		Set(FieldSTAN, c.NextSTAN()).
+		Set(FieldNetworkManagementCode, NetworkEcho)
+	resp, err := c.Exchange(ctx, req)
+	if err != nil {
+		return err
+	}
+	if code := resp.Field(FieldResponseCode); code != ResponseApproved {
+		return fmt.Errorf("echo test answered with response code %q", code)
+	}
+	return nil
+}
+
+// Close drops the connection; later Exchanges fail with ErrConnClosed.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	sess := c.sess
+	c.mu.Unlock()
+	if sess != nil {
+		c.drop(sess, ErrConnClosed)
+	}
+	return nil
+}
+
+func (c *Conn) frame(m *Message) ([]byte, error) {
+	data, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return appendFrame(nil, c.opts.LengthHeaderBytes, data)
+}
+
+// connect returns the live session, dialing a new one when there is none.
+func (c *Conn) connect(ctx context.Context) (*session, error) {
+	c.dialMu.Lock()
+	defer c.dialMu.Unlock()
+	c.mu.Lock()
+	closed, sess := c.closed, c.sess
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrConnClosed
+	}
+	if sess != nil {
+		return sess, nil
+	}
+
+	dialer := net.Dialer{Timeout: c.opts.DialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	sess = &session{nc: nc, done: make(chan struct{}), lastActivity: time.Now()}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		nc.Close()
+		return nil, ErrConnClosed
+	}
+	c.sess = sess
+	c.mu.Unlock()
+	go c.read(sess)
+	if c.opts.EchoInterval > 0 {
+		go c.keepAlive(sess)
+	}
+	return sess, nil
+}
+
+// drop closes sess once and forgets it, failing the requests waiting on it.
+func (c *Conn) drop(sess *session, cause error) {
+	c.mu.Lock()
+	if c.sess != sess {
+		c.mu.Unlock()
+		return
+	}
+	c.sess = nil
+	c.mu.Unlock()
+	close(sess.done)
+	sess.nc.Close()
+	if !errors.Is(cause, ErrConnClosed) {
+		logger.GetLogger().Warn("ISO 8583 connection dropped",
+			zap.String("func", "iso8583.Conn.drop"), zap.String("address", c.addr), zap.Error(cause))
+	}
+}
+
+// read dispatches incoming messages until the session breaks: responses go
+// to the request waiting on their STAN and echo tests from the host are
+// answered.
+func (c *Conn) read(sess *session) {
+	log := logger.GetLogger().With(zap.String("func", "iso8583.Conn.read"), zap.String("address", c.addr))
+	for {
+		data, err := readFrame(sess.nc, c.opts.LengthHeaderBytes)
+		if err != nil {
+			c.drop(sess, err)
+			return
+		}
+		sess.touch()
+		msg, err := Unpack(data)
+		if err != nil {
+			log.Error("Dropping malformed ISO 8583 message", zap.Error(err))
+			continue
+		}
+		if msg.MTI == MTINetworkRequest {
+			resp := NewMessage(MTINetworkResponse)
+			for _, field := range []int{FieldTransmissionDateTime, FieldSTAN, FieldNetworkManagementCode} {
+				if value, ok := msg.Get(field); ok {
+					resp.Set(field, value)
+				}
+			}
+			resp.Set(FieldResponseCode, ResponseApproved)
+			if frame, err := c.frame(resp); err == nil {
+				sess.write(context.Background(), frame)
+			}
+			continue
+		}
+		stan := msg.Field(FieldSTAN)
+		c.mu.Lock()
+		w, ok := c.pending[stan]
+		c.mu.Unlock()
+		if !ok || w.responseMTI != msg.MTI {
+			log.Warn("Dropping unmatched ISO 8583 message", zap.String("mti", msg.MTI), zap.String("stan", stan))
+			continue
+		}
+		select {
+		case w.reply <- msg:
+		default:
+		}
+	}
+}
+
+// keepAlive echoes the host whenever the session has been idle for
+// EchoInterval and drops it when the echo fails.
+func (c *Conn) keepAlive(sess *session) {
+	ticker := time.NewTicker(c.opts.EchoInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sess.done:
+			return
+		case <-ticker.C:
+		}
+		if sess.idle() < c.opts.EchoInterval {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.EchoInterval)
+		err := c.Echo(ctx)
+		cancel()
+		if err != nil {
+			c.drop(sess, fmt.Errorf("echo test failed: %w", err))
+			return
+		}
+	}
+}
+
+func (s *session) write(ctx context.Context, frame []byte) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	s.nc.SetWriteDeadline(deadline)
+	if _, err := s.nc.Write(frame); err != nil {
+		return err
+	}
+	s.touch()
+	return nil
+}
+
+func (s *session) touch() {
+	s.mu.Lock()
+	s.lastActivity = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *session) idle() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastActivity)
+}
+
+// appendFrame appends data preceded by its big-endian length in headerBytes
+// (2 or 4) bytes.
+func appendFrame(buf []byte, headerBytes int, data []byte) ([]byte, error) {
+	if len(data) > maxMessageBytes {
+		return nil, fmt.Errorf("message of %d bytes exceeds %d", len(data), maxMessageBytes)
+	}
+	n := len(data)
+	if headerBytes == 4 {
+		buf = append(buf, byte(n>>24), byte(n>>16))
+	}
+	buf = append(buf, byte(n>>8), byte(n))
+	return append(buf, data...), nil
+}
+
+// readFrame reads one length-prefixed message.
+func readFrame(r io.Reader, headerBytes int) ([]byte, error) {
+	header := make([]byte, headerBytes)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, b := range header {
+		n = n<<8 | int(b)
+	}
+	if n == 0 || n > maxMessageBytes {
+		return nil, fmt.Errorf("invalid frame length %d", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
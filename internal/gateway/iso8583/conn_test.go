@@ -0,0 +1,202 @@
+package iso8583
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// simulate runs a simulator on a local port until the test ends and returns
+// it with its address.
+func simulate(t *testing.T, lengthHeaderBytes int) (*Simulator, string) {
+	t.Helper()
+	sim := NewSimulator(lengthHeaderBytes)
+	addr, err := sim.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { sim.Close() })
+	return sim, addr
+}
+
+// rawHost accepts one connection on a local port and hands it to serve.
+func rawHost(t *testing.T, serve func(net.Conn)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		nc, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer nc.Close()
+		serve(nc)
+	}()
+	return lis.Addr().String()
+}
+
+// waitDropped waits until c has no live session.
+func waitDropped(t *testing.T, c *Conn) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		sess := c.sess
+		c.mu.Unlock()
+		if sess == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("session was not dropped")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestConn_NextSTANWrapsAndSkipsPending(t *testing.T) {
+	c := NewConn("127.0.0.1:1", ConnOptions{})
+	if got := c.NextSTAN(); got != "000001" {
+		t.Errorf("first STAN = %s, want 000001", got)
+	}
+	c.lastSTAN = 999998
+	c.pending["000001"] = &waiter{}
+	for _, want := range []string{"999999", "000002"} {
+		if got := c.NextSTAN(); got != want {
+			t.Errorf("NextSTAN() = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestConn_EchoAndConcurrentExchanges(t *testing.T) {
+	for _, headerBytes := range []int{2, 4} {
+		t.Run(fmt.Sprintf("%d byte header", headerBytes), func(t *testing.T) {
+			_, addr := simulate(t, headerBytes)
+			c := NewConn(addr, ConnOptions{LengthHeaderBytes: headerBytes})
+			defer c.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			if err := c.Echo(ctx); err != nil {
+				t.Fatalf("Echo: %v", err)
+			}
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					stan := c.NextSTAN()
+					req := NewMessage(MTIFinancialRequest).
+						Set(FieldAmount, fmt.Sprintf("%012d", 1000+i*100)).
+						Set(FieldTransmissionDateTime, "0101120000").
+						Set(FieldSTAN, stan)
+					resp, err := c.Exchange(ctx, req)
+					if err != nil {
+						t.Errorf("Exchange %s: %v", stan, err)
+						return
+					}
+					if resp.MTI != MTIFinancialResponse || resp.Field(FieldSTAN) != stan || resp.Field(FieldAmount) != req.Field(FieldAmount) {
+						t.Errorf("request %s got the response %+v", stan, resp)
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestConn_ReconnectsAfterTheHostDrops(t *testing.T) {
+	sim, addr := simulate(t, 2)
+	c := NewConn(addr, ConnOptions{})
+	defer c.Close()
+	ctx := context.Background()
+	if err := c.Echo(ctx); err != nil {
+		t.Fatalf("Echo: %v", err)
+	}
+
+	sim.mu.Lock()
+	for nc := range sim.conns {
+		nc.Close()
+	}
+	sim.mu.Unlock()
+	waitDropped(t, c)
+
+	if err := c.Echo(ctx); err != nil {
+		t.Fatalf("Echo after reconnect: %v", err)
+	}
+}
+
+func TestConn_DialFailureIsNotSent(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	c := NewConn(addr, ConnOptions{DialTimeout: time.Second})
+	if err := c.Echo(context.Background()); err == nil || !NotSent(err) {
+		t.Errorf("expected a not sent error, got %v", err)
+	}
+	c.Close()
+	if err := c.Echo(context.Background()); !NotSent(err) || err.Error() != "not sent: "+ErrConnClosed.Error() {
+		t.Errorf("expected ErrConnClosed, got %v", err)
+	}
+}
+
+func TestConn_UnansweredRequestWasSent(t *testing.T) {
+	_, addr := simulate(t, 2)
+	c := NewConn(addr, ConnOptions{})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req := NewMessage(MTIFinancialRequest).Set(FieldAmount, "000000001068").Set(FieldSTAN, c.NextSTAN())
+	if _, err := c.Exchange(ctx, req); err != context.DeadlineExceeded || NotSent(err) {
+		t.Errorf("expected a sent deadline error, got %v", err)
+	}
+}
+
+func TestConn_AnswersHostEchoAndDropsSilentHost(t *testing.T) {
+	answered := make(chan *Message, 1)
+	addr := rawHost(t, func(nc net.Conn) {
+		// The adapter dials on its first request; echo it back from the host
+		if _, err := readFrame(nc, 2); err != nil {
+			return
+		}
+		data, _ := NewMessage(MTINetworkRequest).Set(FieldSTAN, "900001").Set(FieldNetworkManagementCode, NetworkEcho).Pack()
+		frame, _ := appendFrame(nil, 2, data)
+		nc.Write(frame)
+		if data, err := readFrame(nc, 2); err == nil {
+			resp, _ := Unpack(data)
+			answered <- resp
+		}
+		// Then stay silent, keepalive echoes included
+		for {
+			if _, err := readFrame(nc, 2); err != nil {
+				return
+			}
+		}
+	})
+
+	c := NewConn(addr, ConnOptions{EchoInterval: 100 * time.Millisecond})
+	defer c.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	c.Echo(ctx)
+
+	select {
+	case resp := <-answered:
+		if resp.MTI != MTINetworkResponse || resp.Field(FieldSTAN) != "900001" || resp.Field(FieldResponseCode) != ResponseApproved {
+			t.Errorf("unexpected echo answer %+v", resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the host's echo test was not answered")
+	}
+	waitDropped(t, c)
+}
@@ -0,0 +1,311 @@
+// Package iso8583 is a gateway adapter for acquirer hosts speaking ISO 8583
+// (1987, ASCII elements, binary bitmaps) over a length-framed TCP
+// connection. Gateways use it with type: iso8583.
+package iso8583
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/resilience"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// reversalTimeout bounds the attempts to reverse one unanswered payment.
+const reversalTimeout = 30 * time.Second
+
+func init() {
+	gateway.Register("iso8583", gateway.WithSettings(DefaultSettings, New))
+}
+
+// Gateway sends payments to an acquirer host as 0100 or 0200 requests. A
+// request left unanswered is repeated (0101/0201) within the resilience
+// settings and then reversed with an 0400, so the payment is known not to
+// have been processed once the host acknowledges the reversal.
+type Gateway struct {
+	name       string
+	Conn       *Conn
+	Resilience *resilience.Executor
+	// reversals retries reversals without a circuit breaker or bulkhead:
+	// they must reach the host even while it is failing.
+	reversals *resilience.Executor
+
+	settings Settings
+	reasons  map[string]constants.ReasonCode
+}
+
+// New builds the gateway configured under key from validated settings. The
+// connection is established on the first call.
+func New(key string, cfg config.GatewayConfig, settings Settings) (gateway.PaymentGateway, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	host, port, err := net.SplitHostPort(cfg.URL)
+	if _, perr := strconv.ParseUint(port, 10, 16); err != nil || perr != nil || host == "" || strings.Contains(host, "/") {
+		return nil, fmt.Errorf("url %q must be host:port", cfg.URL)
+	}
+	reversalCfg := cfg.Resilience
+	reversalCfg.CircuitBreaker.Enabled = false
+	reversalCfg.MaxConcurrent = 0
+	g := &Gateway{
+		name: cfg.Name,
+		Conn: NewConn(cfg.URL, ConnOptions{
+			LengthHeaderBytes: settings.LengthHeaderBytes,
+			DialTimeout:       time.Duration(settings.DialTimeoutSeconds) * time.Second,
+			EchoInterval:      time.Duration(settings.EchoIntervalSeconds) * time.Second,
+		}),
+		Resilience: resilience.NewExecutor(cfg.Name, cfg.Resilience),
+		reversals:  resilience.NewExecutor(cfg.Name+" reversals", reversalCfg),
+		settings:   settings,
+		reasons:    make(map[string]constants.ReasonCode, len(responseReasons)+len(settings.DeclineCodes)),
+	}
+	for code, reason := range responseReasons {
+		g.reasons[code] = reason
+	}
+	for code, reason := range settings.DeclineCodes {
+		g.reasons[strings.ToUpper(code)] = reason
+	}
+	return g, nil
+}
+
+// ProcessDeposit sends the deposit with the deposit processing code.
+func (g *Gateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "deposit", g.settings.DepositProcessingCode, req)
+}
+
+// ProcessWithdrawal sends the withdrawal with the withdrawal processing code.
+func (g *Gateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "withdrawal", g.settings.WithdrawalProcessingCode, req)
+}
+
+// HealthCheck runs an echo test. Like HTTP probes it bypasses the executor.
+func (g *Gateway) HealthCheck(ctx context.Context) error {
+	return g.Conn.Echo(ctx)
+}
+
+// Name returns the gateway's configured name.
+func (g *Gateway) Name() string {
+	return g.name
+}
+
+// Available reports whether the gateway's circuit breaker lets calls through.
+func (g *Gateway) Available() bool {
+	return g.Resilience.Available()
+}
+
+// Close ends the session with the host, so closeGateways releases it on shutdown.
+func (g *Gateway) Close() error {
+	return g.Conn.Close()
+}
+
+func (g *Gateway) send(ctx context.Context, operation, processingCode string, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "iso8583.Gateway.send"),
+		zap.String("gateway", g.name),
+		zap.String("operation", operation),
+		zap.String("transaction_id", req.TransactionID),
+	)
+
+	msg, err := g.request(processingCode, req, time.Now().UTC())
+	if err != nil {
+		log.Error("Payment cannot be sent as ISO 8583", zap.Error(err))
+		return nil, gateway.NotProcessed(fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err))
+	}
+	log = log.With(zap.String("mti", msg.MTI), zap.String("stan", msg.Field(FieldSTAN)), zap.String("rrn", msg.Field(FieldRRN)))
+
+	log.Info("Sending request to gateway")
+	resp, sent, err := g.exchange(ctx, g.Resilience, msg)
+	if err != nil {
+		log.Error("Gateway request error", zap.Bool("sent", sent), zap.Error(err))
+		err = g.failure(err)
+		if !sent {
+			return nil, gateway.NotProcessed(err)
+		}
+		// The host may have processed the request; reversing it settles that
+		if g.reverse(log, msg) {
+			return nil, gateway.NotProcessed(err)
+		}
+		return nil, err
+	}
+
+	result, err := g.result(resp, msg)
+	if err != nil {
+		log.Error("Unusable gateway response", zap.Error(err))
+		return nil, err
+	}
+	log.Info("Gateway request completed",
+		zap.String("response_code", result.GatewayCode),
+		zap.String("gateway_ref", result.GatewayRef),
+	)
+	return result, nil
+}
+
+// request builds the 0100 or 0200 message for a payment.
+func (g *Gateway) request(processingCode string, req gateway.PaymentRequest, now time.Time) (*Message, error) {
+	if err := checkField(FieldPAN, req.Account); err != nil || len(req.Account) < 12 {
+		return nil, fmt.Errorf("account must be a card number of 12 to 19 digits")
+	}
+	code := req.Currency
+	if code == "" {
+		code = constants.DefaultCurrency
+	}
+	cur, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return nil, fmt.Errorf("currency %s is not supported", code)
+	}
+	minor := math.Round(req.Amount * math.Pow10(cur.exponent))
+	if minor <= 0 || minor > 999999999999 {
+		return nil, fmt.Errorf("amount %v %s cannot be sent", req.Amount, code)
+	}
+
+	mti := MTIFinancialRequest
+	if g.settings.MessageClass == ClassAuthorization {
+		mti = MTIAuthorizationRequest
+	}
+	stan := g.Conn.NextSTAN()
+	msg := NewMessage(mti).
+		Set(FieldPAN, req.Account).
+		Set(FieldProcessingCode, processingCode).
+		Set(FieldAmount, fmt.Sprintf("%012.0f", minor)).
+		Set(FieldTransmissionDateTime, now.Format("0102150405")).
+		Set(FieldSTAN, stan).
+		Set(FieldLocalTime, now.Format("150405")).
+		Set(FieldLocalDate, now.Format("0102")).
+		Set(FieldAcquirerID, g.settings.AcquirerID).
+		Set(FieldRRN, retrievalReference(now, stan)).
+		Set(FieldTerminalID, g.settings.TerminalID).
+		Set(FieldMerchantID, g.settings.MerchantID).
+		Set(FieldCurrency, cur.code)
+	if _, err := msg.Pack(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// retrievalReference builds data element 37 as YDDDHH followed by the STAN.
+func retrievalReference(now time.Time, stan string) string {
+	return fmt.Sprintf("%d%03d%02d%s", now.Year()%10, now.YearDay(), now.Hour(), stan)
+}
+
+// exchange sends msg through exec, repeating it with its repeat MTI once an
+// attempt may have reached the host. sent reports whether any attempt did.
+func (g *Gateway) exchange(ctx context.Context, exec *resilience.Executor, msg *Message) (resp *Message, sent bool, err error) {
+	repeat := repeatOf(msg)
+	err = exec.Execute(ctx, func(ctx context.Context) error {
+		req := msg
+		if sent {
+			req = repeat
+		}
+		r, err := g.Conn.Exchange(ctx, req)
+		if err != nil {
+			if !NotSent(err) {
+				sent = true
+			}
+			return err
+		}
+		resp = r
+		return nil
+	})
+	return resp, sent, err
+}
+
+// reverse sends an 0400 reversal of an unanswered request and reports
+// whether the host acknowledged it, either reversing the payment or not
+// knowing it. It runs to completion even when the payment's context is done.
+func (g *Gateway) reverse(log *zap.Logger, original *Message) bool {
+	now := time.Now().UTC()
+	reversal := NewMessage(MTIReversalRequest)
+	for _, field := range []int{FieldPAN, FieldProcessingCode, FieldAmount, FieldLocalTime, FieldLocalDate, FieldAcquirerID, FieldRRN, FieldTerminalID, FieldMerchantID, FieldCurrency} {
+		reversal.Set(field, original.Field(field))
+	}
+	reversal.Set(FieldTransmissionDateTime, now.Format("0102150405"))
+	reversal.Set(FieldSTAN, g.Conn.NextSTAN())
+	reversal.Set(FieldOriginalData, originalData(original, g.settings.AcquirerID))
+	log = log.With(zap.String("reversal_stan", reversal.Field(FieldSTAN)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), reversalTimeout)
+	defer cancel()
+	resp, _, err := g.exchange(ctx, g.reversals, reversal)
+	if err != nil {
+		log.Error("Reversal unanswered; the payment needs manual reconciliation", zap.Error(err))
+		return false
+	}
+	switch code := resp.Field(FieldResponseCode); code {
+	case ResponseApproved, ResponseOriginalNotFound:
+		log.Info("Payment reversed", zap.String("response_code", code))
+		return true
+	default:
+		log.Error("Reversal refused; the payment needs manual reconciliation", zap.String("response_code", code))
+		return false
+	}
+}
+
+// originalData builds data element 90 identifying original: its MTI, STAN,
+// transmission date and time, and acquirer and forwarding institution IDs.
+func originalData(original *Message, acquirerID string) string {
+	return original.MTI + original.Field(FieldSTAN) + original.Field(FieldTransmissionDateTime) +
+		fmt.Sprintf("%011s%011d", acquirerID, 0)
+}
+
+// repeatOf returns msg as a repeat (retransmission): the same message with
+// the last MTI digit set to 1.
+func repeatOf(msg *Message) *Message {
+	repeat := NewMessage(msg.MTI[:3] + "1")
+	for field, value := range msg.fields {
+		repeat.Set(field, value)
+	}
+	return repeat
+}
+
+// failure converts the executor's last error into the application's gateway errors.
+func (g *Gateway) failure(err error) error {
+	switch {
+	case errors.Is(err, apperrors.ErrGatewayNotAvailable):
+		// The circuit breaker or bulkhead turned the call away
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return apperrors.WithMessage(apperrors.ErrGatewayTimeout, g.name+" timeout")
+	default:
+		return fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
+	}
+}
+
+func (g *Gateway) result(resp, req *Message) (*gateway.PaymentResult, error) {
+	code := resp.Field(FieldResponseCode)
+	if code == "" {
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, g.name+" answered without a response code")
+	}
+	raw, _ := resp.Pack()
+	result := &gateway.PaymentResult{
+		GatewayRef:  resp.Field(FieldRRN),
+		GatewayCode: code,
+		RawResponse: raw,
+	}
+	if result.GatewayRef == "" {
+		result.GatewayRef = req.Field(FieldRRN)
+	}
+	if authID := resp.Field(FieldAuthorizationID); authID != "" {
+		result.Message = "approval code " + authID
+	}
+	outcome, ok := outcomes[code]
+	if !ok {
+		outcome = gateway.OutcomeDeclined
+		result.Reason = constants.ReasonDeclined
+		if reason, ok := g.reasons[strings.ToUpper(code)]; ok {
+			result.Reason = reason
+		}
+	}
+	result.Outcome = outcome
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package iso8583
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/gatewaytest"
+	"Payment-Gateway/internal/resilience"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testPAN is the card number the test payments are made with.
+const testPAN = "4111111111111111"
+
+func newGateway(t *testing.T, url, settings string) *Gateway {
+	t.Helper()
+	return gatewaytest.New(t, "gatewayIso", gatewaytest.Config(t, "iso8583", url, settings)).(*Gateway)
+}
+
+func TestGateway_Simulator(t *testing.T) {
+	_, addr := simulate(t, 2)
+	gw := newGateway(t, addr, "")
+	tests := []struct {
+		amount      float64
+		wantOutcome gateway.Outcome
+		wantCode    string
+		wantReason  constants.ReasonCode
+	}{
+		{10.00, gateway.OutcomeApproved, "00", ""},
+		{10.51, gateway.OutcomeDeclined, "51", constants.ReasonInsufficientFunds},
+		{10.05, gateway.OutcomeDeclined, "05", constants.ReasonDoNotHonor},
+		{10.91, gateway.OutcomeDeclined, "91", constants.ReasonGatewayUnavailable},
+		{10.09, gateway.OutcomePending, "09", ""},
+	}
+	for _, tt := range tests {
+		result, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest(testPAN, tt.amount))
+		if err != nil {
+			t.Fatalf("%.2f: ProcessDeposit: %v", tt.amount, err)
+		}
+		if result.Outcome != tt.wantOutcome || result.GatewayCode != tt.wantCode || result.Reason != tt.wantReason {
+			t.Errorf("%.2f: unexpected result %+v", tt.amount, result)
+		}
+		if len(result.GatewayRef) != 12 || len(result.RawResponse) == 0 {
+			t.Errorf("%.2f: expected the retrieval reference and raw response, got %+v", tt.amount, result)
+		}
+	}
+
+	if err := gateway.ProbeFor(gw, nil, "")(context.Background()); err != nil {
+		t.Errorf("expected the echo test to pass, got %v", err)
+	}
+}
+
+func TestGateway_CloseEndsSession(t *testing.T) {
+	_, addr := simulate(t, 2)
+	gw := newGateway(t, addr, "")
+	if err := gw.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+
+	var closer io.Closer = gw // closeGateways only closes io.Closers
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := gw.HealthCheck(context.Background()); !errors.Is(err, ErrConnClosed) {
+		t.Errorf("expected ErrConnClosed after Close, got %v", err)
+	}
+}
+
+func TestGateway_MessageContents(t *testing.T) {
+	sim, addr := simulate(t, 4)
+	gw := newGateway(t, addr, `{lengthHeaderBytes: 4, messageClass: authorization, terminalID: "TERM 042", acquirerID: "4321"}`)
+
+	result, err := gw.ProcessWithdrawal(context.Background(), gateway.PaymentRequest{TransactionID: "tx-1", Account: "5500000000000004", Amount: 1234, Currency: "jpy"})
+	if err != nil || result.Outcome != gateway.OutcomeApproved || !strings.HasPrefix(result.Message, "approval code ") {
+		t.Fatalf("expected approval, got %+v, %v", result, err)
+	}
+	resp, err := Unpack(result.RawResponse)
+	if err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	want := map[int]string{
+		FieldPAN:            "5500000000000004",
+		FieldProcessingCode: "260000",
+		FieldAmount:         "000000001234",
+		FieldAcquirerID:     "4321",
+		FieldTerminalID:     "TERM 042",
+		FieldCurrency:       "392",
+	}
+	for field, value := range want {
+		if got := resp.Field(field); got != value {
+			t.Errorf("field %d = %q, want %q", field, got, value)
+		}
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if resp.MTI != MTIAuthorizationResponse || !reflect.DeepEqual(sim.received, []string{MTIAuthorizationRequest}) {
+		t.Errorf("expected an authorization exchange, got %s after %v", resp.MTI, sim.received)
+	}
+}
+
+func TestGateway_UnansweredPaymentIsRepeatedAndReversed(t *testing.T) {
+	sim, addr := simulate(t, 2)
+	gw := newGateway(t, addr, "")
+
+	_, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest(testPAN, 10.68))
+	if !errors.Is(err, apperrors.ErrGatewayTimeout) || !gateway.SafeToFailover(err) {
+		t.Fatalf("expected a timeout that is safe to fail over, got %v", err)
+	}
+	sim.mu.Lock()
+	defer sim.mu.Unlock()
+	if want := []string{MTIFinancialRequest, MTIFinancialRepeat, MTIReversalRequest}; !reflect.DeepEqual(sim.received, want) {
+		t.Errorf("host received %v, want %v", sim.received, want)
+	}
+	if len(sim.reversed) != 1 {
+		t.Errorf("expected the payment to be reversed, got %v", sim.reversed)
+	}
+}
+
+func TestGateway_UnreversedPaymentIsNotSafeToFailover(t *testing.T) {
+	addr := rawHost(t, func(nc net.Conn) {
+		// Receive everything and answer nothing, reversals included
+		for {
+			if _, err := readFrame(nc, 2); err != nil {
+				return
+			}
+		}
+	})
+	gw := newGateway(t, addr, "")
+	gw.reversals = resilience.NewExecutor("GatewayUnderTest reversals", config.ResilienceConfig{HTTPTimeoutSeconds: 1})
+
+	_, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest(testPAN, 10.00))
+	if !errors.Is(err, apperrors.ErrGatewayTimeout) || gateway.SafeToFailover(err) {
+		t.Errorf("expected a timeout that is not safe to fail over, got %v", err)
+	}
+}
+
+func TestGateway_NotSentPayments(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	unreachable := lis.Addr().String()
+	lis.Close()
+	_, reachable := simulate(t, 2)
+
+	tests := map[string]struct {
+		addr    string
+		req     gateway.PaymentRequest
+		wantErr error
+	}{
+		"unreachable host":  {unreachable, gatewaytest.PaymentRequest(testPAN, 10), apperrors.ErrGatewayNotAvailable},
+		"account not a PAN": {reachable, gateway.PaymentRequest{TransactionID: "tx-1", Account: "acc-1", Amount: 10}, apperrors.ErrProcessingFailed},
+		"unknown currency":  {reachable, gateway.PaymentRequest{TransactionID: "tx-1", Account: "4111111111111111", Amount: 10, Currency: "XYZ"}, apperrors.ErrProcessingFailed},
+		"zero amount":       {reachable, gatewaytest.PaymentRequest(testPAN, 0.001), apperrors.ErrProcessingFailed},
+	}
+	for name, tt := range tests {
+		_, err := newGateway(t, tt.addr, "").ProcessDeposit(context.Background(), tt.req)
+		if !errors.Is(err, tt.wantErr) || !gateway.SafeToFailover(err) {
+			t.Errorf("%s: expected %v, safe to fail over, got %v", name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestNew_InvalidConfiguration(t *testing.T) {
+	tests := map[string]struct{ url, settings string }{
+		"no url":         {"", ""},
+		"url not a host": {"http://acquirer.example.com/iso", ""},
+		"url port":       {"acquirer.example.com:iso", ""},
+		"length header":  {"127.0.0.1:8583", `{lengthHeaderBytes: 3}`},
+		"message class":  {"127.0.0.1:8583", `{messageClass: advice}`},
+		"terminal id":    {"127.0.0.1:8583", `{terminalID: "TERMINAL-0001"}`},
+		"processing":     {"127.0.0.1:8583", `{depositProcessingCode: "00"}`},
+		"decline code":   {"127.0.0.1:8583", `{declineCodes: {"51": broke}}`},
+	}
+	for name, tt := range tests {
+		if _, err := gateway.New("gatewayIso", gatewaytest.Config(t, "iso8583", tt.url, tt.settings)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package iso8583
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Message type indicators the adapter and simulator exchange. A request's
+// response MTI is the request's plus 10; a repeat (retransmission) is the
+// request's plus 1.
+const (
+	MTIAuthorizationRequest  = "0100"
+	MTIAuthorizationRepeat   = "0101"
+	MTIAuthorizationResponse = "0110"
+	MTIFinancialRequest      = "0200"
+	MTIFinancialRepeat       = "0201"
+	MTIFinancialResponse     = "0210"
+	MTIReversalRequest       = "0400"
+	MTIReversalRepeat        = "0401"
+	MTIReversalResponse      = "0410"
+	MTINetworkRequest        = "0800"
+	MTINetworkResponse       = "0810"
+)
+
+// Data elements the adapter uses.
+const (
+	FieldPAN                   = 2
+	FieldProcessingCode        = 3
+	FieldAmount                = 4
+	FieldTransmissionDateTime  = 7
+	FieldSTAN                  = 11
+	FieldLocalTime             = 12
+	FieldLocalDate             = 13
+	FieldAcquirerID            = 32
+	FieldRRN                   = 37
+	FieldAuthorizationID       = 38
+	FieldResponseCode          = 39
+	FieldTerminalID            = 41
+	FieldMerchantID            = 42
+	FieldCurrency              = 49
+	FieldNetworkManagementCode = 70
+	FieldOriginalData          = 90
+)
+
+// NetworkEcho is the network management code of an echo test.
+const NetworkEcho = "301"
+
+type lengthType int
+
+const (
+	fixed lengthType = iota
+	llvar
+	lllvar
+)
+
+type charset int
+
+const (
+	numeric      charset = iota // n: digits
+	alphanumeric                // an: letters and digits
+	printable                   // ans: printable ASCII, including spaces
+)
+
+// fieldSpec is how a data element is encoded: ASCII, with an ASCII length
+// prefix for variable fields.
+type fieldSpec struct {
+	length  lengthType
+	max     int
+	charset charset
+}
+
+// specs lists the data elements this codec understands, per ISO 8583:1987.
+// Messages carrying any other element cannot be unpacked because its length
+// would be unknown.
+var specs = map[int]fieldSpec{
+	FieldPAN:                   {llvar, 19, numeric},
+	FieldProcessingCode:        {fixed, 6, numeric},
+	FieldAmount:                {fixed, 12, numeric},
+	FieldTransmissionDateTime:  {fixed, 10, numeric},
+	FieldSTAN:                  {fixed, 6, numeric},
+	FieldLocalTime:             {fixed, 6, numeric},
+	FieldLocalDate:             {fixed, 4, numeric},
+	FieldAcquirerID:            {llvar, 11, numeric},
+	FieldRRN:                   {fixed, 12, alphanumeric},
+	FieldAuthorizationID:       {fixed, 6, alphanumeric},
+	FieldResponseCode:          {fixed, 2, alphanumeric},
+	FieldTerminalID:            {fixed, 8, printable},
+	FieldMerchantID:            {fixed, 15, printable},
+	FieldCurrency:              {fixed, 3, numeric},
+	FieldNetworkManagementCode: {fixed, 3, numeric},
+	FieldOriginalData:          {fixed, 42, numeric},
+}
+
+// Message is an ISO 8583 message: an MTI and its data elements as text.
+type Message struct {
+	MTI    string
+	fields map[int]string
+}
+
+// NewMessage returns an empty message of type mti.
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, fields: make(map[int]string)}
+}
+
+// Set sets data element field to value.
+func (m *Message) Set(field int, value string) *Message {
+	m.fields[field] = value
+	return m
+}
+
+// Get returns data element field and whether it is present.
+func (m *Message) Get(field int) (string, bool) {
+	value, ok := m.fields[field]
+	return value, ok
+}
+
+// Field returns data element field, or "" when it is absent.
+func (m *Message) Field(field int) string {
+	return m.fields[field]
+}
+
+// Fields returns the numbers of the data elements present, in order.
+func (m *Message) Fields() []int {
+	fields := make([]int, 0, len(m.fields))
+	for field := range m.fields {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+// Pack encodes the message: the MTI, a binary primary bitmap, a secondary
+// bitmap when any element above 64 is present, then the elements in order.
+func (m *Message) Pack() ([]byte, error) {
+	if !isDigits(m.MTI) || len(m.MTI) != 4 {
+		return nil, fmt.Errorf("invalid MTI %q", m.MTI)
+	}
+	fields := m.Fields()
+	bitmap := make([]byte, 8)
+	for _, field := range fields {
+		if field > 64 {
+			bitmap = make([]byte, 16)
+			bitmap[0] |= 0x80
+			break
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(m.MTI)
+	var data bytes.Buffer
+	for _, field := range fields {
+		spec, ok := specs[field]
+		if !ok || field < 2 || field > 128 {
+			return nil, fmt.Errorf("field %d is not supported", field)
+		}
+		bitmap[(field-1)/8] |= 0x80 >> ((field - 1) % 8)
+		if err := spec.encode(&data, m.fields[field]); err != nil {
+			return nil, fmt.Errorf("field %d: %w", field, err)
+		}
+	}
+	buf.Write(bitmap)
+	buf.Write(data.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Unpack decodes a message packed by Pack or a peer using the same encoding.
+func Unpack(data []byte) (*Message, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("message of %d bytes is too short", len(data))
+	}
+	m := NewMessage(string(data[:4]))
+	if !isDigits(m.MTI) {
+		return nil, fmt.Errorf("invalid MTI %q", m.MTI)
+	}
+	bitmap := data[4:12]
+	pos := 12
+	if bitmap[0]&0x80 != 0 {
+		if len(data) < 20 {
+			return nil, fmt.Errorf("message of %d bytes is too short for a secondary bitmap", len(data))
+		}
+		bitmap = data[4:20]
+		pos = 20
+	}
+	for field := 2; field <= len(bitmap)*8; field++ {
+		if bitmap[(field-1)/8]&(0x80>>((field-1)%8)) == 0 {
+			continue
+		}
+		spec, ok := specs[field]
+		if !ok {
+			return nil, fmt.Errorf("field %d is not supported", field)
+		}
+		value, n, err := spec.decode(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", field, err)
+		}
+		m.fields[field] = value
+		pos += n
+	}
+	if pos != len(data) {
+		return nil, fmt.Errorf("%d trailing bytes after the last field", len(data)-pos)
+	}
+	return m, nil
+}
+
+// checkField reports whether value can be sent as data element field.
+func checkField(field int, value string) error {
+	var buf bytes.Buffer
+	return specs[field].encode(&buf, value)
+}
+
+func (s fieldSpec) encode(buf *bytes.Buffer, value string) error {
+	if err := s.check(value); err != nil {
+		return err
+	}
+	switch s.length {
+	case fixed:
+		if len(value) != s.max {
+			return fmt.Errorf("length %d, want %d", len(value), s.max)
+		}
+	case llvar:
+		fmt.Fprintf(buf, "%02d", len(value))
+	case lllvar:
+		fmt.Fprintf(buf, "%03d", len(value))
+	}
+	buf.WriteString(value)
+	return nil
+}
+
+func (s fieldSpec) decode(data []byte) (string, int, error) {
+	length, prefix := s.max, 0
+	if s.length != fixed {
+		prefix = 2
+		if s.length == lllvar {
+			prefix = 3
+		}
+		if len(data) < prefix {
+			return "", 0, fmt.Errorf("truncated length")
+		}
+		n, err := strconv.Atoi(string(data[:prefix]))
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("invalid length %q", data[:prefix])
+		}
+		length = n
+	}
+	if len(data) < prefix+length {
+		return "", 0, fmt.Errorf("truncated value")
+	}
+	value := string(data[prefix : prefix+length])
+	if err := s.check(value); err != nil {
+		return "", 0, err
+	}
+	return value, prefix + length, nil
+}
+
+func (s fieldSpec) check(value string) error {
+	if len(value) > s.max {
+		return fmt.Errorf("length %d exceeds %d", len(value), s.max)
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case s.charset == numeric && (c < '0' || c > '9'),
+			s.charset == alphanumeric && !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'),
+			s.charset == printable && (c < 0x20 || c > 0x7e):
+			return fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ResponseMTI returns the MTI answering a request of type mti, repeats
+// included.
+func ResponseMTI(mti string) string {
+	if len(mti) != 4 || !isDigits(mti) {
+		return ""
+	}
+	return mti[:2] + "10"
+}
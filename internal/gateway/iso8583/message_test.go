@@ -0,0 +1,102 @@
+package iso8583
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMessage_PackUnpackRoundTrip(t *testing.T) {
+	msg := NewMessage(MTIFinancialRequest).
+		Set(FieldPAN, "4111111111111111").
+		Set(FieldProcessingCode, "000000").
+		Set(FieldAmount, "000000001234").
+		Set(FieldSTAN, "000042").
+		Set(FieldAcquirerID, "12345").
+		Set(FieldTerminalID, "TERM 001").
+		Set(FieldCurrency, "978")
+
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	// Fields 2, 3, 4, 11, 32, 41 and 49 in a primary bitmap only
+	wantBitmap := []byte{0x70, 0x20, 0x00, 0x01, 0x00, 0x80, 0x80, 0x00}
+	if string(data[:4]) != "0200" || !bytes.Equal(data[4:12], wantBitmap) {
+		t.Fatalf("unexpected header % x", data[:12])
+	}
+	if want := "164111111111111111000000000000001234000042051234" + "5TERM 001978"; string(data[12:]) != want {
+		t.Errorf("data %q, want %q", data[12:], want)
+	}
+
+	got, err := Unpack(data)
+	if err != nil {
+		t.Fatalf("Unpack: %v", err)
+	}
+	if got.MTI != msg.MTI || len(got.Fields()) != len(msg.Fields()) {
+		t.Fatalf("round trip lost data: %+v", got)
+	}
+	for _, field := range msg.Fields() {
+		if got.Field(field) != msg.Field(field) {
+			t.Errorf("field %d = %q, want %q", field, got.Field(field), msg.Field(field))
+		}
+	}
+}
+
+func TestMessage_SecondaryBitmap(t *testing.T) {
+	msg := NewMessage(MTINetworkRequest).Set(FieldSTAN, "000001").Set(FieldNetworkManagementCode, NetworkEcho)
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	if data[4]&0x80 == 0 || len(data) != 4+16+6+3 {
+		t.Fatalf("expected a secondary bitmap, got % x", data)
+	}
+	got, err := Unpack(data)
+	if err != nil || got.Field(FieldNetworkManagementCode) != NetworkEcho {
+		t.Errorf("unexpected unpack %+v, %v", got, err)
+	}
+}
+
+func TestMessage_PackRejectsInvalidFields(t *testing.T) {
+	tests := map[string]*Message{
+		"mti":            NewMessage("02A0"),
+		"fixed length":   NewMessage(MTIFinancialRequest).Set(FieldProcessingCode, "0000"),
+		"numeric":        NewMessage(MTIFinancialRequest).Set(FieldAmount, "00000000012A"),
+		"variable max":   NewMessage(MTIFinancialRequest).Set(FieldPAN, strings.Repeat("4", 20)),
+		"printable":      NewMessage(MTIFinancialRequest).Set(FieldTerminalID, "TERM\n001"),
+		"unknown field":  NewMessage(MTIFinancialRequest).Set(5, "x"),
+		"bitmap element": NewMessage(MTIFinancialRequest).Set(1, "x"),
+	}
+	for name, msg := range tests {
+		if _, err := msg.Pack(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUnpack_RejectsMalformedMessages(t *testing.T) {
+	valid, _ := NewMessage(MTIFinancialResponse).Set(FieldSTAN, "000001").Set(FieldResponseCode, "00").Pack()
+	unknown := append([]byte("0210"), 0x08, 0, 0, 0, 0, 0, 0, 0) // field 5
+	tests := map[string][]byte{
+		"short":         valid[:10],
+		"truncated":     valid[:len(valid)-1],
+		"trailing":      append(append([]byte{}, valid...), '0'),
+		"unknown field": append(unknown, "000000000001"...),
+		"mti":           append([]byte("02x0"), valid[4:]...),
+	}
+	for name, data := range tests {
+		if _, err := Unpack(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestResponseMTI(t *testing.T) {
+	tests := map[string]string{"0100": "0110", "0201": "0210", "0400": "0410", "0401": "0410", "0800": "0810", "x": ""}
+	for mti, want := range tests {
+		if got := ResponseMTI(mti); got != want {
+			t.Errorf("ResponseMTI(%s) = %q, want %q", mti, got, want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package iso8583
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+)
+
+// Message classes a gateway sends payments as.
+const (
+	// ClassFinancial sends 0200 financial requests (single message).
+	ClassFinancial = "financial"
+	// ClassAuthorization sends 0100 authorization requests (dual message,
+	// cleared separately).
+	ClassAuthorization = "authorization"
+)
+
+// Settings configure an acquirer host under a gateway's settings block. The
+// gateway's url is the host's address, e.g. "acquirer.example.com:5000".
+type Settings struct {
+	// LengthHeaderBytes is the binary message length header: 2 or 4.
+	LengthHeaderBytes int    `yaml:"lengthHeaderBytes"`
+	MessageClass      string `yaml:"messageClass"`
+	// DepositProcessingCode and WithdrawalProcessingCode go in data element
+	// 3; the defaults are purchase (00) and payment credit (26).
+	DepositProcessingCode    string `yaml:"depositProcessingCode"`
+	WithdrawalProcessingCode string `yaml:"withdrawalProcessingCode"`
+	// AcquirerID, TerminalID and MerchantID are the identifiers the acquirer
+	// assigned: data elements 32, 41 and 42.
+	AcquirerID string `yaml:"acquirerID"`
+	TerminalID string `yaml:"terminalID"`
+	MerchantID string `yaml:"merchantID"`
+	// EchoIntervalSeconds is how long the connection may stay idle before an
+	// echo test; 0 disables keepalive.
+	EchoIntervalSeconds int `yaml:"echoIntervalSeconds"`
+	DialTimeoutSeconds  int `yaml:"dialTimeoutSeconds"`
+	// DeclineCodes maps response codes to normalized reasons, on top of the
+	// common ones the adapter knows.
+	DeclineCodes map[string]constants.ReasonCode `yaml:"declineCodes"`
+}
+
+// DefaultSettings are the settings a gateway with an empty settings block gets.
+func DefaultSettings() Settings {
+	return Settings{
+		LengthHeaderBytes:        2,
+		MessageClass:             ClassFinancial,
+		DepositProcessingCode:    "000000",
+		WithdrawalProcessingCode: "260000",
+		AcquirerID:               "000000",
+		TerminalID:               "TERM0001",
+		MerchantID:               "MERCHANT0000001",
+		EchoIntervalSeconds:      30,
+		DialTimeoutSeconds:       5,
+	}
+}
+
+// Validate checks the settings after they were decoded over DefaultSettings.
+func (s Settings) Validate() error {
+	if s.LengthHeaderBytes != 2 && s.LengthHeaderBytes != 4 {
+		return fmt.Errorf("lengthHeaderBytes must be 2 or 4")
+	}
+	if s.MessageClass != ClassFinancial && s.MessageClass != ClassAuthorization {
+		return fmt.Errorf("messageClass %q must be %s or %s", s.MessageClass, ClassFinancial, ClassAuthorization)
+	}
+	for name, field := range map[string]struct {
+		value string
+		id    int
+	}{
+		"depositProcessingCode":    {s.DepositProcessingCode, FieldProcessingCode},
+		"withdrawalProcessingCode": {s.WithdrawalProcessingCode, FieldProcessingCode},
+		"acquirerID":               {s.AcquirerID, FieldAcquirerID},
+		"terminalID":               {s.TerminalID, FieldTerminalID},
+		"merchantID":               {s.MerchantID, FieldMerchantID},
+	} {
+		if err := checkField(field.id, field.value); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if s.EchoIntervalSeconds < 0 || s.DialTimeoutSeconds < 0 {
+		return fmt.Errorf("echoIntervalSeconds and dialTimeoutSeconds must not be negative")
+	}
+	for code, reason := range s.DeclineCodes {
+		if len(code) != 2 {
+			return fmt.Errorf("declineCodes.%s: response codes have two characters", code)
+		}
+		if !gateway.KnownReason(reason) {
+			return fmt.Errorf("declineCodes.%s: unknown reason %q", code, reason)
+		}
+	}
+	return nil
+}
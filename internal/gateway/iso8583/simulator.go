@@ -0,0 +1,186 @@
+package iso8583
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"Payment-Gateway/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Simulator is a local acquirer host for tests and local runs. It answers
+// echo tests and approves payments unless the last two digits of the amount
+// in minor units select another response code: 05, 14, 51, 59 and 61
+// decline, 91 reports the issuer unavailable, 09 leaves the payment in
+// progress and 68 gets no answer at all, so the adapter repeats and then
+// reverses it. Repeats get the first answer again and reversals are
+// acknowledged with 00, or 25 when the original is unknown.
+type Simulator struct {
+	headerBytes int
+
+	mu      sync.Mutex
+	lis     net.Listener
+	conns   map[net.Conn]struct{}
+	answers map[string]*Message // by STAN and transmission time; nil when unanswered
+	// reversed lists the payments reversed, by the same key.
+	reversed map[string]bool
+	// received lists the MTIs of the requests received, in order.
+	received []string
+}
+
+// simulatorDeclines are the response codes the simulator can be asked for
+// through the amount.
+var simulatorDeclines = map[string]bool{"05": true, "14": true, "51": true, "59": true, "61": true, ResponseIssuerUnavailable: true, ResponseInProgress: true}
+
+// NewSimulator returns a simulator framing messages with a lengthHeaderBytes
+// (2 or 4) length header.
+func NewSimulator(lengthHeaderBytes int) *Simulator {
+	if lengthHeaderBytes == 0 {
+		lengthHeaderBytes = 2
+	}
+	return &Simulator{
+		headerBytes: lengthHeaderBytes,
+		conns:       make(map[net.Conn]struct{}),
+		answers:     make(map[string]*Message),
+		reversed:    make(map[string]bool),
+	}
+}
+
+// Listen starts serving on addr and returns the address listened on, which
+// tells the port when addr asks for any (":0").
+func (s *Simulator) Listen(addr string) (string, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.lis = lis
+	s.mu.Unlock()
+	go s.serve(lis)
+	return lis.Addr().String(), nil
+}
+
+// Close stops listening and drops every connection.
+func (s *Simulator) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.lis == nil {
+		return nil
+	}
+	return s.lis.Close()
+}
+
+func (s *Simulator) serve(lis net.Listener) {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.GetLogger().Error("ISO 8583 simulator stopped accepting", zap.String("func", "iso8583.Simulator.serve"), zap.Error(err))
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *Simulator) handle(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		data, err := readFrame(conn, s.headerBytes)
+		if err != nil {
+			return
+		}
+		req, err := Unpack(data)
+		if err != nil {
+			logger.GetLogger().Warn("ISO 8583 simulator dropped a malformed message", zap.String("func", "iso8583.Simulator.handle"), zap.Error(err))
+			continue
+		}
+		resp := s.answer(req)
+		if resp == nil {
+			continue
+		}
+		packed, err := resp.Pack()
+		if err != nil {
+			return
+		}
+		frame, err := appendFrame(nil, s.headerBytes, packed)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+// answer returns the response to req, or nil to stay silent.
+func (s *Simulator) answer(req *Message) *Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.received = append(s.received, req.MTI)
+
+	switch req.MTI {
+	case MTINetworkRequest:
+		return response(req, ResponseApproved)
+	case MTIAuthorizationRequest, MTIAuthorizationRepeat, MTIFinancialRequest, MTIFinancialRepeat:
+		key := req.Field(FieldSTAN) + req.Field(FieldTransmissionDateTime)
+		if resp, seen := s.answers[key]; seen {
+			return resp
+		}
+		amount := req.Field(FieldAmount)
+		if len(amount) < 2 {
+			return response(req, "13") // invalid amount
+		}
+		code := ResponseApproved
+		if tail := amount[len(amount)-2:]; simulatorDeclines[tail] {
+			code = tail
+		} else if tail == "68" {
+			s.answers[key] = nil
+			return nil
+		}
+		resp := response(req, code)
+		if code == ResponseApproved {
+			resp.Set(FieldAuthorizationID, "A"+req.Field(FieldSTAN)[1:])
+		}
+		s.answers[key] = resp
+		return resp
+	case MTIReversalRequest, MTIReversalRepeat:
+		original := req.Field(FieldOriginalData)
+		if len(original) < 20 {
+			return response(req, "30") // format error
+		}
+		key := original[4:20]
+		if _, known := s.answers[key]; !known {
+			return response(req, ResponseOriginalNotFound)
+		}
+		s.reversed[key] = true
+		return response(req, ResponseApproved)
+	default:
+		return response(req, "12") // invalid transaction
+	}
+}
+
+// response builds the answer to req with the given response code, echoing
+// the data elements a host returns.
+func response(req *Message, code string) *Message {
+	resp := NewMessage(ResponseMTI(req.MTI))
+	for _, field := range []int{FieldPAN, FieldProcessingCode, FieldAmount, FieldTransmissionDateTime, FieldSTAN, FieldLocalTime, FieldLocalDate, FieldAcquirerID, FieldRRN, FieldTerminalID, FieldMerchantID, FieldCurrency, FieldNetworkManagementCode, FieldOriginalData} {
+		if value, ok := req.Get(field); ok {
+			resp.Set(field, value)
+		}
+	}
+	return resp.Set(FieldResponseCode, code)
+}
@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	mockgateway "Payment-Gateway/internal/handler/mock_gateway"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGateways_BodyStatusOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      string
+		code        string
+		wantOutcome Outcome
+		wantErr     error
+	}{
+		{"approved", "success", "", OutcomeApproved, nil},
+		{"declined", "failed", "INSUFFICIENT_FUNDS", OutcomeDeclined, nil},
+		{"declined alias", "DECLINED", "DO_NOT_HONOR", OutcomeDeclined, nil},
+		{"pending", "pending", "", OutcomePending, nil},
+		{"unknown status", "maybe", "", "", apperrors.ErrProcessingFailed},
+		{"missing status", "", "", "", apperrors.ErrProcessingFailed},
+	}
+
+	adapters := []struct {
+		name  string
+		new   func(url string) PaymentGateway
+		reply func(w http.ResponseWriter, status, code string)
+	}{
+		{
+			name: "gatewayA",
+			new:  func(url string) PaymentGateway { return NewGatewayA(url, "gatewayA", getTestResilienceConfig()) },
+			reply: func(w http.ResponseWriter, status, code string) {
+				json.NewEncoder(w).Encode(dtos.GatewayAResponse{Status: status, Code: code, Message: "m", Reference: "ref"})
+			},
+		},
+		{
+			name: "gatewayB",
+			new:  func(url string) PaymentGateway { return NewGatewayB(url, "gatewayB", getTestResilienceConfig()) },
+			reply: func(w http.ResponseWriter, status, code string) {
+				xml.NewEncoder(w).Encode(dtos.SOAPEnvelope{Body: dtos.SOAPBody{
+					Response: &dtos.SOAPResponse{Status: status, Code: code, Message: "m", Reference: "ref"},
+				}})
+			},
+		},
+	}
+
+	for _, adapter := range adapters {
+		for _, tt := range tests {
+			t.Run(adapter.name+"/"+tt.name, func(t *testing.T) {
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					adapter.reply(w, tt.status, tt.code)
+				}))
+				defer ts.Close()
+
+				result, err := adapter.new(ts.URL).ProcessDeposit(context.Background(), testPaymentRequest())
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("expected %v, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if result.Outcome != tt.wantOutcome || result.GatewayCode != tt.code || result.GatewayRef != "ref" {
+					t.Errorf("unexpected result: %+v", result)
+				}
+			})
+		}
+	}
+}
+
+func TestGateways_AgainstMockGateways(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a/deposit", mockgateway.GatewayAMockDepositHandler)
+	mux.HandleFunc("/a/withdrawal", mockgateway.GatewayAMockWithdrawalHandler)
+	mux.HandleFunc("/b/deposit", mockgateway.GatewayBMockDepositHandler)
+	mux.HandleFunc("/b/withdrawal", mockgateway.GatewayBMockWithdrawalHandler)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	gateways := map[string]PaymentGateway{
+		"gatewayA": NewGatewayA(ts.URL+"/a", "gatewayA", getTestResilienceConfig()),
+		"gatewayB": NewGatewayB(ts.URL+"/b", "gatewayB", getTestResilienceConfig()),
+		"gatewayB/soap12": NewGatewayBWithSettings(ts.URL+"/b", "gatewayB", getTestResilienceConfig(), GatewayBSettings{
+			SOAPVersion: SOAP12,
+			Security:    WSSecuritySettings{Username: "merchant", Password: "secret", PasswordType: PasswordDigest},
+		}),
+	}
+	accounts := map[string]struct {
+		outcome Outcome
+		reason  constants.ReasonCode
+	}{
+		"acc1":      {OutcomeApproved, ""},
+		"nsf-1":     {OutcomeDeclined, constants.ReasonInsufficientFunds},
+		"decline-1": {OutcomeDeclined, constants.ReasonDoNotHonor},
+		"pending-1": {OutcomePending, ""},
+	}
+
+	for name, g := range gateways {
+		for account, want := range accounts {
+			req := testPaymentRequest()
+			req.Account = account
+			for op, call := range map[string]func(context.Context, PaymentRequest) (*PaymentResult, error){
+				"deposit":    g.ProcessDeposit,
+				"withdrawal": g.ProcessWithdrawal,
+			} {
+				result, err := call(context.Background(), req)
+				if err != nil {
+					t.Fatalf("%s %s %s: unexpected error: %v", name, op, account, err)
+				}
+				if result.Outcome != want.outcome || result.Reason != want.reason || result.GatewayRef == "" {
+					t.Errorf("%s %s %s: got %+v, want %+v", name, op, account, result, want)
+				}
+				if want.outcome == OutcomeDeclined && result.GatewayCode == "" {
+					t.Errorf("%s %s %s: expected a decline code", name, op, account)
+				}
+			}
+		}
+	}
+}
+
+func TestReasonForCode(t *testing.T) {
+	tests := []struct {
+		table map[string]constants.ReasonCode
+		code  string
+		want  constants.ReasonCode
+	}{
+		{gatewayAReasonCodes, "INSUFFICIENT_FUNDS", constants.ReasonInsufficientFunds},
+		{gatewayAReasonCodes, "account_closed", constants.ReasonInvalidAccount},
+		{gatewayAReasonCodes, "SOMETHING_NEW", constants.ReasonDeclined},
+		{gatewayAReasonCodes, "", constants.ReasonDeclined},
+		{gatewayBReasonCodes, "B051", constants.ReasonInsufficientFunds},
+		{gatewayBReasonCodes, "B005", constants.ReasonDoNotHonor},
+		{gatewayBReasonCodes, "INSUFFICIENT_FUNDS", constants.ReasonDeclined},
+	}
+	for _, tt := range tests {
+		if got := reasonForCode(tt.table, tt.code); got != tt.want {
+			t.Errorf("reasonForCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestReasonForError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want constants.ReasonCode
+	}{
+		{apperrors.WithMessage(apperrors.ErrGatewayTimeout, "gateway A timeout"), constants.ReasonTimeout},
+		{context.DeadlineExceeded, constants.ReasonTimeout},
+		{fmt.Errorf("%w: dial tcp: refused", apperrors.ErrGatewayNotAvailable), constants.ReasonGatewayUnavailable},
+		{apperrors.ErrNoGatewayAvailable, constants.ReasonGatewayUnavailable},
+		{apperrors.ErrProcessingFailed, constants.ReasonGatewayError},
+	}
+	for _, tt := range tests {
+		if got := ReasonForError(tt.err); got != tt.want {
+			t.Errorf("ReasonForError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
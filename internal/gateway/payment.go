@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/constants"
+	"strings"
+)
+
+// Outcome is the gateway's answer normalized across adapters.
+type Outcome string
+
+const (
+	OutcomeApproved Outcome = "approved"
+	OutcomeDeclined Outcome = "declined"
+	OutcomePending  Outcome = "pending"
+)
+
+// OutcomeFromStatus maps a gateway's business status to an Outcome. Unknown
+// statuses are reported as not ok so they are never mistaken for approvals.
+func OutcomeFromStatus(status string) (Outcome, bool) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "succeeded", "approved", "ok":
+		return OutcomeApproved, true
+	case "failed", "failure", "declined", "rejected", "error":
+		return OutcomeDeclined, true
+	case "pending", "processing", "accepted":
+		return OutcomePending, true
+	default:
+		return "", false
+	}
+}
+
+// PaymentRequest is what the transaction service asks a gateway to process.
+type PaymentRequest struct {
+	TransactionID string
+	Account       string
+	Amount        float64
+	Currency      string
+	MerchantID    string
+	Metadata      map[string]string
+}
+
+// PaymentResult is a gateway's answer to a PaymentRequest.
+type PaymentResult struct {
+	// GatewayRef is the gateway's own reference for the payment, if any.
+	GatewayRef string
+	Outcome    Outcome
+	// Reason is the normalized decline reason; empty unless Outcome is declined.
+	Reason constants.ReasonCode
+	// GatewayCode is the gateway's own decline or result code, if any.
+	GatewayCode string
+	Message     string
+	// StatusCode and RawResponse are kept as received for troubleshooting.
+	StatusCode  int
+	RawResponse []byte
+}
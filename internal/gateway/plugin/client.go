@@ -0,0 +1,151 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// maxLineBytes bounds one protocol message.
+const maxLineBytes = 1 << 20
+
+// errPluginExited fails the calls a plugin process left unanswered.
+var errPluginExited = errors.New("plugin exited")
+
+// notSentError marks a call that never reached the plugin, so it cannot
+// have been processed.
+type notSentError struct{ err error }
+
+func (e *notSentError) Error() string { return "not sent: " + e.err.Error() }
+func (e *notSentError) Unwrap() error { return e.err }
+
+// NotSent reports whether err is from a call that never reached the plugin.
+func NotSent(err error) bool {
+	var target *notSentError
+	return errors.As(err, &target)
+}
+
+// client makes JSON-RPC calls over a plugin's stdin and stdout. Calls may be
+// concurrent; responses are matched by ID.
+type client struct {
+	w       io.Writer
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *response
+	err     error // set once the plugin's output ended
+}
+
+func newClient(w io.Writer) *client {
+	return &client{w: w, pending: make(map[string]chan *response)}
+}
+
+// call sends method with params and decodes the result into result, which
+// may be nil. A plugin's error answer is returned as an *Error.
+func (c *client) call(ctx context.Context, method string, params, result any) error {
+	var rawParams json.RawMessage
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return &notSentError{err}
+		}
+		rawParams = data
+	}
+
+	reply := make(chan *response, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return &notSentError{err}
+	}
+	c.nextID++
+	id := strconv.FormatInt(c.nextID, 10)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	line, err := json.Marshal(request{JSONRPC: "2.0", ID: json.RawMessage(id), Method: method, Params: rawParams})
+	if err != nil {
+		return &notSentError{err}
+	}
+	c.writeMu.Lock()
+	_, err = c.w.Write(append(line, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		// A pipe write either reaches the plugin whole or not at all
+		return &notSentError{err}
+	}
+
+	select {
+	case resp, ok := <-reply:
+		if !ok {
+			return errPluginExited
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("malformed %s result: %w", method, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// read dispatches the responses on r until it ends or carries something
+// that is not a response, then fails the calls still waiting.
+func (c *client) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	var err error
+	for scanner.Scan() {
+		var resp response
+		if err = json.Unmarshal(scanner.Bytes(), &resp); err != nil || resp.JSONRPC != "2.0" {
+			err = fmt.Errorf("plugin wrote a malformed message to stdout: %q", truncate(scanner.Text(), 200))
+			break
+		}
+		c.mu.Lock()
+		reply, ok := c.pending[string(resp.ID)]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case reply <- &resp:
+			default: // a second answer to the same call
+			}
+		}
+	}
+	if err == nil {
+		err = scanner.Err()
+	}
+
+	c.mu.Lock()
+	c.err = errPluginExited
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	return err
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,227 @@
+// Package plugin runs gateway adapters as separate executables, so adapters
+// maintained elsewhere need not be compiled into the server. The server
+// launches each plugin, speaks JSON-RPC 2.0 to it over its stdin and stdout
+// (one message per line; stderr is logged), health-checks it and restarts it
+// when it crashes or hangs. Gateways use it with type: plugin; Serve is the
+// plugin side for adapters written in Go.
+package plugin
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/resilience"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	gateway.Register("plugin", gateway.WithSettings(DefaultSettings, New))
+}
+
+// Gateway sends payments to a plugin process.
+type Gateway struct {
+	name       string
+	Process    *Process
+	Resilience *resilience.Executor
+
+	codes map[string]constants.ReasonCode
+}
+
+// New builds the gateway configured under key from validated settings and
+// starts its plugin.
+func New(key string, cfg config.GatewayConfig, settings Settings) (gateway.PaymentGateway, error) {
+	initialize := InitializeParams{
+		Gateway:         key,
+		Name:            cfg.Name,
+		URL:             cfg.URL,
+		ProtocolVersion: ProtocolVersion,
+		Config:          make(map[string]string, len(settings.Config)),
+	}
+	for k, v := range settings.Config {
+		initialize.Config[k] = os.ExpandEnv(v)
+	}
+	process, err := StartProcess(cfg.Name, settings, initialize)
+	if err != nil {
+		return nil, fmt.Errorf("command: %w", err)
+	}
+	g := &Gateway{
+		name:       cfg.Name,
+		Process:    process,
+		Resilience: resilience.NewExecutor(cfg.Name, cfg.Resilience),
+		codes:      make(map[string]constants.ReasonCode, len(settings.DeclineCodes)),
+	}
+	for code, reason := range settings.DeclineCodes {
+		g.codes[strings.ToUpper(code)] = reason
+	}
+	return g, nil
+}
+
+// ProcessDeposit calls the plugin's deposit method.
+func (g *Gateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, MethodDeposit, req)
+}
+
+// ProcessWithdrawal calls the plugin's withdrawal method.
+func (g *Gateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, MethodWithdrawal, req)
+}
+
+// HealthCheck calls the plugin's health method. Like HTTP probes it bypasses
+// the executor. Unanswered checks count towards restarting a hung plugin.
+func (g *Gateway) HealthCheck(ctx context.Context) error {
+	inst, err := g.Process.call(ctx, MethodHealth, nil, nil)
+	if inst != nil {
+		var pluginErr *Error
+		g.Process.healthChecked(inst, err == nil || errors.As(err, &pluginErr))
+	}
+	return err
+}
+
+// Name returns the gateway's configured name.
+func (g *Gateway) Name() string {
+	return g.name
+}
+
+// Available reports whether the gateway's circuit breaker lets calls through.
+func (g *Gateway) Available() bool {
+	return g.Resilience.Available()
+}
+
+// Close stops the plugin process.
+func (g *Gateway) Close() error {
+	return g.Process.Close()
+}
+
+// send makes the call through the executor and converts the reply into a
+// PaymentResult. Failures where no attempt can have been processed are tagged
+// with gateway.ErrNotProcessed.
+func (g *Gateway) send(ctx context.Context, method string, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "plugin.Gateway.send"),
+		zap.String("gateway", g.name),
+		zap.String("operation", method),
+		zap.String("transaction_id", req.TransactionID),
+	)
+	params := PaymentParams{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		MerchantID:    req.MerchantID,
+		Metadata:      req.Metadata,
+	}
+
+	log.Info("Sending request to gateway")
+	var reply PaymentReply
+	refusedAll := true // no attempt so far may have been processed
+	err := g.Resilience.Execute(ctx, func(ctx context.Context) error {
+		reply = PaymentReply{}
+		_, err := g.Process.call(ctx, method, params, &reply)
+		if err == nil {
+			refusedAll = false
+			return nil
+		}
+		var pluginErr *Error
+		if !errors.As(err, &pluginErr) {
+			if !NotSent(err) {
+				refusedAll = false
+			}
+			return err
+		}
+		if !refused(pluginErr) {
+			refusedAll = false
+		}
+		if !retryable(pluginErr) {
+			return resilience.Permanent(err)
+		}
+		if pluginErr.Data != nil && pluginErr.Data.RetryAfterMillis > 0 {
+			return resilience.RetryAfter(err, time.Duration(pluginErr.Data.RetryAfterMillis)*time.Millisecond)
+		}
+		return err
+	})
+	if err != nil {
+		log.Error("Gateway request error", zap.Error(err))
+		err = g.failure(err)
+		if refusedAll {
+			return nil, gateway.NotProcessed(err)
+		}
+		return nil, err
+	}
+
+	result, err := g.result(reply)
+	if err != nil {
+		log.Error("Unusable gateway response", zap.Error(err))
+		return nil, err
+	}
+	log.Info("Gateway request completed",
+		zap.String("gateway_status", reply.Outcome),
+		zap.String("gateway_code", result.GatewayCode),
+		zap.String("gateway_ref", result.GatewayRef),
+	)
+	return result, nil
+}
+
+// failure converts the executor's last error into the application's gateway errors.
+func (g *Gateway) failure(err error) error {
+	if errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+		// The circuit breaker or bulkhead turned the call away
+		return err
+	}
+	var pluginErr *Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return apperrors.WithMessage(apperrors.ErrGatewayTimeout, g.name+" timeout")
+	case !errors.As(err, &pluginErr):
+		// The plugin was not running or exited during the call
+		return fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
+	case pluginErr.Code == CodeUnavailable:
+		return fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
+	case pluginErr.Code == CodeTimeout:
+		return fmt.Errorf("%w: %w", apperrors.ErrGatewayTimeout, err)
+	default:
+		return fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err)
+	}
+}
+
+func (g *Gateway) result(reply PaymentReply) (*gateway.PaymentResult, error) {
+	outcome := gateway.Outcome(reply.Outcome)
+	switch outcome {
+	case gateway.OutcomeApproved, gateway.OutcomeDeclined, gateway.OutcomePending:
+	default:
+		return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("%s returned unknown outcome %q", g.name, reply.Outcome))
+	}
+	raw, _ := json.Marshal(reply)
+	result := &gateway.PaymentResult{
+		Outcome:     outcome,
+		GatewayRef:  reply.Reference,
+		GatewayCode: reply.Code,
+		Message:     reply.Message,
+		RawResponse: raw,
+	}
+	if outcome == gateway.OutcomeDeclined {
+		result.Reason = g.reason(reply)
+	}
+	return result, nil
+}
+
+// reason takes the plugin's normalized reason, or maps its decline code
+// through DeclineCodes, and falls back to a generic decline.
+func (g *Gateway) reason(reply PaymentReply) constants.ReasonCode {
+	if reason := constants.ReasonCode(reply.Reason); gateway.KnownReason(reason) {
+		return reason
+	}
+	if reason, ok := g.codes[strings.ToUpper(strings.TrimSpace(reply.Code))]; ok {
+		return reason
+	}
+	return constants.ReasonDeclined
+}
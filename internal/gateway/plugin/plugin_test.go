@@ -0,0 +1,247 @@
+package plugin
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/gatewaytest"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary run as the plugin the tests launch: with
+// PLUGIN_TEST_MODE set it serves testGateway instead of running the tests.
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("PLUGIN_TEST_MODE"); mode != "" {
+		if mode == "garbage" {
+			fmt.Println("hello from a plugin that forgot stdout is the protocol")
+		}
+		if err := Serve(func(params InitializeParams) (gateway.PaymentGateway, error) {
+			return &testGateway{params: params, hang: mode == "hang"}, nil
+		}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testGateway answers for the scenario selected by the account prefix.
+type testGateway struct {
+	params InitializeParams
+	hang   bool
+}
+
+func (g *testGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	switch {
+	case strings.HasPrefix(req.Account, "nsf"):
+		return &gateway.PaymentResult{Outcome: gateway.OutcomeDeclined, Reason: constants.ReasonInsufficientFunds, GatewayCode: "51"}, nil
+	case strings.HasPrefix(req.Account, "decline"):
+		return &gateway.PaymentResult{Outcome: gateway.OutcomeDeclined, GatewayCode: "r05"}, nil
+	case strings.HasPrefix(req.Account, "pending"):
+		return &gateway.PaymentResult{Outcome: gateway.OutcomePending, GatewayRef: "P-" + req.TransactionID}, nil
+	case strings.HasPrefix(req.Account, "busy"):
+		return nil, gateway.NotProcessed(apperrors.ErrGatewayNotAvailable)
+	case strings.HasPrefix(req.Account, "bad"):
+		return nil, gateway.NotProcessed(apperrors.ErrInvalidRequest)
+	case strings.HasPrefix(req.Account, "crash"):
+		os.Exit(3)
+	case strings.HasPrefix(req.Account, "slow"):
+		time.Sleep(1500 * time.Millisecond)
+	}
+	return &gateway.PaymentResult{
+		Outcome:    gateway.OutcomeApproved,
+		GatewayRef: "P-" + req.TransactionID,
+		Message:    g.params.Name + " " + g.params.Config["greeting"] + " " + os.Getenv("PLUGIN_TEST_SECRET"),
+	}, nil
+}
+
+func (g *testGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.ProcessDeposit(ctx, req)
+}
+
+func (g *testGateway) HealthCheck(ctx context.Context) error {
+	if g.hang {
+		select {} // ignores ctx, like a deadlocked plugin
+	}
+	return nil
+}
+
+func testConfig(t *testing.T, mode string) config.GatewayConfig {
+	t.Helper()
+	return gatewaytest.Config(t, "plugin", "", fmt.Sprintf(`
+command: %q
+env: {PLUGIN_TEST_MODE: %s, PLUGIN_TEST_SECRET: "${PLUGIN_TEST_SECRET}"}
+config: {greeting: hello}
+restartBackoffMillis: 50
+maxRestartBackoffMillis: 100
+stopTimeoutSeconds: 1
+declineCodes: {R05: do_not_honor}`, os.Args[0], mode))
+}
+
+func newGateway(t *testing.T, mode string) *Gateway {
+	t.Helper()
+	t.Setenv("PLUGIN_TEST_SECRET", "s3cret")
+	return gatewaytest.New(t, "gatewayPlugin", testConfig(t, mode)).(*Gateway)
+}
+
+func TestGateway_Plugin(t *testing.T) {
+	gw := newGateway(t, "serve")
+	tests := []struct {
+		account     string
+		wantOutcome gateway.Outcome
+		wantReason  constants.ReasonCode
+	}{
+		{"acc-1", gateway.OutcomeApproved, ""},
+		{"nsf-1", gateway.OutcomeDeclined, constants.ReasonInsufficientFunds},
+		{"decline-1", gateway.OutcomeDeclined, constants.ReasonDoNotHonor},
+		{"pending-1", gateway.OutcomePending, ""},
+	}
+	for _, tt := range tests {
+		result, err := gw.ProcessWithdrawal(context.Background(), gatewaytest.PaymentRequest(tt.account, 12.34))
+		if err != nil {
+			t.Fatalf("%s: ProcessWithdrawal: %v", tt.account, err)
+		}
+		if result.Outcome != tt.wantOutcome || result.Reason != tt.wantReason {
+			t.Errorf("%s: unexpected result %+v", tt.account, result)
+		}
+	}
+
+	result, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-2", 12.34))
+	if err != nil {
+		t.Fatalf("ProcessDeposit: %v", err)
+	}
+	// The plugin got its config and environment, but not the server's
+	if result.GatewayRef != "P-tx-acc-2" || result.Message != "GatewayUnderTest hello s3cret" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if err := gateway.ProbeFor(gw, nil, "")(context.Background()); err != nil {
+		t.Errorf("expected the health check to pass, got %v", err)
+	}
+}
+
+func TestGateway_PluginErrors(t *testing.T) {
+	gw := newGateway(t, "serve")
+	tests := []struct {
+		account      string
+		wantErr      error
+		wantFailover bool
+	}{
+		{"busy-1", apperrors.ErrGatewayNotAvailable, true},
+		{"bad-1", apperrors.ErrProcessingFailed, true},
+		{"slow-1", apperrors.ErrGatewayTimeout, false},
+	}
+	for _, tt := range tests {
+		_, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest(tt.account, 12.34))
+		if !errors.Is(err, tt.wantErr) || gateway.SafeToFailover(err) != tt.wantFailover {
+			t.Errorf("%s: expected %v with failover %v, got %v", tt.account, tt.wantErr, tt.wantFailover, err)
+		}
+	}
+}
+
+func TestGateway_RestartsCrashedPlugin(t *testing.T) {
+	gw := newGateway(t, "serve")
+	if _, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34)); err != nil {
+		t.Fatalf("ProcessDeposit: %v", err)
+	}
+
+	// The plugin crashes on every attempt: the payment may have been processed
+	_, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("crash-1", 12.34))
+	if !errors.Is(err, apperrors.ErrGatewayNotAvailable) || gateway.SafeToFailover(err) {
+		t.Fatalf("expected an unavailable failure that is not safe to fail over, got %v", err)
+	}
+
+	result, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-2", 12.34))
+	if err != nil || result.Outcome != gateway.OutcomeApproved {
+		t.Fatalf("expected the restarted plugin to approve, got %+v, %v", result, err)
+	}
+	gw.Process.mu.Lock()
+	defer gw.Process.mu.Unlock()
+	if gw.Process.restarts < 2 {
+		t.Errorf("expected a restart per crash, got %d", gw.Process.restarts)
+	}
+}
+
+func TestGateway_RestartsHungPlugin(t *testing.T) {
+	gw := newGateway(t, "hang")
+	if _, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34)); err != nil {
+		t.Fatalf("ProcessDeposit: %v", err)
+	}
+	gw.Process.mu.Lock()
+	first := gw.Process.inst
+	gw.Process.mu.Unlock()
+
+	for i := 0; i < DefaultSettings().MaxHealthFailures; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err := gw.HealthCheck(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the health check to go unanswered, got %v", err)
+		}
+	}
+	select {
+	case <-first.exited:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the hung plugin was not killed")
+	}
+	if _, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-2", 12.34)); err != nil {
+		t.Errorf("expected the restarted plugin to answer, got %v", err)
+	}
+}
+
+func TestGateway_PluginThatCannotStart(t *testing.T) {
+	gw := newGateway(t, "garbage")
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_, err := gw.ProcessDeposit(ctx, gatewaytest.PaymentRequest("acc-1", 12.34))
+	if !gateway.SafeToFailover(err) {
+		t.Errorf("expected a not processed failure, got %v", err)
+	}
+}
+
+func TestGateway_Close(t *testing.T) {
+	gw := newGateway(t, "serve")
+	if err := gw.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	gw.Process.mu.Lock()
+	inst := gw.Process.inst
+	gw.Process.mu.Unlock()
+
+	gw.Close()
+	select {
+	case <-inst.exited:
+	default:
+		t.Fatal("the plugin is still running")
+	}
+	if inst.err != nil {
+		t.Errorf("expected the plugin to exit cleanly once its stdin closed, got %v", inst.err)
+	}
+	_, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", 12.34))
+	if !errors.Is(err, ErrClosed) || !gateway.SafeToFailover(err) {
+		t.Errorf("expected a not processed ErrClosed, got %v", err)
+	}
+}
+
+func TestNew_InvalidConfiguration(t *testing.T) {
+	tests := map[string]string{
+		"no command":      `{args: [x]}`,
+		"missing command": `{command: ./no-such-plugin}`,
+		"env":             `{command: sh, env: {PATH: /tmp}}`,
+		"backoff":         `{command: sh, restartBackoffMillis: 100, maxRestartBackoffMillis: 10}`,
+		"decline code":    `{command: sh, declineCodes: {X: broke}}`,
+		"unknown key":     `{command: sh, retries: 3}`,
+	}
+	for name, settings := range tests {
+		if gw, err := gateway.New("gatewayPlugin", gatewaytest.Config(t, "plugin", "", settings)); err == nil {
+			gw.(*Gateway).Close()
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
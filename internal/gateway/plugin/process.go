@@ -0,0 +1,281 @@
+package plugin
+
+import (
+	"Payment-Gateway/pkg/logger"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.uber.org/zap"
+)
+
+// ErrClosed is returned for calls made after Process.Close.
+var ErrClosed = errors.New("plugin: process closed")
+
+// Process runs a plugin executable and keeps it running: a process that
+// exits, fails its initialize call or is found hung is restarted with backoff
+// until Close.
+type Process struct {
+	name       string
+	path       string
+	env        []string
+	settings   Settings
+	initialize InitializeParams
+
+	mu       sync.Mutex
+	inst     *instance     // the initialized process; nil while (re)starting
+	ready    chan struct{} // closed once inst is set
+	closed   bool
+	restarts int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// instance is one run of the plugin executable.
+type instance struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	client *client
+	exited chan struct{} // closed once the process was waited for
+	err    error         // why it exited; set before exited is closed
+
+	healthFailures int // guarded by Process.mu
+}
+
+// StartProcess resolves the executable and starts supervising it. Calls wait
+// for the first process to be initialized.
+func StartProcess(name string, settings Settings, initialize InitializeParams) (*Process, error) {
+	path, err := exec.LookPath(settings.Command)
+	if err != nil {
+		return nil, err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+	env := []string{"PATH=" + os.Getenv("PATH")}
+	for key, value := range settings.Env {
+		env = append(env, key+"="+os.ExpandEnv(value))
+	}
+	sort.Strings(env[1:])
+
+	p := &Process{
+		name:       name,
+		path:       path,
+		env:        env,
+		settings:   settings,
+		initialize: initialize,
+		ready:      make(chan struct{}),
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go p.supervise()
+	return p, nil
+}
+
+// Close stops the plugin, giving it StopTimeoutSeconds to exit once its stdin
+// is closed, and stops restarting it.
+func (p *Process) Close() error {
+	p.stopOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		close(p.stop)
+	})
+	<-p.stopped
+	return nil
+}
+
+// call makes a call to the running plugin, waiting for one to be initialized
+// until ctx is done.
+func (p *Process) call(ctx context.Context, method string, params, result any) (*instance, error) {
+	inst, err := p.running(ctx)
+	if err != nil {
+		return nil, &notSentError{err}
+	}
+	return inst, inst.client.call(ctx, method, params, result)
+}
+
+func (p *Process) running(ctx context.Context) (*instance, error) {
+	for {
+		p.mu.Lock()
+		inst, ready, closed := p.inst, p.ready, p.closed
+		p.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		if inst != nil {
+			return inst, nil
+		}
+		select {
+		case <-ready:
+		case <-p.stop:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("plugin is not running: %w", ctx.Err())
+		}
+	}
+}
+
+// healthChecked records a health check of inst. After MaxHealthFailures
+// unanswered checks in a row the process is killed, to be restarted.
+func (p *Process) healthChecked(inst *instance, answered bool) {
+	p.mu.Lock()
+	if answered {
+		inst.healthFailures = 0
+		p.mu.Unlock()
+		return
+	}
+	inst.healthFailures++
+	hung := p.settings.MaxHealthFailures > 0 && inst.healthFailures == p.settings.MaxHealthFailures
+	p.mu.Unlock()
+	if hung {
+		logger.GetLogger().Error("Plugin left its health checks unanswered; restarting it",
+			zap.String("func", "plugin.Process.healthChecked"), zap.String("gateway", p.name),
+			zap.Int("pid", inst.cmd.Process.Pid), zap.Int("failures", p.settings.MaxHealthFailures))
+		inst.cmd.Process.Kill()
+	}
+}
+
+// supervise starts the plugin and restarts it whenever it exits, until Close.
+func (p *Process) supervise() {
+	defer close(p.stopped)
+	log := logger.GetLogger().With(zap.String("func", "plugin.Process.supervise"), zap.String("gateway", p.name), zap.String("command", p.path))
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Duration(p.settings.RestartBackoffMillis) * time.Millisecond
+	b.MaxInterval = time.Duration(p.settings.MaxRestartBackoffMillis) * time.Millisecond
+	b.MaxElapsedTime = 0
+	b.Reset()
+	for {
+		started := time.Now()
+		inst, err := p.start(log)
+		if err != nil {
+			log.Error("Plugin failed to start", zap.Error(err))
+		} else {
+			log.Info("Plugin started", zap.Int("pid", inst.cmd.Process.Pid))
+			p.mu.Lock()
+			p.inst = inst
+			close(p.ready)
+			p.mu.Unlock()
+
+			select {
+			case <-inst.exited:
+				p.forget(inst)
+				log.Error("Plugin exited", zap.Int("pid", inst.cmd.Process.Pid), zap.Error(inst.err))
+			case <-p.stop:
+				p.forget(inst)
+				p.shutdown(inst)
+				log.Info("Plugin stopped", zap.Int("pid", inst.cmd.Process.Pid))
+				return
+			}
+		}
+
+		if time.Since(started) >= b.MaxInterval {
+			b.Reset()
+		}
+		wait := b.NextBackOff()
+		log.Info("Restarting plugin", zap.Duration("after", wait))
+		select {
+		case <-time.After(wait):
+		case <-p.stop:
+			return
+		}
+		p.mu.Lock()
+		p.restarts++
+		p.mu.Unlock()
+	}
+}
+
+// start launches the executable and initializes it.
+func (p *Process) start(log *zap.Logger) (*instance, error) {
+	cmd := exec.Command(p.path, p.settings.Args...)
+	cmd.Dir = p.settings.Dir
+	cmd.Env = p.env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	inst := &instance{cmd: cmd, stdin: stdin, client: newClient(stdin), exited: make(chan struct{})}
+	go inst.run(stdout, stderr, log.With(zap.Int("pid", cmd.Process.Pid)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.settings.StartTimeoutSeconds)*time.Second)
+	defer cancel()
+	var result InitializeResult
+	err = inst.client.call(ctx, MethodInitialize, p.initialize, &result)
+	if err == nil && result.ProtocolVersion != ProtocolVersion {
+		err = fmt.Errorf("plugin speaks protocol version %d, want %d", result.ProtocolVersion, ProtocolVersion)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		<-inst.exited
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+	return inst, nil
+}
+
+// forget makes later calls wait for the next process once inst is gone.
+func (p *Process) forget(inst *instance) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inst == inst {
+		p.inst = nil
+		p.ready = make(chan struct{})
+	}
+}
+
+// shutdown closes the plugin's stdin, which asks it to exit, and kills it if
+// it has not within StopTimeoutSeconds.
+func (p *Process) shutdown(inst *instance) {
+	inst.stdin.Close()
+	select {
+	case <-inst.exited:
+	case <-time.After(time.Duration(p.settings.StopTimeoutSeconds) * time.Second):
+		inst.cmd.Process.Kill()
+		<-inst.exited
+	}
+}
+
+// run reads the plugin's output until it ends, then reaps the process: a
+// plugin that closed its stdout or broke the protocol is killed, as it can no
+// longer answer.
+func (inst *instance) run(stdout, stderr io.Reader, log *zap.Logger) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+		for scanner.Scan() {
+			log.Info("Plugin output", zap.String("stderr", scanner.Text()))
+		}
+		io.Copy(io.Discard, stderr) // past an overlong line
+	}()
+	if err := inst.client.read(stdout); err != nil {
+		log.Error("Plugin broke the protocol; killing it", zap.Error(err))
+	}
+	inst.cmd.Process.Kill()
+	wg.Wait()
+	inst.err = inst.cmd.Wait()
+	close(inst.exited)
+}
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the version of the plugin protocol the server speaks. A
+// plugin answering initialize with another version is not started.
+const ProtocolVersion = 1
+
+// Methods a plugin serves. The server only sends requests; a plugin never
+// calls the server.
+const (
+	// MethodInitialize is the first call, made once per process with
+	// InitializeParams; the plugin answers with InitializeResult.
+	MethodInitialize = "initialize"
+	// MethodDeposit and MethodWithdrawal take PaymentParams and answer with
+	// PaymentReply.
+	MethodDeposit    = "deposit"
+	MethodWithdrawal = "withdrawal"
+	// MethodHealth takes no params and answers with any result when the
+	// plugin can take payments, or with an error when it cannot.
+	MethodHealth = "health"
+)
+
+// Error codes a plugin answers with, besides the standard JSON-RPC ones
+// (-32700 to -32603). They tell the server whether the payment may have been
+// processed.
+const (
+	// CodeUnavailable: the provider could not be reached or turned the
+	// payment away unprocessed. It is retried and may fail over.
+	CodeUnavailable = -32001
+	// CodeRejected: the provider rejected the request itself without
+	// processing it. It is not retried and may fail over.
+	CodeRejected = -32002
+	// CodeTimeout: the provider did not answer in time; the payment may have
+	// been processed. It is retried with the same transaction ID.
+	CodeTimeout = -32003
+	// CodeFailed: the payment failed with an unknown outcome. It is retried
+	// with the same transaction ID.
+	CodeFailed = -32004
+
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// InitializeParams hand a new plugin process the configuration of the
+// gateway it serves.
+type InitializeParams struct {
+	Gateway         string `json:"gateway"`
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	ProtocolVersion int    `json:"protocol_version"`
+	// Config is the gateway's settings.config block, with ${VAR} replaced
+	// from the server's environment.
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// InitializeResult is the plugin's answer to initialize.
+type InitializeResult struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// PaymentParams carry a deposit or withdrawal. Plugins must treat a repeated
+// transaction ID as a retry of the same payment.
+type PaymentParams struct {
+	TransactionID string            `json:"transaction_id"`
+	Account       string            `json:"account"`
+	Amount        float64           `json:"amount"`
+	Currency      string            `json:"currency"`
+	MerchantID    string            `json:"merchant_id,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// PaymentReply is the provider's answer to a payment.
+type PaymentReply struct {
+	// Outcome is approved, declined or pending.
+	Outcome   string `json:"outcome"`
+	Reference string `json:"reference,omitempty"`
+	// Reason is the normalized decline reason, e.g. insufficient_funds.
+	Reason  string `json:"reason,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Error is a JSON-RPC error object; plugins answer failed calls with one.
+type Error struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *ErrorData `json:"data,omitempty"`
+}
+
+// ErrorData is the optional detail of an Error.
+type ErrorData struct {
+	// RetryAfterMillis asks the server to wait before retrying.
+	RetryAfterMillis int `json:"retry_after_ms,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// request and response are JSON-RPC 2.0 messages, one per line.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// refused reports whether a plugin answered err proving the payment was not
+// processed.
+func refused(err *Error) bool {
+	switch err.Code {
+	case CodeUnavailable, CodeRejected, codeParseError, codeInvalidRequest, codeMethodNotFound, codeInvalidParams:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether another attempt may get a different answer.
+func retryable(err *Error) bool {
+	switch err.Code {
+	case CodeUnavailable, CodeTimeout, CodeFailed, codeInternalError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"Payment-Gateway/internal/gateway"
+	apperrors "Payment-Gateway/pkg/error"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// healthTimeout bounds a served gateway's health check, so that a slow
+// provider is reported unhealthy instead of leaving the check unanswered,
+// which would get the plugin restarted.
+const healthTimeout = time.Second
+
+// Builder builds the gateway a plugin serves from the server's initialize call.
+type Builder func(params InitializeParams) (gateway.PaymentGateway, error)
+
+// Serve is the main loop of a plugin written in Go: it answers the server's
+// calls on stdin and stdout with the gateway build returns, and returns once
+// stdin is closed, which is the server asking it to exit. Anything else the
+// plugin prints must go to stderr, as the logger's output does.
+func Serve(build Builder) error {
+	return serve(os.Stdin, os.Stdout, build)
+}
+
+// server answers calls for one plugin process. Payments are served
+// concurrently.
+type server struct {
+	build Builder
+	gw    gateway.PaymentGateway // set by initialize, before later calls are read
+
+	writeMu sync.Mutex
+	w       io.Writer
+}
+
+func serve(r io.Reader, w io.Writer, build Builder) error {
+	s := &server{build: build, w: w}
+	var wg sync.WaitGroup
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			s.reply(nil, nil, &Error{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			s.reply(req.ID, nil, &Error{Code: codeInvalidRequest, Message: "not a JSON-RPC 2.0 request"})
+			continue
+		}
+		if req.ID == nil {
+			continue // notifications need no answer
+		}
+		if req.Method == MethodInitialize {
+			// Answered before reading on, so later calls see the gateway
+			result, rpcErr := s.initialize(req)
+			s.reply(req.ID, result, rpcErr)
+			continue
+		}
+		gw := s.gw
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, rpcErr := s.handle(req, gw)
+			s.reply(req.ID, result, rpcErr)
+		}()
+	}
+	wg.Wait()
+	return scanner.Err()
+}
+
+func (s *server) initialize(req request) (any, *Error) {
+	var params InitializeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &Error{Code: codeInvalidParams, Message: err.Error()}
+	}
+	gw, err := s.build(params)
+	if err != nil {
+		return nil, &Error{Code: CodeRejected, Message: err.Error()}
+	}
+	s.gw = gw
+	return InitializeResult{ProtocolVersion: ProtocolVersion}, nil
+}
+
+// handle answers a call other than initialize with gw, the gateway as of
+// when the call was read.
+func (s *server) handle(req request, gw gateway.PaymentGateway) (any, *Error) {
+	if gw == nil && (req.Method == MethodDeposit || req.Method == MethodWithdrawal || req.Method == MethodHealth) {
+		return nil, &Error{Code: CodeRejected, Message: "plugin not initialized"}
+	}
+	switch req.Method {
+	case MethodDeposit, MethodWithdrawal:
+		var params PaymentParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &Error{Code: codeInvalidParams, Message: err.Error()}
+		}
+		process := gw.ProcessDeposit
+		if req.Method == MethodWithdrawal {
+			process = gw.ProcessWithdrawal
+		}
+		result, err := process(context.Background(), gateway.PaymentRequest{
+			TransactionID: params.TransactionID,
+			Account:       params.Account,
+			Amount:        params.Amount,
+			Currency:      params.Currency,
+			MerchantID:    params.MerchantID,
+			Metadata:      params.Metadata,
+		})
+		if err != nil {
+			return nil, errorFor(err)
+		}
+		return PaymentReply{
+			Outcome:   string(result.Outcome),
+			Reference: result.GatewayRef,
+			Reason:    string(result.Reason),
+			Code:      result.GatewayCode,
+			Message:   result.Message,
+		}, nil
+	case MethodHealth:
+		if checker, ok := gw.(gateway.HealthChecker); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+			defer cancel()
+			if err := checker.HealthCheck(ctx); err != nil {
+				return nil, &Error{Code: CodeUnavailable, Message: err.Error()}
+			}
+		}
+		return struct{}{}, nil
+	default:
+		return nil, &Error{Code: codeMethodNotFound, Message: "unknown method " + req.Method}
+	}
+}
+
+// errorFor converts a PaymentGateway error into the error code telling the
+// server whether the payment may have been processed.
+func errorFor(err error) *Error {
+	code := CodeFailed
+	switch {
+	case gateway.SafeToFailover(err) && errors.Is(err, apperrors.ErrGatewayNotAvailable):
+		code = CodeUnavailable
+	case gateway.SafeToFailover(err):
+		code = CodeRejected
+	case errors.Is(err, apperrors.ErrGatewayTimeout):
+		code = CodeTimeout
+	}
+	return &Error{Code: code, Message: err.Error()}
+}
+
+func (s *server) reply(id json.RawMessage, result any, rpcErr *Error) {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	if rpcErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &Error{Code: codeInternalError, Message: err.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+	line, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.w.Write(append(line, '\n'))
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"Payment-Gateway/internal/gateway"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServe_Protocol(t *testing.T) {
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"deposit","params":{"transaction_id":"tx-1","account":"acc-1","amount":1}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"gateway":"gatewayPlugin","protocol_version":1}}`,
+		`{"jsonrpc":"2.0","id":"three","method":"refund"}`,
+		`{"jsonrpc":"2.0","method":"health"}`,
+		`not json`,
+		`{"id":5,"method":"health"}`,
+		`{"jsonrpc":"2.0","id":6,"method":"deposit","params":{"amount":"ten"}}`,
+	}, "\n")
+	var out bytes.Buffer
+	err := serve(strings.NewReader(input), &out, func(params InitializeParams) (gateway.PaymentGateway, error) {
+		if params.Gateway != "gatewayPlugin" {
+			return nil, errors.New("unexpected gateway " + params.Gateway)
+		}
+		return &testGateway{params: params}, nil
+	})
+	if err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	wantCodes := map[string]int{
+		"1":       CodeRejected, // before initialize
+		"2":       0,
+		`"three"`: codeMethodNotFound,
+		"null":    codeParseError,
+		"5":       codeInvalidRequest,
+		"6":       codeInvalidParams,
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(wantCodes) {
+		t.Fatalf("expected %d responses, got %q", len(wantCodes), lines)
+	}
+	for _, line := range lines {
+		var resp response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil || resp.JSONRPC != "2.0" {
+			t.Fatalf("malformed response %q", line)
+		}
+		want, ok := wantCodes[string(resp.ID)]
+		if !ok {
+			t.Errorf("unexpected response %q", line)
+			continue
+		}
+		switch {
+		case want == 0 && (resp.Error != nil || string(resp.Result) != `{"protocol_version":1}`):
+			t.Errorf("id %s: expected the initialize result, got %q", resp.ID, line)
+		case want != 0 && (resp.Error == nil || resp.Error.Code != want):
+			t.Errorf("id %s: expected error %d, got %q", resp.ID, want, line)
+		}
+	}
+}
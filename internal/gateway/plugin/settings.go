@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+	"strings"
+)
+
+// Settings configure a plugin gateway under a gateway's settings block. The
+// gateway's url, if any, is handed to the plugin at initialize.
+type Settings struct {
+	// Command is the plugin executable: a path, relative to the server's
+	// working directory, or a name looked up in PATH.
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Dir     string   `yaml:"dir"`
+	// Env is the plugin's environment besides PATH, which it inherits; ${VAR}
+	// is replaced from the server's environment so secrets stay out of
+	// config.yaml.
+	Env map[string]string `yaml:"env"`
+	// Config is passed to the plugin at initialize, with ${VAR} replaced the
+	// same way.
+	Config map[string]string `yaml:"config"`
+	// StartTimeoutSeconds bounds starting the process and its initialize call.
+	StartTimeoutSeconds int `yaml:"startTimeoutSeconds"`
+	// StopTimeoutSeconds is how long a plugin has to exit once its stdin is
+	// closed before it is killed.
+	StopTimeoutSeconds int `yaml:"stopTimeoutSeconds"`
+	// A crashed plugin is restarted after a delay doubling from
+	// RestartBackoffMillis up to MaxRestartBackoffMillis; the delay resets
+	// once a process stayed up that long.
+	RestartBackoffMillis    int `yaml:"restartBackoffMillis"`
+	MaxRestartBackoffMillis int `yaml:"maxRestartBackoffMillis"`
+	// MaxHealthFailures is how many health checks in a row a plugin may leave
+	// unanswered before it is considered hung and restarted; 0 never
+	// restarts a live process.
+	MaxHealthFailures int `yaml:"maxHealthFailures"`
+	// DeclineCodes maps the plugin's decline codes, case-insensitively, to
+	// normalized reasons for replies without a reason.
+	DeclineCodes map[string]constants.ReasonCode `yaml:"declineCodes"`
+}
+
+// DefaultSettings are the settings a gateway with an empty settings block gets.
+func DefaultSettings() Settings {
+	return Settings{
+		StartTimeoutSeconds:     10,
+		StopTimeoutSeconds:      5,
+		RestartBackoffMillis:    500,
+		MaxRestartBackoffMillis: 30000,
+		MaxHealthFailures:       3,
+	}
+}
+
+// Validate checks the settings after they were decoded over DefaultSettings.
+func (s Settings) Validate() error {
+	if s.Command == "" {
+		return fmt.Errorf("command is required")
+	}
+	if s.StartTimeoutSeconds <= 0 || s.StopTimeoutSeconds <= 0 {
+		return fmt.Errorf("startTimeoutSeconds and stopTimeoutSeconds must be positive")
+	}
+	if s.RestartBackoffMillis <= 0 || s.MaxRestartBackoffMillis < s.RestartBackoffMillis {
+		return fmt.Errorf("restartBackoffMillis must be positive and at most maxRestartBackoffMillis")
+	}
+	if s.MaxHealthFailures < 0 {
+		return fmt.Errorf("maxHealthFailures must not be negative")
+	}
+	for key := range s.Env {
+		if key == "" || key == "PATH" || strings.Contains(key, "=") {
+			return fmt.Errorf("env key %q is not allowed", key)
+		}
+	}
+	for code, reason := range s.DeclineCodes {
+		if !gateway.KnownReason(reason) {
+			return fmt.Errorf("declineCodes.%s: unknown reason %q", code, reason)
+		}
+	}
+	return nil
+}
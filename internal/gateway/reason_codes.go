@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/constants"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"strings"
+)
+
+// gatewayAReasonCodes maps GatewayA JSON "code" values to normalized reasons.
+var gatewayAReasonCodes = map[string]constants.ReasonCode{
+	"INSUFFICIENT_FUNDS": constants.ReasonInsufficientFunds,
+	"NSF":                constants.ReasonInsufficientFunds,
+	"INVALID_ACCOUNT":    constants.ReasonInvalidAccount,
+	"ACCOUNT_CLOSED":     constants.ReasonInvalidAccount,
+	"INVALID_AMOUNT":     constants.ReasonInvalidAmount,
+	"DO_NOT_HONOR":       constants.ReasonDoNotHonor,
+	"LIMIT_EXCEEDED":     constants.ReasonLimitExceeded,
+	"FRAUD_SUSPECTED":    constants.ReasonSuspectedFraud,
+	"DUPLICATE":          constants.ReasonDuplicate,
+}
+
+// gatewayBReasonCodes maps GatewayB SOAP result and fault codes to normalized reasons.
+var gatewayBReasonCodes = map[string]constants.ReasonCode{
+	"B051": constants.ReasonInsufficientFunds,
+	"B014": constants.ReasonInvalidAccount,
+	"B013": constants.ReasonInvalidAmount,
+	"B005": constants.ReasonDoNotHonor,
+	"B061": constants.ReasonLimitExceeded,
+	"B059": constants.ReasonSuspectedFraud,
+	"B094": constants.ReasonDuplicate,
+	"B091": constants.ReasonGatewayUnavailable,
+}
+
+// knownReasons lists every normalized reason an adapter may report.
+var knownReasons = map[constants.ReasonCode]bool{
+	constants.ReasonInsufficientFunds:  true,
+	constants.ReasonInvalidAccount:     true,
+	constants.ReasonInvalidAmount:      true,
+	constants.ReasonDoNotHonor:         true,
+	constants.ReasonLimitExceeded:      true,
+	constants.ReasonSuspectedFraud:     true,
+	constants.ReasonDuplicate:          true,
+	constants.ReasonDeclined:           true,
+	constants.ReasonGatewayUnavailable: true,
+	constants.ReasonTimeout:            true,
+	constants.ReasonGatewayError:       true,
+}
+
+// KnownReason reports whether reason is one of the normalized reason codes,
+// for adapters whose code mappings come from configuration.
+func KnownReason(reason constants.ReasonCode) bool {
+	return knownReasons[reason]
+}
+
+// reasonForCode looks a gateway code up in table. Declines with a missing or
+// unmapped code are reported as the generic ReasonDeclined.
+func reasonForCode(table map[string]constants.ReasonCode, code string) constants.ReasonCode {
+	if reason, ok := table[strings.ToUpper(strings.TrimSpace(code))]; ok {
+		return reason
+	}
+	return constants.ReasonDeclined
+}
+
+// ReasonForError classifies an error returned by a PaymentGateway. Errors
+// that know their reason, such as SOAP faults, report it themselves.
+func ReasonForError(err error) constants.ReasonCode {
+	var coded interface{ ReasonCode() constants.ReasonCode }
+	if errors.As(err, &coded) {
+		return coded.ReasonCode()
+	}
+	switch {
+	case errors.Is(err, apperrors.ErrGatewayTimeout), errors.Is(err, context.DeadlineExceeded):
+		return constants.ReasonTimeout
+	case errors.Is(err, apperrors.ErrGatewayNotAvailable), errors.Is(err, apperrors.ErrNoGatewayAvailable):
+		return constants.ReasonGatewayUnavailable
+	default:
+		return constants.ReasonGatewayError
+	}
+}
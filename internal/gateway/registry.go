@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/config"
+	apperrors "Payment-Gateway/pkg/error"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Factory builds a gateway adapter from the gateway's configuration; key is
+// the gateway's key under gateways in config.yaml.
+type Factory func(key string, cfg config.GatewayConfig) (PaymentGateway, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes an adapter available under name, which gateways select with
+// their type setting. Adapters register from an init function in their own
+// package; registering a name twice panics.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("gateway: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("gateway: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// Adapters returns the names of the registered adapters, sorted.
+func Adapters() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the gateway configured under key with the adapter named by its
+// type, or by key itself when it has none.
+func New(key string, cfg config.GatewayConfig) (PaymentGateway, error) {
+	adapter := cfg.Type
+	if adapter == "" {
+		adapter = key
+	}
+	registryMu.RLock()
+	factory, ok := registry[adapter]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("gateway %s: %w: no adapter named %q (registered: %s)",
+			key, apperrors.ErrUnsupportedGateway, adapter, strings.Join(Adapters(), ", "))
+	}
+	gw, err := factory(key, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gateway %s: %w", key, err)
+	}
+	return gw, nil
+}
+
+// Validator is implemented by adapter settings that check their own values.
+type Validator interface {
+	Validate() error
+}
+
+// DecodeSettings decodes the gateway's settings block into v, rejecting keys
+// v does not declare, and validates the result when v is a Validator.
+func DecodeSettings(cfg config.GatewayConfig, v any) error {
+	if !cfg.Settings.IsZero() {
+		// Re-encode so the strict decoder can report unknown keys
+		raw, err := yaml.Marshal(&cfg.Settings)
+		if err != nil {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+	}
+	if validator, ok := v.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+	}
+	return nil
+}
+
+// WithSettings adapts a constructor taking typed settings S to a Factory. The
+// gateway's settings block is decoded into a zero S, or into the value
+// returned by defaults when it is not nil, and validated before build runs.
+func WithSettings[S any](defaults func() S, build func(key string, cfg config.GatewayConfig, settings S) (PaymentGateway, error)) Factory {
+	return func(key string, cfg config.GatewayConfig) (PaymentGateway, error) {
+		var settings S
+		if defaults != nil {
+			settings = defaults()
+		}
+		if err := DecodeSettings(cfg, &settings); err != nil {
+			return nil, err
+		}
+		return build(key, cfg, settings)
+	}
+}
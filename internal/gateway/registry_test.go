@@ -0,0 +1,116 @@
+package gateway_test
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/gatewaytest"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type registryTestGateway struct {
+	settings registryTestSettings
+}
+
+func (g *registryTestGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
+
+func (g *registryTestGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
+
+type registryTestSettings struct {
+	Merchant string `yaml:"merchant"`
+	Retries  int    `yaml:"retries"`
+}
+
+func (s registryTestSettings) Validate() error {
+	if s.Merchant == "" {
+		return errors.New("merchant is required")
+	}
+	return nil
+}
+
+func init() {
+	gateway.Register("registryTest", gateway.WithSettings(
+		func() registryTestSettings { return registryTestSettings{Retries: 3} },
+		func(key string, cfg config.GatewayConfig, settings registryTestSettings) (gateway.PaymentGateway, error) {
+			return &registryTestGateway{settings: settings}, nil
+		},
+	))
+}
+
+func TestNew_DecodesTypedSettings(t *testing.T) {
+	gw, err := gateway.New("acquirer", gatewaytest.Config(t, "registryTest", "", "merchant: m-1"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	got := gw.(*registryTestGateway).settings
+	if got.Merchant != "m-1" || got.Retries != 3 {
+		t.Errorf("expected decoded settings over defaults, got %+v", got)
+	}
+}
+
+func TestNew_RejectsInvalidSettings(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":    "merchant: m-1\nretry: 5",
+		"wrong type":     "merchant: m-1\nretries: many",
+		"fails Validate": "retries: 1",
+		"missing block":  "",
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := gateway.New("acquirer", gatewaytest.Config(t, "registryTest", "", settings))
+			if err == nil || !strings.Contains(err.Error(), "gateway acquirer: invalid settings") {
+				t.Errorf("expected a settings error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNew_AdapterDefaultsToKey(t *testing.T) {
+	if _, err := gateway.New("registryTest", gatewaytest.Config(t, "", "", "merchant: m-1")); err != nil {
+		t.Errorf("expected the key to name the adapter, got %v", err)
+	}
+}
+
+func TestNew_UnknownAdapter(t *testing.T) {
+	_, err := gateway.New("acquirer", gatewaytest.Config(t, "nope", "", ""))
+	if !errors.Is(err, apperrors.ErrUnsupportedGateway) {
+		t.Fatalf("expected ErrUnsupportedGateway, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "gatewayA") {
+		t.Errorf("expected the registered adapters to be listed, got %v", err)
+	}
+}
+
+func TestNew_BuiltinAdapters(t *testing.T) {
+	cfg := config.GatewayConfig{URL: "http://localhost/mock", Name: "GatewayA"}
+	if gw, err := gateway.New("gatewayA", cfg); err != nil || gateway.NameOf(gw) != "GatewayA" {
+		t.Errorf("expected GatewayA, got %v, %v", gw, err)
+	}
+	cfg.Type = "gatewayB"
+	if _, err := gateway.New("backup", cfg); err != nil {
+		t.Errorf("expected gatewayB adapter under another key, got %v", err)
+	}
+	if _, err := gateway.New("gatewayA", config.GatewayConfig{}); err == nil {
+		t.Error("expected a missing url to be rejected")
+	}
+	withSettings := gatewaytest.Config(t, "gatewayA", cfg.URL, "soapVersion: \"1.2\"")
+	if _, err := gateway.New("gatewayA", withSettings); err == nil || !strings.Contains(err.Error(), "invalid settings") {
+		t.Errorf("expected gatewayA to reject settings it does not declare, got %v", err)
+	}
+}
+
+func TestRegister_DuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a duplicate registration to panic")
+		}
+	}()
+	gateway.Register("gatewayA", func(key string, cfg config.GatewayConfig) (gateway.PaymentGateway, error) { return nil, nil })
+}
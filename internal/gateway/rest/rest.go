@@ -0,0 +1,263 @@
+// Package rest is a gateway adapter for JSON-over-HTTP providers whose
+// endpoints, request bodies and response fields are described in
+// configuration rather than code. Gateways use it with type: rest.
+package rest
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/resilience"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"go.uber.org/zap"
+)
+
+func init() {
+	gateway.Register("rest", gateway.WithSettings(DefaultSettings, New))
+}
+
+// Gateway sends payments to a provider described by Settings.
+type Gateway struct {
+	name       string
+	URL        string
+	Client     *http.Client
+	Resilience *resilience.Executor
+
+	settings   Settings
+	header     http.Header
+	deposit    *template.Template
+	withdrawal *template.Template
+	statuses   map[string]gateway.Outcome
+	codes      map[string]constants.ReasonCode
+}
+
+// templateData is what body templates render.
+type templateData struct {
+	TransactionID string
+	Account       string
+	Amount        float64
+	Currency      string
+	MerchantID    string
+	Metadata      map[string]string
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"minor": func(amount float64, decimals int) int64 {
+		return int64(math.Round(amount * math.Pow10(decimals)))
+	},
+}
+
+// New builds the gateway configured under key from validated settings.
+func New(key string, cfg config.GatewayConfig, settings Settings) (gateway.PaymentGateway, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("url is required")
+	}
+	g := &Gateway{
+		name:       cfg.Name,
+		URL:        strings.TrimSuffix(cfg.URL, "/"),
+		Client:     &http.Client{},
+		Resilience: resilience.NewExecutor(cfg.Name, cfg.Resilience),
+		settings:   settings,
+		header:     http.Header{},
+		statuses:   make(map[string]gateway.Outcome, len(settings.Statuses)),
+		codes:      make(map[string]constants.ReasonCode, len(settings.DeclineCodes)),
+	}
+	var err error
+	if g.deposit, err = template.New("deposit").Funcs(templateFuncs).Parse(settings.Deposit.Body); err != nil {
+		return nil, fmt.Errorf("invalid settings: deposit.body: %w", err)
+	}
+	if g.withdrawal, err = template.New("withdrawal").Funcs(templateFuncs).Parse(settings.Withdrawal.Body); err != nil {
+		return nil, fmt.Errorf("invalid settings: withdrawal.body: %w", err)
+	}
+	g.header.Set("Content-Type", "application/json")
+	g.header.Set("Accept", "application/json")
+	for name, value := range settings.Headers {
+		g.header.Set(name, os.ExpandEnv(value))
+	}
+	for status, outcome := range settings.Statuses {
+		g.statuses[strings.ToLower(status)] = outcome
+	}
+	for code, reason := range settings.DeclineCodes {
+		g.codes[strings.ToUpper(code)] = reason
+	}
+	return g, nil
+}
+
+// ProcessDeposit renders the deposit template and sends it to the provider.
+func (g *Gateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "deposit", g.settings.Deposit, g.deposit, req)
+}
+
+// ProcessWithdrawal renders the withdrawal template and sends it to the provider.
+func (g *Gateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return g.send(ctx, "withdrawal", g.settings.Withdrawal, g.withdrawal, req)
+}
+
+// Name returns the gateway's configured name.
+func (g *Gateway) Name() string {
+	return g.name
+}
+
+// Available reports whether the gateway's circuit breaker lets calls through.
+func (g *Gateway) Available() bool {
+	return g.Resilience.Available()
+}
+
+// send renders the operation's body, calls its endpoint and converts the
+// reply into a PaymentResult.
+func (g *Gateway) send(ctx context.Context, operation string, endpoint Endpoint, body *template.Template, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	url := g.URL + endpoint.Path
+	log := logger.GetLogger().With(
+		zap.String("func", "rest.Gateway.send"),
+		zap.String("gateway", g.name),
+		zap.String("operation", operation),
+		zap.String("transaction_id", req.TransactionID),
+		zap.String("url", url),
+	)
+
+	var payload bytes.Buffer
+	err := body.Execute(&payload, templateData{
+		TransactionID: req.TransactionID,
+		Account:       req.Account,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		MerchantID:    req.MerchantID,
+		Metadata:      req.Metadata,
+	})
+	if err == nil && !json.Valid(payload.Bytes()) {
+		err = errors.New("rendered body is not valid JSON")
+	}
+	if err != nil {
+		log.Error("Failed to render request body", zap.Error(err))
+		return nil, gateway.NotProcessed(fmt.Errorf("%w: %s body: %w", apperrors.ErrProcessingFailed, operation, err))
+	}
+
+	header := g.header.Clone()
+	if g.settings.IdempotencyHeader != "" {
+		header.Set(g.settings.IdempotencyHeader, req.TransactionID)
+	}
+
+	log.Info("Sending request to gateway")
+	resp, err := gateway.SendHTTP(ctx, g.Client, g.Resilience, gateway.HTTPRequest{
+		Method: endpoint.Method,
+		URL:    url,
+		Header: header,
+		Body:   payload.Bytes(),
+	})
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Error("Gateway request timeout", zap.Error(err))
+			return nil, apperrors.WithMessage(apperrors.ErrGatewayTimeout, g.name+" timeout")
+		}
+		log.Error("Gateway request error", zap.Error(err))
+		if errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, err)
+	}
+
+	outcome, fixed := g.settings.HTTPStatuses[resp.StatusCode]
+	if !fixed && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		log.Error("Gateway request failed", zap.Int("status_code", resp.StatusCode))
+		err := apperrors.WithMessage(apperrors.ErrProcessingFailed, g.name+" failure")
+		if resp.Refused {
+			return nil, gateway.NotProcessed(err)
+		}
+		return nil, err
+	}
+	var reply any
+	if err := json.Unmarshal(resp.Body, &reply); err != nil && !fixed {
+		log.Error("Failed to decode gateway response", zap.Error(err))
+		return nil, fmt.Errorf("%w: %w", apperrors.ErrProcessingFailed, err)
+	}
+	fields := g.settings.Response
+	status, _ := lookup(reply, fields.Status)
+	if !fixed {
+		var ok bool
+		if outcome, ok = g.outcome(status); !ok {
+			log.Error("Unrecognized gateway status", zap.String("gateway_status", status))
+			return nil, apperrors.WithMessage(apperrors.ErrProcessingFailed, fmt.Sprintf("%s returned unknown status %q", g.name, status))
+		}
+	}
+	result := &gateway.PaymentResult{
+		Outcome:     outcome,
+		StatusCode:  resp.StatusCode,
+		RawResponse: resp.Body,
+	}
+	result.GatewayRef, _ = lookup(reply, fields.Reference)
+	result.GatewayCode, _ = lookup(reply, fields.Code)
+	result.Message, _ = lookup(reply, fields.Message)
+	if outcome == gateway.OutcomeDeclined {
+		result.Reason = constants.ReasonDeclined
+		if reason, ok := g.codes[strings.ToUpper(strings.TrimSpace(result.GatewayCode))]; ok {
+			result.Reason = reason
+		}
+	}
+	log.Info("Gateway request completed",
+		zap.Int("status_code", resp.StatusCode),
+		zap.String("gateway_status", status),
+		zap.String("gateway_code", result.GatewayCode),
+		zap.String("gateway_ref", result.GatewayRef),
+	)
+	return result, nil
+}
+
+// outcome maps a provider status through Statuses, or the common status
+// words when none are configured.
+func (g *Gateway) outcome(status string) (gateway.Outcome, bool) {
+	if len(g.statuses) == 0 {
+		return gateway.OutcomeFromStatus(status)
+	}
+	outcome, ok := g.statuses[strings.ToLower(strings.TrimSpace(status))]
+	return outcome, ok
+}
+
+// lookup follows a dotted path through a decoded JSON document and returns
+// the scalar found there as text.
+func lookup(doc any, path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	v := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", false
+			}
+			v = node[i]
+		default:
+			return "", false
+		}
+	}
+	switch value := v.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	default:
+		return "", false
+	}
+}
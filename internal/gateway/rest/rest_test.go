@@ -0,0 +1,223 @@
+package rest
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/gateway/gatewaytest"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testPayment's account has a quote, which the rendered bodies must escape.
+var testPayment = gatewaytest.PaymentRequest(`acc"1`, 12.34)
+
+func TestGateway_DefaultsSpeakGatewayA(t *testing.T) {
+	var body map[string]any
+	var path, key string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, key = r.URL.Path, r.Header.Get(gateway.IdempotencyKeyHeader)
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"status":"failed","code":"nsf","message":"no funds","reference":"ref-1"}`))
+	}))
+	defer ts.Close()
+
+	gw := gatewaytest.New(t, "gatewayRest", gatewaytest.Config(t, "rest", ts.URL, ""))
+	result, err := gw.ProcessWithdrawal(context.Background(), testPayment)
+	if err != nil {
+		t.Fatalf("ProcessWithdrawal: %v", err)
+	}
+	if path != "/withdrawal" || key != testPayment.TransactionID {
+		t.Errorf("expected POST /withdrawal with the idempotency key, got %s %q", path, key)
+	}
+	if body["account"] != `acc"1` || body["amount"] != 12.34 || body["currency"] != "EUR" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if result.Outcome != gateway.OutcomeDeclined || result.GatewayRef != "ref-1" || result.Message != "no funds" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// Unmapped decline codes are generic declines
+	if result.Reason != constants.ReasonDeclined {
+		t.Errorf("expected reason declined, got %s", result.Reason)
+	}
+}
+
+func TestGateway_DefaultBodyAmounts(t *testing.T) {
+	var amount json.RawMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Amount json.RawMessage `json:"amount"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		amount = body.Amount
+		w.Write([]byte(`{"status":"success","reference":"ref-1"}`))
+	}))
+	defer ts.Close()
+
+	gw := gatewaytest.New(t, "gatewayRest", gatewaytest.Config(t, "rest", ts.URL, ""))
+	for _, v := range []float64{1000000, 999999.99, 12.34, 0.000001} {
+		if _, err := gw.ProcessDeposit(context.Background(), gatewaytest.PaymentRequest("acc-1", v)); err != nil {
+			t.Fatalf("%v: ProcessDeposit: %v", v, err)
+		}
+		// GatewayA's requests are encoded with encoding/json
+		if want, _ := json.Marshal(v); string(amount) != string(want) {
+			t.Errorf("amount %v sent as %s, want %s", v, amount, want)
+		}
+	}
+}
+
+const providerSettings = `
+headers:
+  X-Api-Key: "${REST_TEST_API_KEY}"
+idempotencyHeader: X-Request-Id
+deposit:
+  method: PUT
+  path: /v2/payins
+  body: '{"payment": {"ref": {{json .TransactionID}}, "minor": {{minor .Amount 2}}, "payer": {{json .Account}}}}'
+response:
+  status: data.items.0.state
+  reference: data.id
+  code: error.code
+  message: error.detail
+statuses:
+  COMPLETED: approved
+  REJECTED: declined
+  IN_PROGRESS: pending
+httpStatuses:
+  402: declined
+declineCodes:
+  NSF: insufficient_funds
+`
+
+func TestGateway_ConfiguredProvider(t *testing.T) {
+	t.Setenv("REST_TEST_API_KEY", "k-1")
+	tests := []struct {
+		name        string
+		status      int
+		reply       string
+		wantOutcome gateway.Outcome
+		wantReason  constants.ReasonCode
+		wantRef     string
+	}{
+		{"approved", http.StatusOK, `{"data":{"id":"r-1","items":[{"state":"completed"}]}}`, gateway.OutcomeApproved, "", "r-1"},
+		{"pending", http.StatusAccepted, `{"data":{"id":"r-2","items":[{"state":"IN_PROGRESS"}]}}`, gateway.OutcomePending, "", "r-2"},
+		{"declined by http status", http.StatusPaymentRequired, `{"data":{"id":"r-3"},"error":{"code":"NSF","detail":"no funds"}}`, gateway.OutcomeDeclined, constants.ReasonInsufficientFunds, "r-3"},
+		{"declined without body", http.StatusPaymentRequired, `not json`, gateway.OutcomeDeclined, constants.ReasonDeclined, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				b, _ := io.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.reply))
+			}))
+			defer ts.Close()
+
+			gw := gatewaytest.New(t, "gatewayRest", gatewaytest.Config(t, "rest", ts.URL, providerSettings))
+			result, err := gw.ProcessDeposit(context.Background(), testPayment)
+			if err != nil {
+				t.Fatalf("ProcessDeposit: %v", err)
+			}
+			if got.Method != http.MethodPut || got.URL.Path != "/v2/payins" {
+				t.Errorf("expected PUT /v2/payins, got %s %s", got.Method, got.URL.Path)
+			}
+			if got.Header.Get("X-Api-Key") != "k-1" || got.Header.Get("X-Request-Id") != testPayment.TransactionID {
+				t.Errorf("unexpected headers %v", got.Header)
+			}
+			if want := `{"payment": {"ref": "tx-acc\"1", "minor": 1234, "payer": "acc\"1"}}`; body != want {
+				t.Errorf("body %s, want %s", body, want)
+			}
+			if result.Outcome != tt.wantOutcome || result.Reason != tt.wantReason || result.GatewayRef != tt.wantRef {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+}
+
+func TestGateway_FailedAnswers(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		reply        string
+		wantFailover bool
+	}{
+		{"server error", http.StatusInternalServerError, `{}`, false},
+		{"refused", http.StatusServiceUnavailable, `{}`, true},
+		{"unmapped status", http.StatusOK, `{"data":{"items":[{"state":"ON_HOLD"}]}}`, false},
+		{"malformed body", http.StatusOK, `<html>`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.reply))
+			}))
+			defer ts.Close()
+
+			gw := gatewaytest.New(t, "gatewayRest", gatewaytest.Config(t, "rest", ts.URL, providerSettings))
+			_, err := gw.ProcessDeposit(context.Background(), testPayment)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if gateway.SafeToFailover(err) != tt.wantFailover {
+				t.Errorf("SafeToFailover = %v, want %v (%v)", !tt.wantFailover, tt.wantFailover, err)
+			}
+		})
+	}
+}
+
+func TestGateway_InvalidRenderedBodyIsNotSent(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	defer ts.Close()
+
+	gw := gatewaytest.New(t, "gatewayRest", gatewaytest.Config(t, "rest", ts.URL, `deposit: {body: '{"account": {{.Account}}}'}`))
+	_, err := gw.ProcessDeposit(context.Background(), testPayment)
+	if !gateway.SafeToFailover(err) || calls != 0 {
+		t.Errorf("expected an unsent, not processed failure, got %v after %d calls", err, calls)
+	}
+}
+
+func TestNew_InvalidSettings(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":        `timeout: 3`,
+		"method":             `deposit: {method: GET}`,
+		"path":               `withdrawal: {path: payouts}`,
+		"template":           `deposit: {body: '{{.Amount'}`,
+		"status outcome":     `statuses: {DONE: settled}`,
+		"http status":        `httpStatuses: {42: declined}`,
+		"decline reason":     `declineCodes: {NSF: broke}`,
+		"empty status field": `response: {status: ""}`,
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := gateway.New("gatewayRest", gatewaytest.Config(t, "rest", "http://localhost", settings)); err == nil || !strings.Contains(err.Error(), "invalid settings") {
+				t.Errorf("expected an invalid settings error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var doc any
+	json.Unmarshal([]byte(`{"a":{"b":[{"c":"x"},{"n":12.5,"ok":true}]}}`), &doc)
+	tests := map[string]string{"a.b.0.c": "x", "a.b.1.n": "12.5", "a.b.1.ok": "true"}
+	for path, want := range tests {
+		if got, ok := lookup(doc, path); !ok || got != want {
+			t.Errorf("lookup(%s) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+	for _, path := range []string{"", "a.b", "a.b.2.c", "a.x.c", "a.b.z"} {
+		if _, ok := lookup(doc, path); ok {
+			t.Errorf("lookup(%s) should not find a scalar", path)
+		}
+	}
+}
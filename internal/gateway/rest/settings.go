@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Settings describe a provider's JSON API under a gateway's settings block.
+// Every key is optional; the defaults match GatewayA's API.
+type Settings struct {
+	// Headers are sent with every call; ${VAR} is replaced from the
+	// environment so credentials stay out of config.yaml.
+	Headers map[string]string `yaml:"headers"`
+	// IdempotencyHeader carries the transaction ID, stable across retries.
+	IdempotencyHeader string         `yaml:"idempotencyHeader"`
+	Deposit           Endpoint       `yaml:"deposit"`
+	Withdrawal        Endpoint       `yaml:"withdrawal"`
+	Response          ResponseFields `yaml:"response"`
+	// Statuses maps the provider's status values, case-insensitively, to
+	// approved, declined or pending. When empty the common status words
+	// GatewayA uses are recognized.
+	Statuses map[string]gateway.Outcome `yaml:"statuses"`
+	// HTTPStatuses gives the outcome of answers with these HTTP statuses
+	// regardless of the body, for providers that decline with e.g. 402.
+	HTTPStatuses map[int]gateway.Outcome `yaml:"httpStatuses"`
+	// DeclineCodes maps the provider's decline codes, case-insensitively, to
+	// normalized reasons; unmapped codes are reported as declined.
+	DeclineCodes map[string]constants.ReasonCode `yaml:"declineCodes"`
+}
+
+// Endpoint is one operation's HTTP call. Path is appended to the gateway's
+// url. Body is a text/template rendering the JSON request from the payment:
+// .TransactionID, .Account, .Amount, .Currency, .MerchantID and .Metadata,
+// with the functions json (a JSON-encoded value) and minor (an amount in
+// minor units given the currency's decimals, e.g. {{minor .Amount 2}}).
+// Decimal amounts go through json, as in {{json .Amount}}: {{.Amount}}
+// prints 1000000 as 1e+06.
+type Endpoint struct {
+	Method string `yaml:"method"`
+	Path   string `yaml:"path"`
+	Body   string `yaml:"body"`
+}
+
+// ResponseFields locate values in the provider's JSON answer by dotted path;
+// numeric segments index arrays, as in "data.items.0.state".
+type ResponseFields struct {
+	Status    string `yaml:"status"`
+	Reference string `yaml:"reference"`
+	Code      string `yaml:"code"`
+	Message   string `yaml:"message"`
+}
+
+const defaultBody = `{"transaction_id": {{json .TransactionID}}, "account": {{json .Account}}, "amount": {{json .Amount}}, "currency": {{json .Currency}}}`
+
+// DefaultSettings are the settings a gateway with an empty settings block gets.
+func DefaultSettings() Settings {
+	return Settings{
+		IdempotencyHeader: gateway.IdempotencyKeyHeader,
+		Deposit:           Endpoint{Method: http.MethodPost, Path: "/deposit", Body: defaultBody},
+		Withdrawal:        Endpoint{Method: http.MethodPost, Path: "/withdrawal", Body: defaultBody},
+		Response: ResponseFields{
+			Status:    "status",
+			Reference: "reference",
+			Code:      "code",
+			Message:   "message",
+		},
+	}
+}
+
+// Validate checks the settings after they were decoded over DefaultSettings.
+func (s Settings) Validate() error {
+	for name, e := range map[string]Endpoint{"deposit": s.Deposit, "withdrawal": s.Withdrawal} {
+		switch e.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			return fmt.Errorf("%s.method %q must be POST, PUT or PATCH", name, e.Method)
+		}
+		if !strings.HasPrefix(e.Path, "/") {
+			return fmt.Errorf("%s.path %q must start with /", name, e.Path)
+		}
+		if strings.TrimSpace(e.Body) == "" {
+			return fmt.Errorf("%s.body is required", name)
+		}
+	}
+	if s.Response.Status == "" {
+		return fmt.Errorf("response.status is required")
+	}
+	for status, outcome := range s.Statuses {
+		if !validOutcome(outcome) {
+			return fmt.Errorf("statuses.%s: unknown outcome %q", status, outcome)
+		}
+	}
+	for code, outcome := range s.HTTPStatuses {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("httpStatuses: %d is not an HTTP status", code)
+		}
+		if !validOutcome(outcome) {
+			return fmt.Errorf("httpStatuses.%d: unknown outcome %q", code, outcome)
+		}
+	}
+	for code, reason := range s.DeclineCodes {
+		if !gateway.KnownReason(reason) {
+			return fmt.Errorf("declineCodes.%s: unknown reason %q", code, reason)
+		}
+	}
+	return nil
+}
+
+func validOutcome(o gateway.Outcome) bool {
+	return o == gateway.OutcomeApproved || o == gateway.OutcomeDeclined || o == gateway.OutcomePending
+}
@@ -0,0 +1,198 @@
+package gateway
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	apperrors "Payment-Gateway/pkg/error"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SOAP versions a SOAP adapter can speak.
+const (
+	SOAP11 = "1.1"
+	SOAP12 = "1.2"
+)
+
+// WS-Security password types.
+const (
+	PasswordText   = "text"
+	PasswordDigest = "digest"
+)
+
+// WSSecuritySettings add a WS-Security UsernameToken header to every call
+// when Username is set.
+type WSSecuritySettings struct {
+	Username string `yaml:"username"`
+	// Password may reference the environment as ${VAR}.
+	Password string `yaml:"password"`
+	// PasswordType is text (default) or digest, which sends
+	// Base64(SHA-1(nonce + created + password)) instead of the password.
+	PasswordType string `yaml:"passwordType"`
+}
+
+func (s WSSecuritySettings) validate() error {
+	switch s.PasswordType {
+	case "", PasswordText, PasswordDigest:
+	default:
+		return fmt.Errorf("security.passwordType %q must be %s or %s", s.PasswordType, PasswordText, PasswordDigest)
+	}
+	if s.Username == "" && s.Password != "" {
+		return fmt.Errorf("security.username is required with a password")
+	}
+	return nil
+}
+
+// soapNamespace returns the envelope namespace of a SOAP version.
+func soapNamespace(version string) string {
+	if version == SOAP12 {
+		return dtos.SOAP12Namespace
+	}
+	return dtos.SOAP11Namespace
+}
+
+// soapHeader returns the HTTP headers of a SOAP call: SOAP 1.1 names the
+// action in a SOAPAction header, SOAP 1.2 in the Content-Type's action
+// parameter.
+func soapHeader(version, action string) http.Header {
+	header := http.Header{}
+	if version == SOAP12 {
+		header.Set("Content-Type", fmt.Sprintf(`application/soap+xml; charset=utf-8; action="%s"`, action))
+		return header
+	}
+	header.Set("Content-Type", "text/xml; charset=utf-8")
+	header.Set("SOAPAction", `"`+action+`"`)
+	return header
+}
+
+// usernameToken builds a fresh WS-Security header; digests use a new nonce
+// and creation time for every message so they cannot be replayed.
+func usernameToken(s WSSecuritySettings, now time.Time) (*dtos.SOAPHeader, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	created := now.UTC().Format(time.RFC3339)
+	token := &dtos.WSSUsernameToken{
+		Username: s.Username,
+		Password: dtos.WSSPassword{Type: dtos.WSSPasswordText, Value: s.Password},
+		Nonce:    &dtos.WSSNonce{EncodingType: dtos.WSSBase64EncodingURI, Value: base64.StdEncoding.EncodeToString(nonce)},
+		Created:  created,
+	}
+	if s.PasswordType == PasswordDigest {
+		token.Password = dtos.WSSPassword{Type: dtos.WSSPasswordDigest, Value: PasswordDigestValue(nonce, created, s.Password)}
+	}
+	return &dtos.SOAPHeader{Security: &dtos.WSSecurity{MustUnderstand: "1", UsernameToken: token}}, nil
+}
+
+// PasswordDigestValue is the UsernameToken digest of password for a nonce and
+// creation time.
+func PasswordDigestValue(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// SOAPFaultError is a SOAP Fault returned by a gateway, normalized across
+// SOAP versions. Code is the fault code without its namespace prefix: Client,
+// Server, VersionMismatch or MustUnderstand in SOAP 1.1 and Sender, Receiver,
+// VersionMismatch, MustUnderstand or DataEncodingUnknown in SOAP 1.2.
+type SOAPFaultError struct {
+	Code    string
+	Subcode string
+	Reason  string
+	// DetailCode is the gateway's own result code from the fault detail.
+	DetailCode string
+	StatusCode int
+	reason     constants.ReasonCode
+}
+
+func (e *SOAPFaultError) Error() string {
+	msg := fmt.Sprintf("SOAP fault %s", e.Code)
+	if e.Subcode != "" {
+		msg += "/" + e.Subcode
+	}
+	if e.DetailCode != "" {
+		msg += " (" + e.DetailCode + ")"
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Unwrap reports faults as failed processing.
+func (e *SOAPFaultError) Unwrap() error { return apperrors.ErrProcessingFailed }
+
+// ReasonCode is the normalized reason for the fault's detail code.
+func (e *SOAPFaultError) ReasonCode() constants.ReasonCode { return e.reason }
+
+// SenderFault reports whether the gateway blamed the message itself, so the
+// payment was rejected before any processing.
+func (e *SOAPFaultError) SenderFault() bool {
+	switch e.Code {
+	case "Client", "Sender", "VersionMismatch", "MustUnderstand", "DataEncodingUnknown":
+		return true
+	}
+	return false
+}
+
+// Declined reports whether the fault's detail code maps to a decline reason,
+// so the fault is the gateway refusing the payment rather than the message.
+func (e *SOAPFaultError) Declined() bool {
+	return e.reason != constants.ReasonGatewayError && e.reason != constants.ReasonGatewayUnavailable
+}
+
+// parseSOAPFault returns the fault carried by body, if it is a SOAP envelope
+// with a Fault, mapping its detail code through reasons.
+func parseSOAPFault(body []byte, statusCode int, reasons map[string]constants.ReasonCode) (*SOAPFaultError, bool) {
+	var envelope dtos.SOAPEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Body.Fault == nil {
+		return nil, false
+	}
+	f := envelope.Body.Fault
+	fault := &SOAPFaultError{StatusCode: statusCode, Code: localName(f.FaultCode), Reason: strings.TrimSpace(f.FaultString)}
+	detail := f.FaultDetail
+	if f.Code != nil {
+		fault.Code = localName(f.Code.Value)
+		if f.Code.Subcode != nil {
+			fault.Subcode = localName(f.Code.Subcode.Value)
+		}
+		if f.Reason != nil && len(f.Reason.Text) > 0 {
+			fault.Reason = strings.TrimSpace(f.Reason.Text[0].Value)
+		}
+		detail = f.Detail
+	}
+	fault.reason = constants.ReasonGatewayError
+	if detail != nil && detail.PaymentFault != nil {
+		fault.DetailCode = strings.TrimSpace(detail.PaymentFault.Code)
+		if reason, ok := reasons[strings.ToUpper(fault.DetailCode)]; ok {
+			fault.reason = reason
+		}
+	}
+	return fault, true
+}
+
+// isSOAPFaultReply reports whether a retryable status carries a SOAP Fault,
+// which is a final answer rather than a transient failure.
+func isSOAPFaultReply(reply *HTTPReply) bool {
+	_, ok := parseSOAPFault(reply.Body, reply.StatusCode, nil)
+	return ok
+}
+
+// localName strips the namespace prefix from a qualified name such as soap:Server.
+func localName(qname string) string {
+	qname = strings.TrimSpace(qname)
+	if i := strings.LastIndex(qname, ":"); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
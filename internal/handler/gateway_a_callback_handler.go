@@ -5,6 +5,7 @@ import (
 	"Payment-Gateway/internal/dtos"
 	"Payment-Gateway/internal/middleware"
 	"Payment-Gateway/internal/service"
+	errors "Payment-Gateway/pkg/error"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -32,7 +33,7 @@ func (h *GatewayACallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	var req dtos.HandleCallbackRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Warn("Invalid GatewayA callback JSON", zap.Error(err))
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		middleware.WriteError(w, r, errors.ErrCallbackInvalid)
 		return
 	}
 
@@ -46,7 +47,7 @@ func (h *GatewayACallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 
 	if err := req.Validate(); err != nil {
 		log.Warn("Validation failed for GatewayA callback", zap.Error(err))
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+		middleware.WriteError(w, r, err)
 		return
 	}
 
@@ -61,7 +62,7 @@ func (h *GatewayACallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	resp, err := h.Service.HandleCallback(req)
 	if err != nil {
 		log.Error("GatewayA callback processing failed", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		middleware.WriteError(w, r, err)
 		return
 	}
 
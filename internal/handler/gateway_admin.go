@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/internal/middleware"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/service"
+	errors "Payment-Gateway/pkg/error"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+const maxStateChangeBodyBytes = 4 << 10
+
+type GatewayAdminHandler struct {
+	Service service.GatewayAdmin
+}
+
+func NewGatewayAdminHandler(service service.GatewayAdmin) GatewayAdminHandler {
+	return GatewayAdminHandler{
+		Service: service,
+	}
+}
+
+// List reports every gateway's state, in-flight calls and maintenance windows.
+func (h *GatewayAdminHandler) List(w http.ResponseWriter, r *http.Request) {
+	statuses := h.Service.Statuses()
+	if statuses == nil {
+		statuses = []models.GatewayStatus{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dtos.GatewayStatusListResponse{Gateways: statuses})
+}
+
+func (h *GatewayAdminHandler) Get(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["gateway"]
+	status, err := h.Service.Status(name)
+	if err != nil {
+		middleware.LoggerFromContext(r.Context()).Warn("Failed to get gateway status",
+			zap.String("func", "GatewayAdminHandler.Get"), zap.String("gateway", name), zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	writeGatewayStatus(w, http.StatusOK, status)
+}
+
+// Drain stops new transactions to the gateway and disables it once its calls
+// in flight have finished. Drain and Disable answer 202 while calls are still
+// in flight and 200 once none are.
+func (h *GatewayAdminHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "GatewayAdminHandler.Drain", h.Service.Drain)
+}
+
+func (h *GatewayAdminHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "GatewayAdminHandler.Disable", h.Service.Disable)
+}
+
+func (h *GatewayAdminHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.changeState(w, r, "GatewayAdminHandler.Enable", func(name, _ string) (models.GatewayStatus, error) {
+		return h.Service.Enable(name)
+	})
+}
+
+func (h *GatewayAdminHandler) changeState(w http.ResponseWriter, r *http.Request, funcName string, change func(name, reason string) (models.GatewayStatus, error)) {
+	name := mux.Vars(r)["gateway"]
+	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", funcName), zap.String("gateway", name))
+
+	var req dtos.GatewayStateChangeRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxStateChangeBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && err != io.EOF {
+		log.Warn("Invalid gateway state change request", zap.Error(err))
+		middleware.WriteError(w, r, errors.WithDetails(errors.ErrInvalidRequest,
+			errors.ErrorDetail{Field: "body", Reason: `must be empty or a JSON object with an optional "reason"`}))
+		return
+	}
+
+	status, err := change(name, req.Reason)
+	if err != nil {
+		log.Warn("Gateway state change failed", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	log.Info("Gateway state changed", zap.String("state", string(status.State)), zap.Int("in_flight", status.InFlight))
+	code := http.StatusOK
+	if status.InFlight > 0 && status.State != constants.GatewayEnabled {
+		code = http.StatusAccepted
+	}
+	writeGatewayStatus(w, code, status)
+}
+
+func writeGatewayStatus(w http.ResponseWriter, code int, status models.GatewayStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
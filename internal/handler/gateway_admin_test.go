@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestGatewayAdminHandler_Drain(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		status     models.GatewayStatus
+		err        error
+		wantReason string
+		wantCode   int
+	}{
+		{"still in flight", `{"reason":"provider upgrade"}`, models.GatewayStatus{State: constants.GatewayDraining, InFlight: 2}, nil, "provider upgrade", http.StatusAccepted},
+		{"drained", "", models.GatewayStatus{State: constants.GatewayDisabled}, nil, "", http.StatusOK},
+		{"unknown gateway", "", models.GatewayStatus{}, errors.ErrGatewayNotFound, "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSvc := mocks.NewMockGatewayAdmin(ctrl)
+			mockSvc.EXPECT().Drain("gatewayA", tt.wantReason).Return(tt.status, tt.err)
+
+			handler := NewGatewayAdminHandler(mockSvc)
+			req := httptest.NewRequest("POST", "/admin/gateways/gatewayA/drain", strings.NewReader(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"gateway": "gatewayA"})
+			w := httptest.NewRecorder()
+
+			handler.Drain(w, req)
+			if w.Code != tt.wantCode {
+				t.Errorf("expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGatewayAdminHandler_Disable_InvalidBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := NewGatewayAdminHandler(mocks.NewMockGatewayAdmin(ctrl))
+	req := httptest.NewRequest("POST", "/admin/gateways/gatewayA/disable", strings.NewReader(`{"why":"x"}`))
+	req = mux.SetURLVars(req, map[string]string{"gateway": "gatewayA"})
+	w := httptest.NewRecorder()
+
+	handler.Disable(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
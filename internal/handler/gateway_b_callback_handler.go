@@ -5,6 +5,7 @@ import (
 	"Payment-Gateway/internal/dtos"
 	"Payment-Gateway/internal/middleware"
 	"Payment-Gateway/internal/service"
+	errors "Payment-Gateway/pkg/error"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -33,13 +34,13 @@ func (h *GatewayBCallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		log.Warn("Failed to read GatewayB callback body", zap.Error(err))
-		http.Error(w, "invalid XML", http.StatusBadRequest)
+		middleware.WriteXMLError(w, r, errors.ErrCallbackInvalid)
 		return
 	}
 	var req dtos.HandleCallbackRequest
 	if err := xml.Unmarshal(body, &req); err != nil {
 		log.Warn("Invalid GatewayB callback XML", zap.Error(err))
-		http.Error(w, "invalid XML", http.StatusBadRequest)
+		middleware.WriteXMLError(w, r, errors.ErrCallbackInvalid)
 		return
 	}
 
@@ -54,7 +55,7 @@ func (h *GatewayBCallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 
 	if err := req.Validate(); err != nil {
 		log.Warn("Validation failed for GatewayB callback", zap.Error(err))
-		http.Error(w, "Validation failed", http.StatusBadRequest)
+		middleware.WriteXMLError(w, r, err)
 		return
 	}
 
@@ -68,7 +69,7 @@ func (h *GatewayBCallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 	resp, err := h.Service.HandleCallback(req)
 	if err != nil {
 		log.Error("GatewayB callback processing failed", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		middleware.WriteXMLError(w, r, err)
 		return
 	}
 
@@ -4,4 +4,7 @@ type Handlers struct {
 	TransactionHandler TransactionHandler
 	GatewayACallback   GatewayACallbackHandler
 	GatewayBCallback   GatewayBCallbackHandler
+	WebhookAdmin       WebhookAdminHandler
+	RoutingAdmin       RoutingAdminHandler
+	GatewayAdmin       GatewayAdminHandler
 }
@@ -1,6 +1,7 @@
 package mockgateway
 
 import (
+	"Payment-Gateway/internal/dtos"
 	"encoding/json"
 	"net/http"
 )
@@ -15,19 +16,33 @@ func GatewayAMockHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func GatewayAMockDepositHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	resp := map[string]interface{}{
-		"status":  "success",
-		"message": "Mock Gateway A processed the deposit successfully",
-	}
-	json.NewEncoder(w).Encode(resp)
+	gatewayAMockRespond(w, r, "deposit")
 }
 
 func GatewayAMockWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	resp := map[string]interface{}{
-		"status":  "success",
-		"message": "Mock Gateway A processed the withdrawal successfully",
+	gatewayAMockRespond(w, r, "withdrawal")
+}
+
+// gatewayAMockRespond answers with the JSON body GatewayA sends for the
+// scenario selected by the request's account.
+func gatewayAMockRespond(w http.ResponseWriter, r *http.Request, operation string) {
+	var req dtos.GatewayADepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
 	}
+
+	resp := dtos.GatewayAResponse{Reference: referenceFor("A", operation, r)}
+	switch scenarioFor(req.Account) {
+	case scenarioInsufficientFunds:
+		resp.Status, resp.Code, resp.Message = "failed", "INSUFFICIENT_FUNDS", "Mock Gateway A declined the "+operation+": insufficient funds"
+	case scenarioDoNotHonor:
+		resp.Status, resp.Code, resp.Message = "failed", "DO_NOT_HONOR", "Mock Gateway A declined the "+operation
+	case scenarioPending:
+		resp.Status, resp.Message = "pending", "Mock Gateway A is processing the "+operation
+	default:
+		resp.Status, resp.Message = "success", "Mock Gateway A processed the "+operation+" successfully"
+	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
@@ -1,59 +1,150 @@
 package mockgateway
 
 import (
+	"Payment-Gateway/internal/dtos"
 	"encoding/xml"
+	"mime"
 	"net/http"
 )
 
-type SOAPEnvelope struct {
-	XMLName xml.Name `xml:"Envelope"`
-	Body    SOAPBody `xml:"Body"`
+// SOAP 1.1 fault codes; SOAP 1.2 faults use Sender and Receiver for Client and Server.
+const (
+	faultClient          = "Client"
+	faultServer          = "Server"
+	faultVersionMismatch = "VersionMismatch"
+)
+
+// gatewayBActions are the SOAP actions mock gateway B accepts per operation.
+var gatewayBActions = map[string]string{
+	"deposit":    dtos.GatewayBNamespace + "/Deposit",
+	"withdrawal": dtos.GatewayBNamespace + "/Withdrawal",
 }
 
-type SOAPBody struct {
-	Response SOAPResponse `xml:"Response"`
+func GatewayBMockHandler(w http.ResponseWriter, r *http.Request) {
+	writeSOAP(w, dtos.SOAP11Namespace, http.StatusOK, dtos.SOAPBody{
+		Response: &dtos.SOAPResponse{
+			Status:  "success",
+			Message: "Mock Gateway B processed the request successfully",
+		},
+	})
 }
 
-type SOAPResponse struct {
-	Status  string `xml:"Status"`
-	Message string `xml:"Message"`
+func GatewayBMockDepositHandler(w http.ResponseWriter, r *http.Request) {
+	gatewayBMockRespond(w, r, "deposit")
 }
 
-func GatewayBMockHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/xml")
-	resp := SOAPEnvelope{
-		Body: SOAPBody{
-			Response: SOAPResponse{
-				Status:  "success",
-				Message: "Mock Gateway B processed the request successfully",
-			},
-		},
+func GatewayBMockWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	gatewayBMockRespond(w, r, "withdrawal")
+}
+
+// gatewayBMockRespond answers like a SOAP endpoint in the SOAP version of the
+// request: envelopes in another namespace get a VersionMismatch fault, a
+// missing or wrong SOAP action or an incomplete WS-Security UsernameToken a
+// Client (1.1) or Sender (1.2) fault, and otherwise the scenario selected by
+// the request's account is answered.
+func gatewayBMockRespond(w http.ResponseWriter, r *http.Request, operation string) {
+	var req dtos.SOAPEnvelope
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSOAPFault(w, dtos.SOAP11Namespace, faultClient, "invalid SOAP envelope", "")
+		return
+	}
+	ns := req.XMLName.Space
+	if ns != dtos.SOAP11Namespace && ns != dtos.SOAP12Namespace {
+		writeSOAPFault(w, dtos.SOAP11Namespace, faultVersionMismatch, "unsupported envelope namespace "+ns, "")
+		return
+	}
+	if soapAction(r, ns) != gatewayBActions[operation] {
+		writeSOAPFault(w, ns, faultClient, "unknown SOAP action", "")
+		return
+	}
+	if req.Header != nil && req.Header.Security != nil {
+		token := req.Header.Security.UsernameToken
+		if token == nil || token.Username == "" || token.Password.Value == "" ||
+			(token.Password.Type == dtos.WSSPasswordDigest && (token.Nonce == nil || token.Created == "")) {
+			writeSOAPFault(w, ns, faultClient, "invalid WS-Security UsernameToken", "")
+			return
+		}
 	}
-	xml.NewEncoder(w).Encode(resp)
+	var account string
+	switch {
+	case req.Body.DepositRequest != nil:
+		account = req.Body.DepositRequest.Account
+	case req.Body.WithdrawalRequest != nil:
+		account = req.Body.WithdrawalRequest.Account
+	default:
+		writeSOAPFault(w, ns, faultClient, "missing "+operation+" request", "")
+		return
+	}
+
+	resp := dtos.SOAPResponse{Reference: referenceFor("B", operation, r)}
+	switch scenarioFor(account) {
+	case scenarioInsufficientFunds:
+		resp.Status, resp.Code, resp.Message = "declined", "B051", "Mock Gateway B declined the "+operation+": insufficient funds"
+	case scenarioDoNotHonor:
+		resp.Status, resp.Code, resp.Message = "declined", "B005", "Mock Gateway B declined the "+operation
+	case scenarioPending:
+		resp.Status, resp.Message = "pending", "Mock Gateway B is processing the "+operation
+	case scenarioFault:
+		writeSOAPFault(w, ns, faultServer, "Mock Gateway B is temporarily unavailable", "B091")
+		return
+	default:
+		resp.Status, resp.Message = "success", "Mock Gateway B processed the "+operation+" successfully"
+	}
+	writeSOAP(w, ns, http.StatusOK, dtos.SOAPBody{Response: &resp})
 }
 
-func GatewayBMockDepositHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/xml")
-	resp := SOAPEnvelope{
-		Body: SOAPBody{
-			Response: SOAPResponse{
-				Status:  "success",
-				Message: "Mock Gateway B processed the deposit successfully",
-			},
-		},
+// soapAction reads the action from the SOAPAction header (SOAP 1.1) or the
+// Content-Type action parameter (SOAP 1.2).
+func soapAction(r *http.Request, ns string) string {
+	if ns == dtos.SOAP11Namespace {
+		action := r.Header.Get("SOAPAction")
+		if len(action) >= 2 && action[0] == '"' && action[len(action)-1] == '"' {
+			action = action[1 : len(action)-1]
+		}
+		return action
+	}
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
 	}
-	xml.NewEncoder(w).Encode(resp)
+	return params["action"]
 }
 
-func GatewayBMockWithdrawalHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/xml")
-	resp := SOAPEnvelope{
-		Body: SOAPBody{
-			Response: SOAPResponse{
-				Status:  "success",
-				Message: "Mock Gateway B processed the withdrawal successfully",
-			},
-		},
+// writeSOAPFault answers with a fault in the format of the SOAP version,
+// carrying code as GatewayB's PaymentFault detail when set.
+func writeSOAPFault(w http.ResponseWriter, ns, fault, reason, code string) {
+	var detail *dtos.SOAPFaultDetail
+	if code != "" {
+		detail = &dtos.SOAPFaultDetail{PaymentFault: &dtos.GatewayBPaymentFault{Code: code, Message: reason}}
+	}
+	if ns == dtos.SOAP12Namespace {
+		status := http.StatusInternalServerError
+		switch fault {
+		case faultClient:
+			fault, status = "Sender", http.StatusBadRequest
+		case faultServer:
+			fault = "Receiver"
+		}
+		writeSOAP(w, ns, status, dtos.SOAPBody{Fault: &dtos.SOAPFault{
+			Code:   &dtos.SOAPFaultCode{Value: "soap:" + fault},
+			Reason: &dtos.SOAPFaultReason{Text: []dtos.SOAPFaultText{{Lang: "en", Value: reason}}},
+			Detail: detail,
+		}})
+		return
+	}
+	writeSOAP(w, dtos.SOAP11Namespace, http.StatusInternalServerError, dtos.SOAPBody{Fault: &dtos.SOAPFault{
+		FaultCode:   "soap:" + fault,
+		FaultString: reason,
+		FaultDetail: detail,
+	}})
+}
+
+func writeSOAP(w http.ResponseWriter, ns string, status int, body dtos.SOAPBody) {
+	if ns == dtos.SOAP12Namespace {
+		w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
 	}
-	xml.NewEncoder(w).Encode(resp)
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(dtos.SOAPEnvelope{XMLName: xml.Name{Space: ns}, Body: body})
 }
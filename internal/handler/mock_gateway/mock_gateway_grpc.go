@@ -0,0 +1,90 @@
+package mockgateway
+
+import (
+	"Payment-Gateway/internal/gateway/grpcgw/paymentpb"
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCMockServer is the mock gRPC provider's PaymentService. It answers for
+// the scenario selected by the account, except that "fault..." accounts get
+// UNAVAILABLE, and repeats its first answer for a known transaction ID.
+type GRPCMockServer struct {
+	paymentpb.UnimplementedPaymentServiceServer
+	payments sync.Map // transaction ID -> *paymentpb.PaymentResponse
+}
+
+// NewGRPCServer returns a gRPC server with the mock PaymentService and the
+// standard health service reporting it as serving.
+func NewGRPCServer() *grpc.Server {
+	srv := grpc.NewServer()
+	paymentpb.RegisterPaymentServiceServer(srv, &GRPCMockServer{})
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(paymentpb.PaymentService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+	return srv
+}
+
+func (s *GRPCMockServer) Deposit(ctx context.Context, req *paymentpb.PaymentRequest) (*paymentpb.PaymentResponse, error) {
+	return s.process(ctx, req, "deposit")
+}
+
+func (s *GRPCMockServer) Withdrawal(ctx context.Context, req *paymentpb.PaymentRequest) (*paymentpb.PaymentResponse, error) {
+	return s.process(ctx, req, "withdrawal")
+}
+
+func (s *GRPCMockServer) GetPaymentStatus(ctx context.Context, req *paymentpb.PaymentStatusRequest) (*paymentpb.PaymentResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	resp, ok := s.payments.Load(req.GetTransactionId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown transaction %q", req.GetTransactionId())
+	}
+	return proto.Clone(resp.(*paymentpb.PaymentResponse)).(*paymentpb.PaymentResponse), nil
+}
+
+func (s *GRPCMockServer) process(ctx context.Context, req *paymentpb.PaymentRequest, operation string) (*paymentpb.PaymentResponse, error) {
+	if err := authorize(ctx); err != nil {
+		return nil, err
+	}
+	if req.GetTransactionId() == "" || req.GetAccount() == "" || req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "transaction_id, account and a positive amount are required")
+	}
+
+	resp := &paymentpb.PaymentResponse{TransactionId: req.GetTransactionId(), Reference: newReference("G")}
+	switch scenarioFor(req.GetAccount()) {
+	case scenarioInsufficientFunds:
+		resp.Status, resp.Code = paymentpb.PaymentStatus_PAYMENT_STATUS_DECLINED, "INSUFFICIENT_FUNDS"
+		resp.Message = "Mock gRPC provider declined the " + operation + ": insufficient funds"
+	case scenarioDoNotHonor:
+		resp.Status, resp.Code = paymentpb.PaymentStatus_PAYMENT_STATUS_DECLINED, "DO_NOT_HONOR"
+		resp.Message = "Mock gRPC provider declined the " + operation
+	case scenarioPending:
+		resp.Status, resp.Message = paymentpb.PaymentStatus_PAYMENT_STATUS_PENDING, "Mock gRPC provider is processing the "+operation
+	case scenarioFault:
+		return nil, status.Error(codes.Unavailable, "mock gRPC provider is unavailable")
+	default:
+		resp.Status, resp.Message = paymentpb.PaymentStatus_PAYMENT_STATUS_APPROVED, "Mock gRPC provider approved the "+operation
+	}
+	first, _ := s.payments.LoadOrStore(req.GetTransactionId(), resp)
+	return proto.Clone(first.(*paymentpb.PaymentResponse)).(*paymentpb.PaymentResponse), nil
+}
+
+// authorize requires the authorization metadata the grpc adapter is
+// configured to send.
+func authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) == 0 || values[0] == "" {
+		return status.Error(codes.Unauthenticated, "missing authorization")
+	}
+	return nil
+}
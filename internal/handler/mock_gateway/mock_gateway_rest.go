@@ -0,0 +1,74 @@
+package mockgateway
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// restMockRequest is the payout/payin body of the mock REST provider, a JSON
+// API shaped unlike GatewayA's to exercise the configurable rest adapter.
+type restMockRequest struct {
+	Payment struct {
+		Reference   string `json:"reference"`
+		AmountMinor int64  `json:"amount_minor"`
+		Currency    string `json:"currency"`
+		Payer       struct {
+			Account string `json:"account"`
+		} `json:"payer"`
+	} `json:"payment"`
+}
+
+type restMockResponse struct {
+	Data  restMockData   `json:"data"`
+	Error *restMockError `json:"error,omitempty"`
+}
+
+type restMockData struct {
+	ID    string `json:"id"`
+	State string `json:"state"`
+}
+
+type restMockError struct {
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+func RESTMockPayinHandler(w http.ResponseWriter, r *http.Request) {
+	restMockRespond(w, r, "payin")
+}
+
+func RESTMockPayoutHandler(w http.ResponseWriter, r *http.Request) {
+	restMockRespond(w, r, "payout")
+}
+
+// restMockRespond answers like the mock REST provider for the scenario
+// selected by the payer account. Declines are answered with HTTP 402.
+func restMockRespond(w http.ResponseWriter, r *http.Request, operation string) {
+	if r.Header.Get("X-Api-Key") == "" {
+		http.Error(w, "missing api key", http.StatusUnauthorized)
+		return
+	}
+	var req restMockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	resp := restMockResponse{Data: restMockData{ID: referenceFor("R", operation, r)}}
+	switch scenarioFor(req.Payment.Payer.Account) {
+	case scenarioInsufficientFunds:
+		status, resp.Data.State = http.StatusPaymentRequired, "REJECTED"
+		resp.Error = &restMockError{Code: "NSF", Detail: "Mock REST provider declined the " + operation + ": insufficient funds"}
+	case scenarioDoNotHonor:
+		status, resp.Data.State = http.StatusPaymentRequired, "REJECTED"
+		resp.Error = &restMockError{Code: "REFUSED", Detail: "Mock REST provider declined the " + operation}
+	case scenarioPending:
+		resp.Data.State = "IN_PROGRESS"
+	default:
+		resp.Data.State = "COMPLETED"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
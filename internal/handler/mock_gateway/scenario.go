@@ -0,0 +1,66 @@
+package mockgateway
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// scenario is the outcome a mock gateway simulates for a request. It is
+// chosen from the account prefix so declines and pending payments can be
+// exercised by hand: "nsf..." and "decline..." are declined, "pending..." stays
+// pending, "fault..." gets a SOAP Fault from mock gateway B and UNAVAILABLE
+// from the mock gRPC provider (the other mocks approve it) and every other
+// account is approved.
+type scenario int
+
+const (
+	scenarioApproved scenario = iota
+	scenarioInsufficientFunds
+	scenarioDoNotHonor
+	scenarioPending
+	scenarioFault
+)
+
+func scenarioFor(account string) scenario {
+	account = strings.ToLower(account)
+	switch {
+	case strings.HasPrefix(account, "nsf"):
+		return scenarioInsufficientFunds
+	case strings.HasPrefix(account, "decline"):
+		return scenarioDoNotHonor
+	case strings.HasPrefix(account, "pending"):
+		return scenarioPending
+	case strings.HasPrefix(account, "fault"):
+		return scenarioFault
+	default:
+		return scenarioApproved
+	}
+}
+
+// references remembers the reference handed out per idempotency key so a
+// replayed request gets the same answer, as a real gateway would.
+var references sync.Map
+
+// referenceFor returns the reference for r, reusing the one already issued
+// when the request carries a known idempotency key.
+func referenceFor(prefix, operation string, r *http.Request) string {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return newReference(prefix)
+	}
+	ref, _ := references.LoadOrStore(prefix+"/"+operation+"/"+key, newReference(prefix))
+	return ref.(string)
+}
+
+func newReference(prefix string) string {
+	return prefix + "-" + uuid.NewString()
+}
+
+// HealthHandler answers the health probes of the mock gateways.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status":"ok"}`))
+}
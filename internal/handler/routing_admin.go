@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/internal/middleware"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/routing"
+	"Payment-Gateway/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type RoutingAdminHandler struct {
+	Pool      service.GatewayPool
+	Shadow    service.ShadowReporter // Optional; nil when no gateway is shadowed
+	validator *TransactionValidator
+}
+
+func NewRoutingAdminHandler(pool service.GatewayPool, shadow *service.ShadowTraffic, validator *TransactionValidator) RoutingAdminHandler {
+	h := RoutingAdminHandler{
+		Pool:      pool,
+		validator: validator,
+	}
+	// Keep the interface nil rather than holding a nil *ShadowTraffic
+	if shadow != nil {
+		h.Shadow = shadow
+	}
+	return h
+}
+
+// DryRun explains how a sample transaction would be routed: which rules were
+// evaluated, which one matched and the state of each candidate gateway. No
+// transaction is created and no strategy state changes.
+func (h *RoutingAdminHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", "RoutingAdminHandler.DryRun"))
+
+	req, err := h.validator.DecodeDryRun(w, r)
+	if err != nil {
+		log.Warn("Invalid routing dry-run request", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	// Apply the defaults the transaction service applies to real transactions
+	if req.MerchantID == "" {
+		req.MerchantID = constants.DefaultMerchantID
+	}
+	if req.Currency == "" {
+		req.Currency = constants.DefaultCurrency
+	}
+
+	exp := h.Pool.Explain(routing.Request{
+		Type:       req.Type,
+		MerchantID: req.MerchantID,
+		Account:    req.AccountID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Country:    req.Country,
+	})
+	resp := dtos.RoutingDryRunResponse{
+		Request:     req,
+		MatchedRule: exp.Rule,
+		Strategy:    exp.Strategy,
+		Explanation: "no rule matched; every gateway is a candidate for the merchant's strategy",
+		Rules:       make([]dtos.RoutingRuleResult, 0, len(exp.Rules)),
+		Candidates:  make([]dtos.RoutingCandidate, 0, len(exp.Candidates)),
+	}
+	if exp.Rule != "" {
+		resp.Explanation = fmt.Sprintf("rule %s matched; only its gateways are candidates", exp.Rule)
+	}
+	for _, rr := range exp.Rules {
+		resp.Rules = append(resp.Rules, dtos.RoutingRuleResult{Rule: rr.Rule, Matched: rr.Matched, Reason: rr.Reason})
+	}
+	for _, c := range exp.Candidates {
+		resp.Candidates = append(resp.Candidates, dtos.RoutingCandidate{Gateway: c.Gateway, Available: c.Available, Reason: c.Reason})
+	}
+	log.Info("Routing dry run", zap.String("matched_rule", exp.Rule), zap.Int("candidates", len(exp.Candidates)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ListCanaries reports each canary gateway's error rate and rollback state.
+func (h *RoutingAdminHandler) ListCanaries(w http.ResponseWriter, r *http.Request) {
+	statuses := h.Pool.Canaries()
+	resp := dtos.RoutingCanaryListResponse{Canaries: make([]dtos.RoutingCanary, 0, len(statuses))}
+	for _, s := range statuses {
+		c := dtos.RoutingCanary{
+			Gateway:      s.Gateway,
+			Percent:      s.Percent,
+			Samples:      s.Samples,
+			ErrorRate:    s.ErrorRate,
+			MaxErrorRate: s.MaxErrorRate,
+			RolledBack:   s.RolledBack,
+		}
+		if s.RolledBack {
+			at := s.RolledBackAt.UTC().Truncate(time.Second)
+			c.RolledBackAt = &at
+		}
+		resp.Canaries = append(resp.Canaries, c)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ResetCanary puts a rolled back canary gateway back into rotation.
+func (h *RoutingAdminHandler) ResetCanary(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["gateway"]
+	log := middleware.LoggerFromContext(r.Context()).With(
+		zap.String("func", "RoutingAdminHandler.ResetCanary"),
+		zap.String("gateway", name),
+	)
+	if err := h.Pool.ResetCanary(name); err != nil {
+		log.Warn("Canary reset failed", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	log.Info("Canary reset")
+	h.ListCanaries(w, r)
+}
+
+// ShadowReport compares the answers of shadow gateways with the real ones.
+func (h *RoutingAdminHandler) ShadowReport(w http.ResponseWriter, r *http.Request) {
+	resp := dtos.ShadowReportListResponse{Shadows: []models.ShadowReport{}}
+	if h.Shadow != nil {
+		resp.Shadows = h.Shadow.ShadowReports()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/internal/routing"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestRoutingAdminHandler_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPool := mocks.NewMockGatewayPool(ctrl)
+	mockPool.EXPECT().
+		Explain(routing.Request{
+			Type:       constants.TypeWithdrawal,
+			MerchantID: constants.DefaultMerchantID,
+			Account:    "acc1",
+			Amount:     250,
+			Currency:   "EUR",
+			Country:    "DE",
+		}).
+		Return(routing.Explanation{
+			Rules:      []routing.RuleResult{{Rule: "usd", Reason: "currency"}, {Rule: "eur", Matched: true}},
+			Rule:       "eur",
+			Candidates: []routing.Candidate{{Gateway: "GatewayB", Available: true}},
+		})
+
+	handler := NewRoutingAdminHandler(mockPool, nil, newTestValidator(t))
+	body := `{"type":"withdrawal","account_id":"acc1","amount":250,"currency":"eur","country":"de"}`
+	req := httptest.NewRequest("POST", "/admin/routing/dry-run", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.DryRun(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp dtos.RoutingDryRunResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.MatchedRule != "eur" || len(resp.Rules) != 2 || len(resp.Candidates) != 1 || resp.Explanation == "" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestRoutingAdminHandler_DryRun_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := NewRoutingAdminHandler(mocks.NewMockGatewayPool(ctrl), nil, newTestValidator(t))
+	req := httptest.NewRequest("POST", "/admin/routing/dry-run", strings.NewReader(`{"type":"refund","amount":1}`))
+	w := httptest.NewRecorder()
+
+	handler.DryRun(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"type"`) || !strings.Contains(w.Body.String(), `"account_id"`) {
+		t.Errorf("expected type and account_id details, got %s", w.Body.String())
+	}
+}
+
+func TestRoutingAdminHandler_ResetCanary_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockPool := mocks.NewMockGatewayPool(ctrl)
+	mockPool.EXPECT().ResetCanary("gatewayC").Return(errors.ErrGatewayNotFound)
+
+	handler := NewRoutingAdminHandler(mockPool, nil, newTestValidator(t))
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/admin/routing/canaries/gatewayC/reset", nil), map[string]string{"gateway": "gatewayC"})
+	w := httptest.NewRecorder()
+
+	handler.ResetCanary(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestRoutingAdminHandler_ShadowReport_NoShadowGateways(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := NewRoutingAdminHandler(mocks.NewMockGatewayPool(ctrl), nil, newTestValidator(t))
+	w := httptest.NewRecorder()
+
+	handler.ShadowReport(w, httptest.NewRequest("GET", "/admin/routing/shadow", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"shadows":[]`) {
+		t.Errorf("expected an empty list, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/dtos"
 	"Payment-Gateway/internal/middleware"
 	"Payment-Gateway/internal/models"
@@ -13,11 +14,13 @@ import (
 
 type TransactionHandler struct {
 	transactionService service.Transaction
+	validator          *TransactionValidator
 }
 
-func NewTransactionHandler(transactionService service.Transaction) TransactionHandler {
+func NewTransactionHandler(transactionService service.Transaction, validator *TransactionValidator) TransactionHandler {
 	return TransactionHandler{
 		transactionService: transactionService,
+		validator:          validator,
 	}
 }
 
@@ -25,60 +28,98 @@ func (h *TransactionHandler) Deposit(w http.ResponseWriter, r *http.Request) {
 	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", "TransactionHandler.Deposit"))
 	log.Info("Received deposit request")
 
-	var req dtos.TransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Warn("Invalid deposit request payload", zap.Error(err))
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	req, err := h.validator.Decode(w, r)
+	if err != nil {
+		log.Warn("Invalid deposit request", zap.Error(err))
+		writeTransactionError(w, r, err)
 		return
 	}
 
 	log = log.With(zap.String("account_id", req.AccountID), zap.Float64("amount", req.Amount))
 	depositReq := &models.DepositRequest{
-		Account: req.AccountID,
-		Amount:  req.Amount,
+		Account:    req.AccountID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Country:    req.Country,
+		MerchantID: req.MerchantID,
 	}
-	resp := dtos.TransactionResponse{}
-	_, err := h.transactionService.CreateAndProcessDeposit(depositReq)
+	tx, err := h.transactionService.CreateAndProcessDeposit(depositReq)
 	if err != nil {
-		resp.Success = false
-		resp.Message = err.Error()
 		log.Error("Deposit failed", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-	} else {
-		resp.Success = true
-		log.Info("Deposit successful")
+		writeTransactionFailure(w, r, tx, err)
+		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	log.Info("Deposit successful")
+	writeTransactionResult(w, tx)
 }
 
 func (h *TransactionHandler) Withdrawal(w http.ResponseWriter, r *http.Request) {
 	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", "TransactionHandler.Withdrawal"))
 	log.Info("Received withdrawal request")
 
-	var req dtos.TransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Warn("Invalid withdrawal request payload", zap.Error(err))
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	req, err := h.validator.Decode(w, r)
+	if err != nil {
+		log.Warn("Invalid withdrawal request", zap.Error(err))
+		writeTransactionError(w, r, err)
 		return
 	}
 
 	log = log.With(zap.String("account_id", req.AccountID), zap.Float64("amount", req.Amount))
 	withdrawalReq := &models.WithdrawalRequest{
-		Account: req.AccountID,
-		Amount:  req.Amount,
+		Account:    req.AccountID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Country:    req.Country,
+		MerchantID: req.MerchantID,
 	}
-	resp := dtos.TransactionResponse{}
-	_, err := h.transactionService.CreateAndProcessWithdrawal(withdrawalReq)
+	tx, err := h.transactionService.CreateAndProcessWithdrawal(withdrawalReq)
 	if err != nil {
-		resp.Success = false
-		resp.Message = err.Error()
 		log.Error("Withdrawal failed", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
-	} else {
-		resp.Success = true
-		log.Info("Withdrawal successful")
+		writeTransactionFailure(w, r, tx, err)
+		return
+	}
+	log.Info("Withdrawal successful")
+	writeTransactionResult(w, tx)
+}
+
+// writeTransactionResult answers 200 for completed transactions and 202 for
+// those still pending at the gateway.
+func writeTransactionResult(w http.ResponseWriter, tx *models.Transaction) {
+	resp := dtos.TransactionResponse{Success: true}
+	status := http.StatusOK
+	if tx != nil {
+		resp.TransactionID = tx.ID
+		resp.Status = string(tx.Status)
+		resp.Gateway = tx.GatewayResult
+		if tx.Status == constants.StatusPending {
+			status = http.StatusAccepted
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeTransactionError keeps the success/message fields alongside the structured error.
+func writeTransactionError(w http.ResponseWriter, r *http.Request, err error) {
+	writeTransactionFailure(w, r, nil, err)
+}
+
+// writeTransactionFailure reports err together with the transaction, when one
+// was created, so clients see its ID and the gateway's normalized reason.
+func writeTransactionFailure(w http.ResponseWriter, r *http.Request, tx *models.Transaction, err error) {
+	apiErr := middleware.APIErrorFor(r, err)
+	resp := dtos.TransactionResponse{
+		Success: false,
+		Message: apiErr.Message,
+		Error:   apiErr,
+	}
+	if tx != nil {
+		resp.TransactionID = tx.ID
+		resp.Status = string(tx.Status)
+		resp.Gateway = tx.GatewayResult
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
 	json.NewEncoder(w).Encode(resp)
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/models"
 	errors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/mocks"
@@ -24,7 +25,7 @@ func TestTransactionHandler_Deposit_Success(t *testing.T) {
 		CreateAndProcessDeposit(gomock.Any()).
 		Return(&models.Transaction{ID: "tx1"}, nil)
 
-	handler := NewTransactionHandler(mockTx)
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
 	reqBody := dtos.TransactionRequest{AccountID: "acc1", Amount: 100}
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/deposit", bytes.NewReader(body))
@@ -46,7 +47,7 @@ func TestTransactionHandler_Deposit_BadRequest(t *testing.T) {
 		CreateAndProcessDeposit(gomock.Any()).
 		Return(nil, errors.ErrInvalidRequest)
 
-	handler := NewTransactionHandler(mockTx)
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
 	reqBody := dtos.TransactionRequest{AccountID: "acc1", Amount: 100}
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/deposit", bytes.NewReader(body))
@@ -68,7 +69,7 @@ func TestTransactionHandler_Withdrawal_Success(t *testing.T) {
 		CreateAndProcessWithdrawal(gomock.Any()).
 		Return(&models.Transaction{ID: "tx2"}, nil)
 
-	handler := NewTransactionHandler(mockTx)
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
 	reqBody := dtos.TransactionRequest{AccountID: "acc2", Amount: 50}
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/withdrawal", bytes.NewReader(body))
@@ -90,7 +91,7 @@ func TestTransactionHandler_Withdrawal_BadRequest(t *testing.T) {
 		CreateAndProcessWithdrawal(gomock.Any()).
 		Return(nil, errors.ErrInvalidRequest)
 
-	handler := NewTransactionHandler(mockTx)
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
 	reqBody := dtos.TransactionRequest{AccountID: "acc2", Amount: 50}
 	body, _ := json.Marshal(reqBody)
 	req := httptest.NewRequest("POST", "/withdrawal", bytes.NewReader(body))
@@ -102,3 +103,97 @@ func TestTransactionHandler_Withdrawal_BadRequest(t *testing.T) {
 		t.Fatalf("expected 400, got %d", resp.StatusCode)
 	}
 }
+
+func TestTransactionHandler_Deposit_GatewayTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := mocks.NewMockTransaction(ctrl)
+	mockTx.EXPECT().
+		CreateAndProcessDeposit(gomock.Any()).
+		Return(nil, errors.WithMessage(errors.ErrGatewayTimeout, "gateway A timeout"))
+
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
+	reqBody := dtos.TransactionRequest{AccountID: "acc1", Amount: 100}
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/deposit", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Deposit(w, req)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	var resp dtos.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Success || resp.Error == nil || resp.Error.Code != "gateway_timeout" || !resp.Error.Retryable {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestTransactionHandler_Deposit_PendingAtGateway(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := mocks.NewMockTransaction(ctrl)
+	mockTx.EXPECT().
+		CreateAndProcessDeposit(gomock.Any()).
+		Return(&models.Transaction{ID: "tx1", Status: constants.StatusPending}, nil)
+
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
+	body, _ := json.Marshal(dtos.TransactionRequest{AccountID: "acc1", Amount: 100})
+	req := httptest.NewRequest("POST", "/deposit", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Deposit(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", w.Code)
+	}
+	var resp dtos.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Success || resp.TransactionID != "tx1" || resp.Status != string(constants.StatusPending) {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestTransactionHandler_Deposit_Declined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := mocks.NewMockTransaction(ctrl)
+	mockTx.EXPECT().
+		CreateAndProcessDeposit(gomock.Any()).
+		Return(&models.Transaction{
+			ID:     "tx1",
+			Status: constants.StatusFailed,
+			GatewayResult: &models.GatewayResult{
+				Reference:  "A-1",
+				ReasonCode: constants.ReasonInsufficientFunds,
+				Code:       "INSUFFICIENT_FUNDS",
+				Message:    "insufficient funds",
+			},
+		}, errors.WithMessage(errors.ErrPaymentDeclined, "insufficient funds"))
+
+	handler := NewTransactionHandler(mockTx, newTestValidator(t))
+	body, _ := json.Marshal(dtos.TransactionRequest{AccountID: "acc1", Amount: 100})
+	req := httptest.NewRequest("POST", "/deposit", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.Deposit(w, req)
+	if w.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected 402, got %d", w.Code)
+	}
+	var resp dtos.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Success || resp.TransactionID != "tx1" || resp.Error == nil || resp.Error.Code != "payment_declined" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Gateway == nil || resp.Gateway.ReasonCode != constants.ReasonInsufficientFunds || resp.Gateway.Code != "INSUFFICIENT_FUNDS" {
+		t.Errorf("unexpected gateway result: %+v", resp.Gateway)
+	}
+}
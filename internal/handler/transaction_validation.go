@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	errors "Payment-Gateway/pkg/error"
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultMaxBodyBytes   = 64 << 10
+	defaultAmountDecimals = 2
+	defaultAccountPattern = `^[A-Za-z0-9_-]{3,64}$`
+)
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`) // ISO 4217
+	countryPattern  = regexp.MustCompile(`^[A-Z]{2}$`) // ISO 3166-1 alpha-2
+)
+
+// TransactionValidator decodes and validates merchant deposit and withdrawal
+// requests before they reach the transaction service.
+type TransactionValidator struct {
+	maxBodyBytes   int64
+	minAmount      float64
+	maxAmount      float64
+	amountScale    *big.Rat
+	amountDecimals int
+	accountPattern *regexp.Regexp
+	merchants      map[string]struct{}
+}
+
+// transactionPayload mirrors dtos.TransactionRequest but keeps the raw amount
+// so that missing values and decimal precision can be checked exactly.
+type transactionPayload struct {
+	AccountID  *string         `json:"account_id"`
+	Amount     json.RawMessage `json:"amount"`
+	Currency   string          `json:"currency"`
+	Country    string          `json:"country"`
+	MerchantID string          `json:"merchant_id"`
+}
+
+// dryRunPayload is a transactionPayload that also names the transaction type.
+type dryRunPayload struct {
+	Type string `json:"type"`
+	transactionPayload
+}
+
+// NewTransactionValidator builds a validator from cfg. Zero values fall back to
+// defaults; a zero MaxAmount means no upper bound. When merchantIDs is empty any
+// merchant_id is accepted.
+func NewTransactionValidator(cfg config.ValidationConfig, merchantIDs []string) (*TransactionValidator, error) {
+	v := &TransactionValidator{
+		maxBodyBytes:   cfg.MaxBodyBytes,
+		minAmount:      cfg.MinAmount,
+		maxAmount:      cfg.MaxAmount,
+		amountDecimals: cfg.AmountDecimals,
+		merchants:      make(map[string]struct{}, len(merchantIDs)),
+	}
+	if v.maxBodyBytes <= 0 {
+		v.maxBodyBytes = defaultMaxBodyBytes
+	}
+	if v.amountDecimals <= 0 {
+		v.amountDecimals = defaultAmountDecimals
+	}
+	v.amountScale = new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(v.amountDecimals)), nil))
+	if v.minAmount <= 0 {
+		v.minAmount = math.Pow10(-v.amountDecimals)
+	}
+	if v.maxAmount > 0 && v.maxAmount < v.minAmount {
+		return nil, fmt.Errorf("validation: maxAmount %v is below minAmount %v", v.maxAmount, v.minAmount)
+	}
+
+	pattern := cfg.AccountPattern
+	if pattern == "" {
+		pattern = defaultAccountPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("validation: invalid accountPattern %q: %w", pattern, err)
+	}
+	v.accountPattern = re
+
+	for _, id := range merchantIDs {
+		v.merchants[id] = struct{}{}
+	}
+	return v, nil
+}
+
+// Decode reads a single JSON object from the request body, rejecting unknown
+// fields, trailing data and bodies larger than the configured limit, then
+// validates it. Returned errors are ready for writeTransactionError.
+func (v *TransactionValidator) Decode(w http.ResponseWriter, r *http.Request) (dtos.TransactionRequest, error) {
+	var payload transactionPayload
+	if err := v.decodeBody(w, r, &payload); err != nil {
+		return dtos.TransactionRequest{}, err
+	}
+	req, details := v.validate(payload)
+	if len(details) > 0 {
+		return dtos.TransactionRequest{}, errors.WithDetails(errors.ErrValidationFailed, details...)
+	}
+	return req, nil
+}
+
+// DecodeDryRun decodes a routing dry-run request: a transaction request with
+// its type, DEPOSIT or WITHDRAWAL in any case.
+func (v *TransactionValidator) DecodeDryRun(w http.ResponseWriter, r *http.Request) (dtos.RoutingDryRunRequest, error) {
+	var payload dryRunPayload
+	if err := v.decodeBody(w, r, &payload); err != nil {
+		return dtos.RoutingDryRunRequest{}, err
+	}
+	var details []errors.ErrorDetail
+	txType := constants.TransactionType(strings.ToUpper(payload.Type))
+	if txType != constants.TypeDeposit && txType != constants.TypeWithdrawal {
+		details = append(details, errors.ErrorDetail{Field: "type", Reason: "must be DEPOSIT or WITHDRAWAL"})
+	}
+	req, more := v.validate(payload.transactionPayload)
+	details = append(details, more...)
+	if len(details) > 0 {
+		return dtos.RoutingDryRunRequest{}, errors.WithDetails(errors.ErrValidationFailed, details...)
+	}
+	return dtos.RoutingDryRunRequest{Type: txType, TransactionRequest: req}, nil
+}
+
+// decodeBody reads a single JSON object into dst, rejecting unknown fields,
+// trailing data and bodies larger than the configured limit.
+func (v *TransactionValidator) decodeBody(w http.ResponseWriter, r *http.Request, dst any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			return errors.ErrRequestTooLarge
+		}
+		return errors.WithDetails(errors.ErrInvalidRequest,
+			errors.ErrorDetail{Field: "body", Reason: "must contain a single JSON object"})
+	}
+	return nil
+}
+
+// validate returns the request and the reason for every invalid field.
+// Currency and country are optional and normalized to upper case.
+func (v *TransactionValidator) validate(p transactionPayload) (dtos.TransactionRequest, []errors.ErrorDetail) {
+	var (
+		req     dtos.TransactionRequest
+		details []errors.ErrorDetail
+	)
+
+	switch {
+	case p.AccountID == nil || *p.AccountID == "":
+		details = append(details, errors.ErrorDetail{Field: "account_id", Reason: "is required"})
+	case !v.accountPattern.MatchString(*p.AccountID):
+		details = append(details, errors.ErrorDetail{Field: "account_id", Reason: "must match " + v.accountPattern.String()})
+	default:
+		req.AccountID = *p.AccountID
+	}
+
+	amount, reason := v.parseAmount(p.Amount)
+	if reason != "" {
+		details = append(details, errors.ErrorDetail{Field: "amount", Reason: reason})
+	}
+	req.Amount = amount
+
+	if req.Currency = strings.ToUpper(p.Currency); req.Currency != "" && !currencyPattern.MatchString(req.Currency) {
+		details = append(details, errors.ErrorDetail{Field: "currency", Reason: "must be an ISO 4217 currency code"})
+	}
+	if req.Country = strings.ToUpper(p.Country); req.Country != "" && !countryPattern.MatchString(req.Country) {
+		details = append(details, errors.ErrorDetail{Field: "country", Reason: "must be an ISO 3166-1 alpha-2 country code"})
+	}
+
+	if p.MerchantID != "" && len(v.merchants) > 0 {
+		if _, ok := v.merchants[p.MerchantID]; !ok {
+			details = append(details, errors.ErrorDetail{Field: "merchant_id", Reason: "is not a known merchant"})
+		}
+	}
+	req.MerchantID = p.MerchantID
+
+	if len(details) > 0 {
+		return dtos.TransactionRequest{}, details
+	}
+	return req, nil
+}
+
+// parseAmount returns the amount and, when it is invalid, the reason why.
+func (v *TransactionValidator) parseAmount(raw json.RawMessage) (float64, string) {
+	text := string(bytes.TrimSpace(raw))
+	if text == "" || text == "null" {
+		return 0, "is required"
+	}
+	if strings.HasPrefix(text, `"`) {
+		return 0, "must be a number"
+	}
+	rat, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return 0, "must be a number"
+	}
+	if !new(big.Rat).Mul(rat, v.amountScale).IsInt() {
+		return 0, fmt.Sprintf("must have at most %d decimal places", v.amountDecimals)
+	}
+	amount, _ := rat.Float64()
+	if math.IsInf(amount, 0) {
+		return 0, "is too large"
+	}
+	if amount < v.minAmount {
+		return 0, fmt.Sprintf("must be at least %v", v.minAmount)
+	}
+	if v.maxAmount > 0 && amount > v.maxAmount {
+		return 0, fmt.Sprintf("must be at most %v", v.maxAmount)
+	}
+	return amount, ""
+}
+
+// decodeError maps a json.Decoder error to an API error with field details.
+func decodeError(err error) error {
+	var (
+		maxBytesErr *http.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	switch {
+	case stderrors.As(err, &maxBytesErr):
+		return errors.ErrRequestTooLarge
+	case stderrors.As(err, &typeErr):
+		return errors.WithDetails(errors.ErrInvalidRequest,
+			errors.ErrorDetail{Field: typeErr.Field, Reason: "must be a " + typeErr.Type.String()})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return errors.WithDetails(errors.ErrInvalidRequest,
+			errors.ErrorDetail{Field: field, Reason: "is not allowed"})
+	default:
+		return errors.WithDetails(errors.ErrInvalidRequest,
+			errors.ErrorDetail{Field: "body", Reason: "must be a valid JSON object"})
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/pkg/mocks"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func newTestValidator(t *testing.T) *TransactionValidator {
+	t.Helper()
+	v, err := NewTransactionValidator(config.ValidationConfig{
+		MaxBodyBytes:   256,
+		MinAmount:      0.01,
+		MaxAmount:      10000,
+		AmountDecimals: 2,
+	}, []string{"default", "m1"})
+	if err != nil {
+		t.Fatalf("failed to build validator: %v", err)
+	}
+	return v
+}
+
+func TestTransactionValidator_Decode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{"valid", `{"account_id":"acc1","amount":100.25,"merchant_id":"m1"}`, http.StatusOK, "", nil},
+		{"exponent amount", `{"account_id":"acc1","amount":1.5e2}`, http.StatusOK, "", nil},
+		{"missing fields", `{}`, http.StatusUnprocessableEntity, "validation_failed", []string{"account_id", "amount"}},
+		{"null amount", `{"account_id":"acc1","amount":null}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"too many decimals", `{"account_id":"acc1","amount":10.001}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"below minimum", `{"account_id":"acc1","amount":0}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"above maximum", `{"account_id":"acc1","amount":10000.01}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"overflowing amount", `{"account_id":"acc1","amount":1e400}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"quoted amount", `{"account_id":"acc1","amount":"100"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"amount"}},
+		{"bad account", `{"account_id":"a b","amount":1}`, http.StatusUnprocessableEntity, "validation_failed", []string{"account_id"}},
+		{"unknown merchant", `{"account_id":"acc1","amount":1,"merchant_id":"nope"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"merchant_id"}},
+		{"currency and country", `{"account_id":"acc1","amount":1,"currency":"eur","country":"de"}`, http.StatusOK, "", nil},
+		{"bad currency", `{"account_id":"acc1","amount":1,"currency":"EURO"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"currency"}},
+		{"bad country", `{"account_id":"acc1","amount":1,"country":"DEU"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"country"}},
+		{"unknown field", `{"account_id":"acc1","amount":1,"note":"x"}`, http.StatusBadRequest, "invalid_request", []string{"note"}},
+		{"wrong type", `{"account_id":123,"amount":1}`, http.StatusBadRequest, "invalid_request", []string{"account_id"}},
+		{"malformed", `{"account_id":`, http.StatusBadRequest, "invalid_request", []string{"body"}},
+		{"trailing data", `{"account_id":"acc1","amount":1}{}`, http.StatusBadRequest, "invalid_request", []string{"body"}},
+		{"too large", `{"account_id":"` + strings.Repeat("a", 300) + `","amount":1}`, http.StatusRequestEntityTooLarge, "request_too_large", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockTx := mocks.NewMockTransaction(ctrl)
+			if tt.wantStatus == http.StatusOK {
+				mockTx.EXPECT().CreateAndProcessDeposit(gomock.Any()).Return(nil, nil)
+			}
+			handler := NewTransactionHandler(mockTx, newTestValidator(t))
+			req := httptest.NewRequest("POST", "/deposit", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.Deposit(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				return
+			}
+			var resp dtos.TransactionResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode {
+				t.Fatalf("expected code %q, got %+v", tt.wantCode, resp.Error)
+			}
+			if len(resp.Error.Details) != len(tt.wantFields) {
+				t.Fatalf("expected details for %v, got %+v", tt.wantFields, resp.Error.Details)
+			}
+			for i, field := range tt.wantFields {
+				if resp.Error.Details[i].Field != field {
+					t.Errorf("detail %d: expected field %q, got %q", i, field, resp.Error.Details[i].Field)
+				}
+			}
+		})
+	}
+}
+
+func TestTransactionValidator_ParseAmountWithoutMaximum(t *testing.T) {
+	v, err := NewTransactionValidator(config.ValidationConfig{MinAmount: 0.01, AmountDecimals: 2}, nil)
+	if err != nil {
+		t.Fatalf("failed to build validator: %v", err)
+	}
+	tests := []struct {
+		raw        string
+		wantReason bool
+	}{
+		{"1e300", false},
+		{"1e400", true},
+		{"-1e400", true},
+	}
+	for _, tt := range tests {
+		amount, reason := v.parseAmount(json.RawMessage(tt.raw))
+		if (reason != "") != tt.wantReason {
+			t.Errorf("%s: got amount %v, reason %q", tt.raw, amount, reason)
+		}
+	}
+}
+
+func TestNewTransactionValidator_InvalidConfig(t *testing.T) {
+	if _, err := NewTransactionValidator(config.ValidationConfig{AccountPattern: "("}, nil); err == nil {
+		t.Error("expected error for invalid account pattern")
+	}
+	if _, err := NewTransactionValidator(config.ValidationConfig{MinAmount: 10, MaxAmount: 5}, nil); err == nil {
+		t.Error("expected error for maxAmount below minAmount")
+	}
+}
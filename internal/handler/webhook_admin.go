@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/dtos"
+	"Payment-Gateway/internal/middleware"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/service"
+	errors "Payment-Gateway/pkg/error"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type WebhookAdminHandler struct {
+	Service service.WebhookDeliveries
+}
+
+func NewWebhookAdminHandler(service service.WebhookDeliveries) WebhookAdminHandler {
+	return WebhookAdminHandler{
+		Service: service,
+	}
+}
+
+// ListDeliveries lists deliveries, defaulting to dead-lettered ones when no state is given.
+func (h *WebhookAdminHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", "WebhookAdminHandler.ListDeliveries"))
+
+	filter, err := parseDeliveryFilter(r.URL.Query())
+	if err != nil {
+		log.Warn("Invalid delivery filter", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	deliveries, err := h.Service.ListDeliveries(filter)
+	if err != nil {
+		log.Error("Failed to list webhook deliveries", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dtos.WebhookDeliveryListResponse{Deliveries: deliveries})
+}
+
+func (h *WebhookAdminHandler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["event_id"]
+	log := middleware.LoggerFromContext(r.Context()).With(
+		zap.String("func", "WebhookAdminHandler.GetDelivery"),
+		zap.String("event_id", eventID),
+	)
+	delivery, attempts, err := h.Service.GetDelivery(eventID)
+	if err != nil {
+		log.Warn("Failed to get webhook delivery", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dtos.WebhookDeliveryDetailResponse{Delivery: delivery, Attempts: attempts})
+}
+
+func (h *WebhookAdminHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	eventID := mux.Vars(r)["event_id"]
+	log := middleware.LoggerFromContext(r.Context()).With(
+		zap.String("func", "WebhookAdminHandler.Replay"),
+		zap.String("event_id", eventID),
+	)
+	if err := h.Service.Replay(eventID); err != nil {
+		log.Warn("Webhook replay failed", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	log.Info("Webhook replay queued")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dtos.WebhookReplayResponse{Status: "queued", EventIDs: []string{eventID}})
+}
+
+// ReplayMatching replays every delivery matching the query filter, defaulting
+// to dead-lettered deliveries when no state is given.
+func (h *WebhookAdminHandler) ReplayMatching(w http.ResponseWriter, r *http.Request) {
+	log := middleware.LoggerFromContext(r.Context()).With(zap.String("func", "WebhookAdminHandler.ReplayMatching"))
+
+	filter, err := parseDeliveryFilter(r.URL.Query())
+	if err != nil {
+		log.Warn("Invalid delivery filter", zap.Error(err))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	replayed, err := h.Service.ReplayMatching(filter)
+	if err != nil {
+		log.Error("Webhook bulk replay failed", zap.Error(err), zap.Int("queued", len(replayed)))
+		middleware.WriteError(w, r, err)
+		return
+	}
+	log.Info("Webhook bulk replay queued", zap.Int("count", len(replayed)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dtos.WebhookReplayResponse{Status: "queued", EventIDs: replayed})
+}
+
+// parseDeliveryFilter builds a filter from query parameters, reporting invalid
+// parameters as field-level details of ErrInvalidRequest.
+func parseDeliveryFilter(q url.Values) (models.WebhookDeliveryFilter, error) {
+	filter := models.WebhookDeliveryFilter{
+		State:         constants.WebhookDeliveryState(q.Get("state")),
+		MerchantID:    q.Get("merchant_id"),
+		TransactionID: q.Get("transaction_id"),
+	}
+	var details []errors.ErrorDetail
+	switch filter.State {
+	case "":
+		filter.State = constants.DeliveryDeadLettered
+	case constants.DeliveryPending, constants.DeliveryDelivered, constants.DeliveryDeadLettered:
+	default:
+		details = append(details, errors.ErrorDetail{Field: "state", Reason: fmt.Sprintf("unknown state %q", filter.State)})
+	}
+	var err error
+	if v := q.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			details = append(details, errors.ErrorDetail{Field: "from", Reason: "must be an RFC 3339 timestamp"})
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			details = append(details, errors.ErrorDetail{Field: "to", Reason: "must be an RFC 3339 timestamp"})
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			details = append(details, errors.ErrorDetail{Field: "limit", Reason: "must be a non-negative integer"})
+		}
+	}
+	if len(details) > 0 {
+		return filter, errors.WithDetails(errors.ErrInvalidRequest, details...)
+	}
+	return filter, nil
+}
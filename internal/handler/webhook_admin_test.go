@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+)
+
+func TestWebhookAdminHandler_ListDeliveries_DefaultsToDeadLettered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockWebhookDeliveries(ctrl)
+	mockSvc.EXPECT().
+		ListDeliveries(models.WebhookDeliveryFilter{State: constants.DeliveryDeadLettered, MerchantID: "m1"}).
+		Return(nil, nil)
+
+	handler := NewWebhookAdminHandler(mockSvc)
+	req := httptest.NewRequest("GET", "/admin/webhooks/deliveries?merchant_id=m1", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListDeliveries(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestWebhookAdminHandler_ListDeliveries_InvalidFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := NewWebhookAdminHandler(mocks.NewMockWebhookDeliveries(ctrl))
+	req := httptest.NewRequest("GET", "/admin/webhooks/deliveries?from=yesterday", nil)
+	w := httptest.NewRecorder()
+
+	handler.ListDeliveries(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestWebhookAdminHandler_Replay(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"queued", nil, http.StatusAccepted},
+		{"not found", errors.ErrWebhookDeliveryNotFound, http.StatusNotFound},
+		{"in progress", errors.ErrWebhookDeliveryInProgress, http.StatusConflict},
+		{"disabled", errors.ErrWebhooksDisabled, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSvc := mocks.NewMockWebhookDeliveries(ctrl)
+			mockSvc.EXPECT().Replay("evt1").Return(tt.err)
+
+			handler := NewWebhookAdminHandler(mockSvc)
+			req := httptest.NewRequest("POST", "/admin/webhooks/deliveries/evt1/replay", nil)
+			req = mux.SetURLVars(req, map[string]string{"event_id": "evt1"})
+			w := httptest.NewRecorder()
+
+			handler.Replay(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
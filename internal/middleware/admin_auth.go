@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	errors "Payment-Gateway/pkg/error"
+	"crypto/subtle"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+const AdminKeyHeader = "X-Admin-Key"
+
+// AdminAuthMiddleware requires the configured admin key in the X-Admin-Key
+// header. With no key configured every request is rejected.
+func AdminAuthMiddleware(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided := r.Header.Get(AdminKeyHeader)
+			if apiKey == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+				LoggerFromContext(r.Context()).Warn("Rejected admin request",
+					zap.String("path", r.URL.Path),
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.Bool("admin_key_configured", apiKey != ""),
+				)
+				WriteError(w, r, errors.ErrUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// VersionPolicy describes the lifecycle of one mounted API version and
+// advertises it to clients through response headers.
+type VersionPolicy struct {
+	version      string
+	deprecatedAt time.Time
+	sunsetAt     time.Time
+	link         string
+}
+
+// NewVersionPolicy parses the RFC 3339 deprecation and sunset dates of version.
+// Empty dates leave the version current.
+func NewVersionPolicy(version, deprecatedAt, sunsetAt, link string) (*VersionPolicy, error) {
+	p := &VersionPolicy{version: version, link: link}
+	var err error
+	if deprecatedAt != "" {
+		if p.deprecatedAt, err = time.Parse(time.RFC3339, deprecatedAt); err != nil {
+			return nil, fmt.Errorf("api version %s deprecatedAt: %w", version, err)
+		}
+	}
+	if sunsetAt != "" {
+		if p.sunsetAt, err = time.Parse(time.RFC3339, sunsetAt); err != nil {
+			return nil, fmt.Errorf("api version %s sunsetAt: %w", version, err)
+		}
+	}
+	if !p.deprecatedAt.IsZero() && !p.sunsetAt.IsZero() && p.sunsetAt.Before(p.deprecatedAt) {
+		return nil, fmt.Errorf("api version %s: sunsetAt is before deprecatedAt", version)
+	}
+	return p, nil
+}
+
+// Version returns the path segment the policy applies to, e.g. "v1".
+func (p *VersionPolicy) Version() string {
+	return p.version
+}
+
+// Deprecated reports whether the version carries a deprecation date.
+func (p *VersionPolicy) Deprecated() bool {
+	return p != nil && !p.deprecatedAt.IsZero()
+}
+
+// Middleware stamps API-Version on every response and, for deprecated
+// versions, the Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers.
+func (p *VersionPolicy) Middleware(next http.Handler) http.Handler {
+	if p == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("API-Version", p.version)
+		if !p.deprecatedAt.IsZero() {
+			h.Set("Deprecation", fmt.Sprintf("@%d", p.deprecatedAt.Unix()))
+			if p.link != "" {
+				h.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, p.link))
+			}
+		}
+		if !p.sunsetAt.IsZero() {
+			h.Set("Sunset", p.sunsetAt.UTC().Format(http.TimeFormat))
+			if p.link != "" {
+				h.Add("Link", fmt.Sprintf(`<%s>; rel="sunset"`, p.link))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersionPolicy_Middleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		deprecatedAt    string
+		sunsetAt        string
+		link            string
+		wantDeprecation string
+		wantSunset      string
+		wantLinks       int
+	}{
+		{name: "current version"},
+		{name: "deprecated", deprecatedAt: "2025-01-01T00:00:00Z", wantDeprecation: "@1735689600"},
+		{
+			name:            "deprecated with sunset and link",
+			deprecatedAt:    "2025-01-01T00:00:00Z",
+			sunsetAt:        "2025-07-01T00:00:00Z",
+			link:            "https://example.com/migrate",
+			wantDeprecation: "@1735689600",
+			wantSunset:      "Tue, 01 Jul 2025 00:00:00 GMT",
+			wantLinks:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewVersionPolicy("v1", tt.deprecatedAt, tt.sunsetAt, tt.link)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/v1/deposit", nil))
+
+			if got := w.Header().Get("API-Version"); got != "v1" {
+				t.Errorf("API-Version = %q, want v1", got)
+			}
+			if got := w.Header().Get("Deprecation"); got != tt.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tt.wantDeprecation)
+			}
+			if got := w.Header().Get("Sunset"); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+			if got := len(w.Header().Values("Link")); got != tt.wantLinks {
+				t.Errorf("got %d Link headers, want %d", got, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestNewVersionPolicy_InvalidDates(t *testing.T) {
+	if _, err := NewVersionPolicy("v1", "not-a-date", "", ""); err == nil {
+		t.Error("expected error for invalid deprecatedAt")
+	}
+	if _, err := NewVersionPolicy("v1", "2025-07-01T00:00:00Z", "2025-01-01T00:00:00Z", ""); err == nil {
+		t.Error("expected error for sunset before deprecation")
+	}
+}
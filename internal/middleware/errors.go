@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	errors "Payment-Gateway/pkg/error"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+)
+
+// ErrorResponse is the JSON envelope for API errors.
+type ErrorResponse struct {
+	Error *errors.APIError `json:"error"`
+}
+
+// XMLErrorResponse is the XML envelope for API errors returned to SOAP/XML clients.
+type XMLErrorResponse struct {
+	XMLName xml.Name         `xml:"ErrorResponse"`
+	Error   *errors.APIError `xml:"Error"`
+}
+
+// APIErrorFor converts err into an APIError stamped with the request ID.
+func APIErrorFor(r *http.Request, err error) *errors.APIError {
+	apiErr := *errors.FromError(err)
+	if requestID, ok := r.Context().Value(ContextKeyRequestID).(string); ok {
+		apiErr.RequestID = requestID
+	}
+	return &apiErr
+}
+
+// WriteError writes err as a JSON error response with its mapped HTTP status.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := APIErrorFor(r, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: apiErr})
+}
+
+// WriteXMLError writes err as an XML error response with its mapped HTTP status.
+func WriteXMLError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := APIErrorFor(r, err)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(apiErr.Status)
+	xml.NewEncoder(w).Encode(XMLErrorResponse{Error: apiErr})
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	errors "Payment-Gateway/pkg/error"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// IPAllowlist restricts requests to clients whose address falls within a set of CIDR ranges.
+type IPAllowlist struct {
+	name           string
+	allowed        []*net.IPNet
+	trustedProxies []*net.IPNet
+}
+
+// NewIPAllowlist parses the allowed and trusted proxy CIDR lists. An empty
+// allowed list disables the check so every client is accepted.
+func NewIPAllowlist(name string, allowedCIDRs, trustedProxyCIDRs []string) (*IPAllowlist, error) {
+	allowed, err := parseCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist %s: %w", name, err)
+	}
+	proxies, err := parseCIDRs(trustedProxyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist %s trusted proxies: %w", name, err)
+	}
+	return &IPAllowlist{name: name, allowed: allowed, trustedProxies: proxies}, nil
+}
+
+// Middleware rejects requests from clients outside the allowlist with 403.
+func (a *IPAllowlist) Middleware(next http.Handler) http.Handler {
+	if a == nil || len(a.allowed) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r, a.trustedProxies)
+		if ip == nil || !containsIP(a.allowed, ip) {
+			LoggerFromContext(r.Context()).Warn("Rejected request from address outside allowlist",
+				zap.String("allowlist", a.name),
+				zap.String("client_ip", ip.String()),
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("x_forwarded_for", r.Header.Get("X-Forwarded-For")),
+				zap.String("path", r.URL.Path),
+			)
+			WriteError(w, r, errors.ErrForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP resolves the originating client address. X-Forwarded-For is only
+// honoured when the direct peer is a trusted proxy; the chain is then walked
+// from the right and the first address that is not a trusted proxy is returned.
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !containsIP(trustedProxies, remote) {
+		return remote
+	}
+
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// A malformed hop means the chain cannot be trusted past this point.
+			return remote
+		}
+		if !containsIP(trustedProxies, ip) {
+			return ip
+		}
+		remote = ip
+	}
+	return remote
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(c))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPAllowlist_Middleware(t *testing.T) {
+	allowlist, err := NewIPAllowlist("gatewayA", []string{"203.0.113.0/24"}, []string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := allowlist.Middleware(next)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		want       int
+	}{
+		{"direct allowed", "203.0.113.7:4000", "", http.StatusOK},
+		{"direct denied", "198.51.100.1:4000", "", http.StatusForbidden},
+		{"spoofed header from untrusted peer", "198.51.100.1:4000", "203.0.113.7", http.StatusForbidden},
+		{"forwarded by trusted proxy", "10.1.2.3:4000", "203.0.113.7, 10.0.0.5", http.StatusOK},
+		{"forwarded denied client", "10.1.2.3:4000", "203.0.113.7, 198.51.100.1", http.StatusForbidden},
+		{"trusted proxy without header", "10.1.2.3:4000", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/callback/gateway-a", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestIPAllowlist_EmptyAllowsAll(t *testing.T) {
+	allowlist, err := NewIPAllowlist("gatewayB", nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := allowlist.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+	req := httptest.NewRequest("POST", "/callback/gateway-b", nil)
+	req.RemoteAddr = "198.51.100.1:4000"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
+
+func TestNewIPAllowlist_InvalidCIDR(t *testing.T) {
+	if _, err := NewIPAllowlist("gatewayA", []string{"not-a-cidr"}, nil); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+}
@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	errors "Payment-Gateway/pkg/error"
+	"bytes"
+	"container/list"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// RateLimitRule configures one token bucket: RequestsPerSecond refill rate and
+// Burst capacity, at least 1. A non-positive rate disables the dimension.
+type RateLimitRule struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+type tokenBucket struct {
+	key      string
+	tokens   float64
+	last     time.Time
+	lastSeen time.Time
+}
+
+// bucketState describes a bucket after a rate limit decision, for response headers.
+type bucketState struct {
+	limit      int
+	remaining  int
+	reset      time.Duration // until the bucket is full again
+	retryAfter time.Duration // until the next token, when denied
+}
+
+// RateLimiter enforces token-bucket limits per API key, per account and per
+// source IP. A request is admitted only if every applicable bucket has a token.
+// Clients choose their API key and account, so at most maxBuckets buckets are
+// kept; the least recently used one makes room for a new one.
+type RateLimiter struct {
+	mu             sync.Mutex
+	rules          map[string]RateLimitRule // keyed by dimension
+	buckets        map[string]*list.Element // keyed by dimension + ":" + value
+	recent         *list.List               // of *tokenBucket, most recently used first
+	maxBuckets     int
+	maxBodyBytes   int64
+	trustedProxies []*net.IPNet
+	idleTTL        time.Duration
+	now            func() time.Time
+	stop           chan struct{} // closed by Close to end the janitor
+	stopOnce       sync.Once
+}
+
+const (
+	dimensionAPIKey  = "api_key"
+	dimensionAccount = "account"
+	dimensionIP      = "ip"
+)
+
+const (
+	defaultMaxBuckets   = 100000
+	defaultMaxBodyBytes = 64 << 10
+)
+
+// NewRateLimiter builds a limiter reading account IDs from bodies of up to
+// maxBodyBytes and keeping up to maxBuckets buckets; non-positive values fall
+// back to defaults. With a positive idleTTL it drops idle buckets until Close.
+func NewRateLimiter(perAPIKey, perAccount, perIP RateLimitRule, trustedProxyCIDRs []string, idleTTL time.Duration, maxBuckets int, maxBodyBytes int64) (*RateLimiter, error) {
+	rules := map[string]RateLimitRule{
+		dimensionAPIKey:  perAPIKey,
+		dimensionAccount: perAccount,
+		dimensionIP:      perIP,
+	}
+	for _, dimension := range []string{dimensionAPIKey, dimensionAccount, dimensionIP} {
+		// An empty bucket would deny every request in the dimension
+		if rule := rules[dimension]; rule.RequestsPerSecond > 0 && rule.Burst < 1 {
+			return nil, fmt.Errorf("rate limit per %s: burst must be at least 1, got %d", dimension, rule.Burst)
+		}
+	}
+	proxies, err := parseCIDRs(trustedProxyCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	rl := &RateLimiter{
+		rules:          rules,
+		buckets:        make(map[string]*list.Element),
+		recent:         list.New(),
+		maxBuckets:     maxBuckets,
+		maxBodyBytes:   maxBodyBytes,
+		trustedProxies: proxies,
+		idleTTL:        idleTTL,
+		now:            time.Now,
+		stop:           make(chan struct{}),
+	}
+	if rl.maxBuckets <= 0 {
+		rl.maxBuckets = defaultMaxBuckets
+	}
+	if rl.maxBodyBytes <= 0 {
+		rl.maxBodyBytes = defaultMaxBodyBytes
+	}
+	if idleTTL > 0 {
+		go rl.startJanitor(idleTTL)
+	}
+	return rl, nil
+}
+
+// Middleware rejects requests over any limit with 429 and sets RateLimit-* headers.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if rl == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		account, err := accountFromBody(w, r, rl.maxBodyBytes)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		keys := map[string]string{
+			dimensionAPIKey:  r.Header.Get(APIKeyHeader),
+			dimensionAccount: account,
+		}
+		if ip := ClientIP(r, rl.trustedProxies); ip != nil {
+			keys[dimensionIP] = ip.String()
+		}
+
+		allowed, limitedBy, state := rl.take(keys)
+		if state.limit > 0 {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(state.limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(state.remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(state.reset)))
+		}
+		if !allowed {
+			LoggerFromContext(r.Context()).Warn("Rate limit exceeded",
+				zap.String("dimension", limitedBy),
+				zap.String("path", r.URL.Path),
+			)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(state.retryAfter)))
+			WriteError(w, r, errors.ErrRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take refills the buckets for the given keys and consumes one token from each
+// only when all of them have one. The returned state is that of the most
+// constrained bucket, or of the bucket that denied the request.
+func (rl *RateLimiter) take(keys map[string]string) (bool, string, bucketState) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+
+	type entry struct {
+		dimension string
+		rule      RateLimitRule
+		bucket    *tokenBucket
+	}
+	var entries []entry
+	for _, dimension := range []string{dimensionAPIKey, dimensionAccount, dimensionIP} {
+		rule, value := rl.rules[dimension], keys[dimension]
+		if rule.RequestsPerSecond <= 0 || value == "" {
+			continue
+		}
+		b := rl.bucket(dimension+":"+value, rule, now)
+		b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.last).Seconds()*rule.RequestsPerSecond)
+		b.last, b.lastSeen = now, now
+		entries = append(entries, entry{dimension, rule, b})
+	}
+
+	for _, e := range entries {
+		if e.bucket.tokens < 1 {
+			return false, e.dimension, stateOf(e.rule, e.bucket)
+		}
+	}
+	var tightest bucketState
+	for i, e := range entries {
+		e.bucket.tokens--
+		s := stateOf(e.rule, e.bucket)
+		if i == 0 || s.remaining < tightest.remaining {
+			tightest = s
+		}
+	}
+	return true, "", tightest
+}
+
+// bucket returns the bucket for key, creating it full when missing, and marks
+// it most recently used.
+func (rl *RateLimiter) bucket(key string, rule RateLimitRule, now time.Time) *tokenBucket {
+	if elem, ok := rl.buckets[key]; ok {
+		rl.recent.MoveToFront(elem)
+		return elem.Value.(*tokenBucket)
+	}
+	for rl.recent.Len() >= rl.maxBuckets {
+		rl.evict(rl.recent.Back())
+	}
+	b := &tokenBucket{key: key, tokens: float64(rule.Burst), last: now}
+	rl.buckets[key] = rl.recent.PushFront(b)
+	return b
+}
+
+func (rl *RateLimiter) evict(elem *list.Element) {
+	rl.recent.Remove(elem)
+	delete(rl.buckets, elem.Value.(*tokenBucket).key)
+}
+
+func stateOf(rule RateLimitRule, b *tokenBucket) bucketState {
+	perToken := time.Duration(float64(time.Second) / rule.RequestsPerSecond)
+	s := bucketState{
+		limit:     rule.Burst,
+		remaining: int(math.Floor(b.tokens)),
+		reset:     time.Duration((float64(rule.Burst) - b.tokens) * float64(perToken)),
+	}
+	if b.tokens < 1 {
+		s.retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	return s
+}
+
+// startJanitor evicts buckets that have not been used for the idle TTL.
+// Close stops dropping idle buckets.
+func (rl *RateLimiter) Close() {
+	if rl == nil {
+		return
+	}
+	rl.stopOnce.Do(func() { close(rl.stop) })
+}
+
+func (rl *RateLimiter) startJanitor(idleTTL time.Duration) {
+	ticker := time.NewTicker(idleTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-rl.stop:
+			return
+		}
+		cutoff := rl.now().Add(-idleTTL)
+		rl.mu.Lock()
+		for elem := rl.recent.Back(); elem != nil && elem.Value.(*tokenBucket).lastSeen.Before(cutoff); elem = rl.recent.Back() {
+			rl.evict(elem)
+		}
+		rl.mu.Unlock()
+	}
+}
+
+// accountFromBody peeks at the JSON account_id without consuming the body. It
+// fails with ErrRequestTooLarge for bodies over maxBodyBytes rather than
+// buffering them.
+func accountFromBody(w http.ResponseWriter, r *http.Request, maxBodyBytes int64) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			return "", errors.ErrRequestTooLarge
+		}
+		return "", nil
+	}
+	var payload struct {
+		AccountID string `json:"account_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", nil
+	}
+	return payload.AccountID, nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
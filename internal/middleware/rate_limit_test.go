@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(t *testing.T, perAPIKey, perAccount, perIP RateLimitRule) (*RateLimiter, *time.Time) {
+	t.Helper()
+	rl, err := NewRateLimiter(perAPIKey, perAccount, perIP, nil, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	rl.now = func() time.Time { return now }
+	return rl, &now
+}
+
+func doRateLimited(h http.Handler, apiKey, body, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/deposit", strings.NewReader(body))
+	req.RemoteAddr = remoteAddr
+	if apiKey != "" {
+		req.Header.Set(APIKeyHeader, apiKey)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_PerAccount(t *testing.T) {
+	rl, now := newTestRateLimiter(t, RateLimitRule{}, RateLimitRule{RequestsPerSecond: 1, Burst: 2}, RateLimitRule{})
+	var gotBody string
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+	body := `{"account_id":"acc1","amount":10}`
+
+	for i := 0; i < 2; i++ {
+		if w := doRateLimited(h, "", body, "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, w.Code)
+		}
+	}
+	if gotBody != body {
+		t.Errorf("expected body to be passed through, got %q", gotBody)
+	}
+
+	w := doRateLimited(h, "", body, "192.0.2.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("RateLimit-Limit") != "2" {
+		t.Errorf("unexpected headers: %v", w.Header())
+	}
+
+	// Other accounts are unaffected.
+	if w := doRateLimited(h, "", `{"account_id":"acc2"}`, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for other account, got %d", w.Code)
+	}
+
+	*now = now.Add(time.Second)
+	if w := doRateLimited(h, "", body, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after refill, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_DeniedRequestDoesNotConsumeOtherBuckets(t *testing.T) {
+	rl, _ := newTestRateLimiter(t,
+		RateLimitRule{RequestsPerSecond: 1, Burst: 1},
+		RateLimitRule{},
+		RateLimitRule{RequestsPerSecond: 1, Burst: 2},
+	)
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	if w := doRateLimited(h, "key1", "{}", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w := doRateLimited(h, "key1", "{}", "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for exhausted API key, got %d", w.Code)
+	}
+	// The IP bucket still has the token the rejected request did not spend.
+	if w := doRateLimited(h, "key2", "{}", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for another API key from the same IP, got %d", w.Code)
+	}
+}
+
+func TestRateLimiter_RejectsOversizedBody(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitRule{}, RateLimitRule{RequestsPerSecond: 1, Burst: 1}, RateLimitRule{}, nil, 0, 0, 16)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var read string
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		read = string(body)
+	}))
+
+	if w := doRateLimited(h, "", `{"account_id":"a"}`+strings.Repeat(" ", 64), "192.0.2.1:1234"); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", w.Code)
+	}
+	if w := doRateLimited(h, "", `{}`, "192.0.2.1:1234"); w.Code != http.StatusOK || read != `{}` {
+		t.Errorf("expected the small body to reach the handler, got %d %q", w.Code, read)
+	}
+}
+
+func TestRateLimiter_CapsBuckets(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitRule{}, RateLimitRule{RequestsPerSecond: 1, Burst: 1}, RateLimitRule{}, nil, 0, 2, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	doRateLimited(h, "", `{"account_id":"acc1"}`, "192.0.2.1:1234")
+	doRateLimited(h, "", `{"account_id":"acc2"}`, "192.0.2.1:1234")
+	if w := doRateLimited(h, "", `{"account_id":"acc2"}`, "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 for exhausted account, got %d", w.Code)
+	}
+	for i := 0; i < 10; i++ {
+		doRateLimited(h, "", `{"account_id":"other`+strconv.Itoa(i)+`"}`, "192.0.2.1:1234")
+	}
+	rl.mu.Lock()
+	n := len(rl.buckets)
+	rl.mu.Unlock()
+	if n != 2 {
+		t.Errorf("expected at most 2 buckets, got %d", n)
+	}
+}
+
+func TestNewRateLimiter_RejectsEmptyBurst(t *testing.T) {
+	if _, err := NewRateLimiter(RateLimitRule{}, RateLimitRule{RequestsPerSecond: 10}, RateLimitRule{}, nil, 0, 0, 0); err == nil || !strings.Contains(err.Error(), "per account") {
+		t.Errorf("expected a burst error for the account rule, got %v", err)
+	}
+	// A disabled dimension needs no burst
+	if _, err := NewRateLimiter(RateLimitRule{}, RateLimitRule{}, RateLimitRule{RequestsPerSecond: 1, Burst: 1}, nil, 0, 0, 0); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+func TestRateLimiter_CloseStopsJanitor(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitRule{}, RateLimitRule{RequestsPerSecond: 1, Burst: 1}, RateLimitRule{}, nil, time.Millisecond, 0, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rl.Close()
+	rl.Close()
+	time.Sleep(10 * time.Millisecond) // lets a janitor still running see the stop
+
+	doRateLimited(h, "", `{"account_id":"acc1"}`, "192.0.2.1:1234")
+	time.Sleep(10 * time.Millisecond)
+	rl.mu.Lock()
+	n := len(rl.buckets)
+	rl.mu.Unlock()
+	if n != 1 {
+		t.Errorf("expected the idle bucket to be kept once the janitor stopped, got %d buckets", n)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 )
@@ -11,7 +12,7 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printf("panic recovered: %v", err)
-				http.Error(w, "internal server error", http.StatusInternalServerError)
+				WriteError(w, r, fmt.Errorf("panic: %v", err))
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"Payment-Gateway/internal/constants"
+	"time"
+)
+
+// GatewayStatus is a gateway's runtime state as operators see it. Routable is
+// false while the gateway is draining, disabled or in a maintenance window.
+type GatewayStatus struct {
+	Gateway         string                 `json:"gateway"`
+	State           constants.GatewayState `json:"state"`
+	Reason          string                 `json:"reason,omitempty"`
+	ChangedAt       *time.Time             `json:"changed_at,omitempty"`
+	InFlight        int                    `json:"in_flight"`
+	Routable        bool                   `json:"routable"`
+	ActiveWindow    *MaintenanceWindow     `json:"active_window,omitempty"`
+	UpcomingWindows []MaintenanceWindow    `json:"upcoming_windows,omitempty"`
+}
+
+// MaintenanceWindow is a scheduled period during which a gateway gets no traffic.
+type MaintenanceWindow struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"Payment-Gateway/internal/constants"
+	"time"
+)
+
+// ShadowReport compares a shadow gateway's answers with the answers of the
+// gateways that processed the same transactions. Outcomes are "approved",
+// "declined", "pending" or "error"; Comparisons counts each
+// "<primary>/<shadow>" pair.
+type ShadowReport struct {
+	Gateway          string           `json:"gateway"`
+	Percent          float64          `json:"percent"`
+	Mirrored         int              `json:"mirrored"`
+	Matched          int              `json:"matched"`
+	Mismatched       int              `json:"mismatched"`
+	ShadowErrors     int              `json:"shadow_errors"`
+	Dropped          int              `json:"dropped"`
+	AvgLatencyMillis int64            `json:"avg_latency_ms"`
+	Comparisons      map[string]int   `json:"comparisons"`
+	RecentMismatches []ShadowMismatch `json:"recent_mismatches"`
+}
+
+// ShadowMismatch records one transaction the shadow gateway answered differently.
+type ShadowMismatch struct {
+	TransactionID  string               `json:"transaction_id"`
+	PrimaryGateway string               `json:"primary_gateway"`
+	Primary        string               `json:"primary"`
+	PrimaryReason  constants.ReasonCode `json:"primary_reason,omitempty"`
+	Shadow         string               `json:"shadow"`
+	ShadowReason   constants.ReasonCode `json:"shadow_reason,omitempty"`
+	ShadowError    string               `json:"shadow_error,omitempty"`
+	At             time.Time            `json:"at"`
+}
@@ -6,20 +6,60 @@ import (
 )
 
 type Transaction struct {
-	ID        string                      `json:"id"`
-	Type      constants.TransactionType   `json:"type"`
-	Amount    float64                     `json:"amount"`
-	Status    constants.TransactionStatus `json:"status"`
-	Timestamp time.Time                   `json:"timestamp"`
-	Account   string                      `json:"account"`
+	ID         string                      `json:"id"`
+	Type       constants.TransactionType   `json:"type"`
+	Amount     float64                     `json:"amount"`
+	Currency   string                      `json:"currency"`
+	Status     constants.TransactionStatus `json:"status"`
+	Timestamp  time.Time                   `json:"timestamp"`
+	Account    string                      `json:"account"`
+	MerchantID string                      `json:"merchant_id"`
+	Country    string                      `json:"country,omitempty"`
+	// LastCallbackAt is the gateway timestamp of the last callback whose
+	// status change was applied; older callbacks are ignored.
+	LastCallbackAt time.Time `json:"last_callback_at"`
+	// LastCallbackReceivedAt is when we received that callback. Unlike the
+	// gateway's clock it can be compared with when we sent the request.
+	LastCallbackReceivedAt time.Time `json:"last_callback_received_at"`
+	// GatewayResult is what the gateway answered; nil until it has answered
+	// or failed to.
+	GatewayResult *GatewayResult `json:"gateway_result,omitempty"`
+	// GatewayAttempts lists every gateway the transaction was sent to, in
+	// order; there is more than one only after a failover.
+	GatewayAttempts []GatewayAttempt `json:"gateway_attempts,omitempty"`
+}
+
+// GatewayResult records the gateway's answer next to its normalized reason.
+type GatewayResult struct {
+	Reference  string               `json:"reference,omitempty"`
+	ReasonCode constants.ReasonCode `json:"reason_code,omitempty"`
+	Code       string               `json:"code,omitempty"`
+	Message    string               `json:"message,omitempty"`
+}
+
+// GatewayAttempt records one call to a gateway. Outcome is empty when the
+// call failed without an answer, in which case Error says why.
+type GatewayAttempt struct {
+	Gateway    string               `json:"gateway"`
+	Outcome    string               `json:"outcome,omitempty"`
+	ReasonCode constants.ReasonCode `json:"reason_code,omitempty"`
+	Reference  string               `json:"reference,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	At         time.Time            `json:"at"`
 }
 
 type DepositRequest struct {
-	Account string  `json:"account"`
-	Amount  float64 `json:"amount"`
+	Account    string  `json:"account"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	MerchantID string  `json:"merchant_id,omitempty"`
 }
 
 type WithdrawalRequest struct {
-	Account string  `json:"account"`
-	Amount  float64 `json:"amount"`
+	Account    string  `json:"account"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	MerchantID string  `json:"merchant_id,omitempty"`
 }
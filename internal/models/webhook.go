@@ -0,0 +1,54 @@
+package models
+
+import (
+	"Payment-Gateway/internal/constants"
+	"time"
+)
+
+// WebhookEvent is the JSON payload POSTed to a merchant's webhook endpoint.
+type WebhookEvent struct {
+	ID        string                     `json:"id"`
+	Type      constants.WebhookEventType `json:"type"`
+	CreatedAt time.Time                  `json:"created_at"`
+	Data      Transaction                `json:"data"`
+}
+
+// WebhookDelivery tracks the delivery state of one event across all of its attempts.
+type WebhookDelivery struct {
+	EventID        string                         `json:"event_id"`
+	Event          WebhookEvent                   `json:"event"`
+	MerchantID     string                         `json:"merchant_id"`
+	URL            string                         `json:"url"`
+	State          constants.WebhookDeliveryState `json:"state"`
+	Attempts       int                            `json:"attempts"`
+	LastStatusCode int                            `json:"last_status_code,omitempty"`
+	LastError      string                         `json:"last_error,omitempty"`
+	CreatedAt      time.Time                      `json:"created_at"`
+	UpdatedAt      time.Time                      `json:"updated_at"`
+}
+
+// WebhookDeliveryFilter selects deliveries; zero-valued fields match everything.
+type WebhookDeliveryFilter struct {
+	State         constants.WebhookDeliveryState
+	MerchantID    string
+	TransactionID string
+	From          time.Time // inclusive, on CreatedAt
+	To            time.Time // exclusive, on CreatedAt
+	Limit         int
+}
+
+// WebhookDeliveryAttempt records a single attempt to deliver a webhook event.
+type WebhookDeliveryAttempt struct {
+	ID            string                     `json:"id"`
+	EventID       string                     `json:"event_id"`
+	EventType     constants.WebhookEventType `json:"event_type"`
+	TransactionID string                     `json:"transaction_id"`
+	MerchantID    string                     `json:"merchant_id"`
+	URL           string                     `json:"url"`
+	Attempt       int                        `json:"attempt"`
+	StatusCode    int                        `json:"status_code,omitempty"`
+	Error         string                     `json:"error,omitempty"`
+	RequestBody   string                     `json:"request_body,omitempty"`
+	LatencyMillis int64                      `json:"latency_ms"`
+	AttemptedAt   time.Time                  `json:"attempted_at"`
+}
@@ -3,8 +3,10 @@ package repository
 import (
 	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/logger"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -12,11 +14,16 @@ import (
 type TransactionRepository interface {
 	CreateTransaction(tx *models.Transaction) error
 	UpdateTransactionStatus(id string, status constants.TransactionStatus) error
+	UpdateTransactionStatusIfNewer(id string, status constants.TransactionStatus, at time.Time) (bool, error)
+	UpdateTransactionStatusUnlessCallbackSince(id string, status constants.TransactionStatus, sentAt time.Time) (bool, error)
+	SetGatewayResult(id string, result models.GatewayResult) error
+	AddGatewayAttempt(id string, attempt models.GatewayAttempt) error
 	GetTransactionByID(id string) (*models.Transaction, bool)
 }
 
 type InMemoryTransactionRepository struct {
-	store sync.Map // map[string]*models.Transaction
+	store sync.Map   // map[string]*models.Transaction
+	mu    sync.Mutex // serialises read-modify-write of stored transactions
 }
 
 func NewInMemoryTransactionRepository() *InMemoryTransactionRepository {
@@ -31,7 +38,7 @@ func (r *InMemoryTransactionRepository) CreateTransaction(tx *models.Transaction
 		zap.String("transaction_id", tx.ID),
 	)
 	log.Info("Creating transaction")
-	r.store.Store(tx.ID, tx)
+	r.store.Store(tx.ID, clone(tx))
 	return nil
 }
 
@@ -41,6 +48,8 @@ func (r *InMemoryTransactionRepository) UpdateTransactionStatus(id string, statu
 		zap.String("transaction_id", id),
 		zap.String("status", string(status)),
 	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	val, ok := r.store.Load(id)
 	if !ok {
 		log.Warn("Transaction not found for update")
@@ -53,16 +62,131 @@ func (r *InMemoryTransactionRepository) UpdateTransactionStatus(id string, statu
 	return nil
 }
 
+// UpdateTransactionStatusIfNewer applies the status only when at is not older
+// than the last applied callback timestamp. It reports whether the update was applied.
+// A zero at carries no ordering information and is always applied.
+func (r *InMemoryTransactionRepository) UpdateTransactionStatusIfNewer(id string, status constants.TransactionStatus, at time.Time) (bool, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryTransactionRepository.UpdateTransactionStatusIfNewer"),
+		zap.String("transaction_id", id),
+		zap.String("status", string(status)),
+		zap.Time("at", at),
+	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.store.Load(id)
+	if !ok {
+		log.Warn("Transaction not found for update")
+		return false, errors.ErrTransactionNotFound
+	}
+	tx := val.(*models.Transaction)
+	if !at.IsZero() && at.Before(tx.LastCallbackAt) {
+		log.Warn("Ignoring out-of-order status update", zap.Time("last_callback_at", tx.LastCallbackAt))
+		return false, nil
+	}
+	tx.Status = status
+	if !at.IsZero() {
+		tx.LastCallbackAt = at
+	}
+	tx.LastCallbackReceivedAt = time.Now()
+	r.store.Store(id, tx)
+	log.Info("Transaction status updated")
+	return true, nil
+}
+
+// UpdateTransactionStatusUnlessCallbackSince applies the status a gateway
+// answered for a request sent at sentAt, unless a callback received since then
+// has already been applied: that callback is the newer news. Both times are
+// ours, as the gateway's clock need not agree with ours. It reports whether the
+// update was applied.
+func (r *InMemoryTransactionRepository) UpdateTransactionStatusUnlessCallbackSince(id string, status constants.TransactionStatus, sentAt time.Time) (bool, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryTransactionRepository.UpdateTransactionStatusUnlessCallbackSince"),
+		zap.String("transaction_id", id),
+		zap.String("status", string(status)),
+		zap.Time("sent_at", sentAt),
+	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.store.Load(id)
+	if !ok {
+		log.Warn("Transaction not found for update")
+		return false, errors.ErrTransactionNotFound
+	}
+	tx := val.(*models.Transaction)
+	if !tx.LastCallbackReceivedAt.IsZero() && !tx.LastCallbackReceivedAt.Before(sentAt) {
+		log.Warn("Ignoring gateway answer superseded by a callback", zap.Time("last_callback_received_at", tx.LastCallbackReceivedAt))
+		return false, nil
+	}
+	tx.Status = status
+	log.Info("Transaction status updated")
+	return true, nil
+}
+
+// SetGatewayResult stores the gateway's answer on the transaction.
+func (r *InMemoryTransactionRepository) SetGatewayResult(id string, result models.GatewayResult) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryTransactionRepository.SetGatewayResult"),
+		zap.String("transaction_id", id),
+		zap.String("reason_code", string(result.ReasonCode)),
+	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.store.Load(id)
+	if !ok {
+		log.Warn("Transaction not found for gateway result")
+		return errors.ErrTransactionNotFound
+	}
+	tx := val.(*models.Transaction)
+	tx.GatewayResult = &result
+	log.Info("Gateway result stored")
+	return nil
+}
+
+// AddGatewayAttempt appends one gateway call to the transaction's history.
+func (r *InMemoryTransactionRepository) AddGatewayAttempt(id string, attempt models.GatewayAttempt) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryTransactionRepository.AddGatewayAttempt"),
+		zap.String("transaction_id", id),
+		zap.String("gateway", attempt.Gateway),
+	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	val, ok := r.store.Load(id)
+	if !ok {
+		log.Warn("Transaction not found for gateway attempt")
+		return errors.ErrTransactionNotFound
+	}
+	tx := val.(*models.Transaction)
+	tx.GatewayAttempts = append(tx.GatewayAttempts, attempt)
+	log.Info("Gateway attempt stored")
+	return nil
+}
+
 func (r *InMemoryTransactionRepository) GetTransactionByID(id string) (*models.Transaction, bool) {
 	log := logger.GetLogger().With(
 		zap.String("func", "InMemoryTransactionRepository.GetTransactionByID"),
 		zap.String("transaction_id", id),
 	)
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	val, ok := r.store.Load(id)
 	if !ok {
 		log.Warn("Transaction not found")
 		return nil, false
 	}
 	log.Info("Transaction found")
-	return val.(*models.Transaction), true
+	return clone(val.(*models.Transaction)), true
+}
+
+// clone copies tx so that callers never share a stored transaction, which is
+// only changed under r.mu.
+func clone(tx *models.Transaction) *models.Transaction {
+	c := *tx
+	if tx.GatewayResult != nil {
+		result := *tx.GatewayResult
+		c.GatewayResult = &result
+	}
+	c.GatewayAttempts = append([]models.GatewayAttempt(nil), tx.GatewayAttempts...)
+	return &c
 }
@@ -4,6 +4,7 @@ import (
 	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/models"
 	"testing"
+	"time"
 )
 
 func TestCreateAndGetTransaction(t *testing.T) {
@@ -102,3 +103,114 @@ func TestCreateTransaction_Overwrite(t *testing.T) {
 		t.Errorf("transaction not overwritten as expected: %+v", got)
 	}
 }
+
+func TestUpdateTransactionStatusIfNewer_IgnoresOlder(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	repo.CreateTransaction(&models.Transaction{ID: "tx4", Status: constants.StatusPending})
+
+	now := time.Now()
+	applied, err := repo.UpdateTransactionStatusIfNewer("tx4", constants.StatusSuccess, now)
+	if err != nil || !applied {
+		t.Fatalf("expected update to be applied, got applied=%v err=%v", applied, err)
+	}
+
+	applied, err = repo.UpdateTransactionStatusIfNewer("tx4", constants.StatusFailed, now.Add(-time.Second))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if applied {
+		t.Errorf("expected older update to be ignored")
+	}
+
+	got, _ := repo.GetTransactionByID("tx4")
+	if got.Status != constants.StatusSuccess {
+		t.Errorf("expected status %v, got %v", constants.StatusSuccess, got.Status)
+	}
+}
+
+func TestUpdateTransactionStatusIfNewer_NotFound(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	_, err := repo.UpdateTransactionStatusIfNewer("not-exist", constants.StatusFailed, time.Now())
+	if err == nil {
+		t.Errorf("expected error when updating non-existent transaction")
+	}
+}
+
+func TestSetGatewayResult(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	tx := &models.Transaction{ID: "tx-gw", Status: constants.StatusPending}
+	repo.CreateTransaction(tx)
+
+	result := models.GatewayResult{Reference: "ref-1", ReasonCode: constants.ReasonInsufficientFunds, Code: "NSF", Message: "insufficient funds"}
+	if err := repo.SetGatewayResult("tx-gw", result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := repo.GetTransactionByID("tx-gw")
+	if got.GatewayResult == nil || *got.GatewayResult != result {
+		t.Errorf("unexpected gateway result: %+v", got.GatewayResult)
+	}
+	if err := repo.SetGatewayResult("missing", result); err == nil {
+		t.Errorf("expected error when storing a result for a non-existent transaction")
+	}
+}
+
+func TestAddGatewayAttempt(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	repo.CreateTransaction(&models.Transaction{ID: "tx-att", Status: constants.StatusPending})
+
+	first := models.GatewayAttempt{Gateway: "GatewayA", ReasonCode: constants.ReasonGatewayUnavailable, Error: "connection refused"}
+	second := models.GatewayAttempt{Gateway: "GatewayB", Outcome: "approved", Reference: "ref-1"}
+	for _, a := range []models.GatewayAttempt{first, second} {
+		if err := repo.AddGatewayAttempt("tx-att", a); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	got, _ := repo.GetTransactionByID("tx-att")
+	if len(got.GatewayAttempts) != 2 || got.GatewayAttempts[0] != first || got.GatewayAttempts[1] != second {
+		t.Errorf("unexpected attempts: %+v", got.GatewayAttempts)
+	}
+	if err := repo.AddGatewayAttempt("missing", first); err == nil {
+		t.Errorf("expected error when storing an attempt for a non-existent transaction")
+	}
+}
+
+func TestGetTransactionByID_ReturnsCopy(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	repo.CreateTransaction(&models.Transaction{ID: "tx-copy", Status: constants.StatusPending})
+	repo.SetGatewayResult("tx-copy", models.GatewayResult{Reference: "ref-1"})
+	repo.AddGatewayAttempt("tx-copy", models.GatewayAttempt{Gateway: "GatewayA"})
+
+	got, _ := repo.GetTransactionByID("tx-copy")
+	got.Status = constants.StatusSuccess
+	got.GatewayResult.Reference = "changed"
+	got.GatewayAttempts[0].Gateway = "changed"
+
+	again, _ := repo.GetTransactionByID("tx-copy")
+	if again.Status != constants.StatusPending || again.GatewayResult.Reference != "ref-1" || again.GatewayAttempts[0].Gateway != "GatewayA" {
+		t.Errorf("expected the stored transaction to be unchanged, got %+v", again)
+	}
+}
+
+func TestUpdateTransactionStatusUnlessCallbackSince(t *testing.T) {
+	repo := NewInMemoryTransactionRepository()
+	repo.CreateTransaction(&models.Transaction{ID: "tx-sync", Status: constants.StatusPending})
+	sentAt := time.Now()
+
+	// No callback yet: the gateway's answer applies
+	if applied, err := repo.UpdateTransactionStatusUnlessCallbackSince("tx-sync", constants.StatusSuccess, sentAt); err != nil || !applied {
+		t.Fatalf("expected the answer to apply, got %v, %v", applied, err)
+	}
+	// A callback received after the request was sent is newer, even when the
+	// gateway's clock stamps it minutes earlier
+	repo.UpdateTransactionStatusIfNewer("tx-sync", constants.StatusFailed, sentAt.Add(-2*time.Minute))
+	if applied, _ := repo.UpdateTransactionStatusUnlessCallbackSince("tx-sync", constants.StatusSuccess, sentAt); applied {
+		t.Error("expected the answer to be superseded by the callback")
+	}
+	if got, _ := repo.GetTransactionByID("tx-sync"); got.Status != constants.StatusFailed {
+		t.Errorf("expected the callback's status, got %s", got.Status)
+	}
+	// A callback received before the request does not hold back its answer
+	if applied, _ := repo.UpdateTransactionStatusUnlessCallbackSince("tx-sync", constants.StatusSuccess, time.Now().Add(time.Millisecond)); !applied {
+		t.Error("expected a later answer to apply")
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type WebhookDeliveryRepository interface {
+	SaveDelivery(delivery *models.WebhookDelivery) error
+	GetDelivery(eventID string) (*models.WebhookDelivery, error)
+	ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	RecordAttempt(attempt *models.WebhookDeliveryAttempt) error
+	ListAttemptsByEvent(eventID string) ([]*models.WebhookDeliveryAttempt, error)
+}
+
+type InMemoryWebhookDeliveryRepository struct {
+	mu         sync.RWMutex
+	deliveries map[string]*models.WebhookDelivery          // keyed by event ID
+	attempts   map[string][]*models.WebhookDeliveryAttempt // keyed by event ID
+}
+
+func NewInMemoryWebhookDeliveryRepository() *InMemoryWebhookDeliveryRepository {
+	log := logger.GetLogger().With(zap.String("func", "NewInMemoryWebhookDeliveryRepository"))
+	log.Info("Initializing in-memory webhook delivery repository")
+	return &InMemoryWebhookDeliveryRepository{
+		deliveries: make(map[string]*models.WebhookDelivery),
+		attempts:   make(map[string][]*models.WebhookDeliveryAttempt),
+	}
+}
+
+// SaveDelivery inserts or replaces the delivery record. A copy is stored so
+// callers can keep mutating their value.
+func (r *InMemoryWebhookDeliveryRepository) SaveDelivery(delivery *models.WebhookDelivery) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryWebhookDeliveryRepository.SaveDelivery"),
+		zap.String("event_id", delivery.EventID),
+		zap.String("state", string(delivery.State)),
+	)
+	stored := *delivery
+	r.mu.Lock()
+	r.deliveries[delivery.EventID] = &stored
+	r.mu.Unlock()
+	log.Info("Webhook delivery saved")
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) GetDelivery(eventID string) (*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	delivery, ok := r.deliveries[eventID]
+	if !ok {
+		return nil, errors.ErrWebhookDeliveryNotFound
+	}
+	d := *delivery
+	return &d, nil
+}
+
+// ListDeliveries returns matching deliveries ordered by creation time, oldest first.
+func (r *InMemoryWebhookDeliveryRepository) ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	var result []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if matchesDeliveryFilter(delivery, filter) {
+			d := *delivery
+			result = append(result, &d)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) RecordAttempt(attempt *models.WebhookDeliveryAttempt) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "InMemoryWebhookDeliveryRepository.RecordAttempt"),
+		zap.String("event_id", attempt.EventID),
+		zap.Int("attempt", attempt.Attempt),
+	)
+	r.mu.Lock()
+	r.attempts[attempt.EventID] = append(r.attempts[attempt.EventID], attempt)
+	r.mu.Unlock()
+	log.Info("Webhook delivery attempt recorded")
+	return nil
+}
+
+func (r *InMemoryWebhookDeliveryRepository) ListAttemptsByEvent(eventID string) ([]*models.WebhookDeliveryAttempt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	attempts := make([]*models.WebhookDeliveryAttempt, len(r.attempts[eventID]))
+	copy(attempts, r.attempts[eventID])
+	return attempts, nil
+}
+
+func matchesDeliveryFilter(d *models.WebhookDelivery, f models.WebhookDeliveryFilter) bool {
+	if f.State != "" && d.State != f.State {
+		return false
+	}
+	if f.MerchantID != "" && d.MerchantID != f.MerchantID {
+		return false
+	}
+	if f.TransactionID != "" && d.Event.Data.ID != f.TransactionID {
+		return false
+	}
+	if !f.From.IsZero() && d.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !d.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
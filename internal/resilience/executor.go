@@ -0,0 +1,130 @@
+package resilience
+
+import (
+	"Payment-Gateway/internal/config"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/sony/gobreaker"
+)
+
+// ErrBulkheadFull is returned when the downstream already has the maximum
+// number of calls in flight.
+var ErrBulkheadFull = fmt.Errorf("%w: too many concurrent requests", apperrors.ErrGatewayNotAvailable)
+
+// Executor guards calls to one downstream service with a bulkhead, a
+// per-attempt timeout, retries with exponential backoff and a circuit breaker.
+// Adapters share one Executor per gateway so the breaker and bulkhead see all
+// of that gateway's traffic.
+type Executor struct {
+	name     string
+	cfg      config.ResilienceConfig
+	breaker  *gobreaker.CircuitBreaker
+	bulkhead chan struct{}
+}
+
+// NewExecutor builds an Executor from cfg. Zero MaxConcurrent disables the
+// bulkhead and zero HTTPTimeoutSeconds disables the per-attempt timeout.
+func NewExecutor(name string, cfg config.ResilienceConfig) *Executor {
+	e := &Executor{name: name, cfg: cfg}
+	if cfg.CircuitBreaker.Enabled {
+		e.breaker = gobreaker.NewCircuitBreaker(gobreaker.Settings{
+			Name:        name,
+			MaxRequests: cfg.CircuitBreaker.MaxRequests,
+			Interval:    time.Duration(cfg.CircuitBreaker.Interval) * time.Second,
+			Timeout:     time.Duration(cfg.CircuitBreaker.Timeout) * time.Second,
+			ReadyToTrip: func(counts gobreaker.Counts) bool {
+				failRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+				return counts.Requests >= 3 && failRatio >= cfg.CircuitBreaker.FailureRatio
+			},
+			// Terminal failures (e.g. 4xx) mean the gateway is up; they do not trip the breaker.
+			IsSuccessful: func(err error) bool {
+				return err == nil || isPermanent(err)
+			},
+		})
+	}
+	if cfg.MaxConcurrent > 0 {
+		e.bulkhead = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return e
+}
+
+// Execute runs call until it succeeds, fails permanently, ctx is done or the
+// retry budget is spent, and returns the last error. Waits between attempts
+// follow exponential backoff, stretched to any RetryAfter hint. Each attempt
+// gets its own context, so call must finish with any response (e.g. read the
+// body) before returning and must build a fresh request every time.
+func (e *Executor) Execute(ctx context.Context, call func(ctx context.Context) error) error {
+	if e.bulkhead != nil {
+		select {
+		case e.bulkhead <- struct{}{}:
+			defer func() { <-e.bulkhead }()
+		default:
+			return ErrBulkheadFull
+		}
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Duration(e.cfg.InitialBackoffMillis) * time.Millisecond
+	b.MaxInterval = time.Duration(e.cfg.MaxBackoffMillis) * time.Millisecond
+	b.MaxElapsedTime = time.Duration(e.cfg.HTTPTimeoutSeconds*e.cfg.MaxRetries) * time.Second
+	b.Reset()
+
+	for attempt := 0; ; attempt++ {
+		err := e.attempt(ctx, call)
+		switch {
+		case err == nil:
+			return nil
+		case isPermanent(err):
+			return unwrapMarkers(err)
+		case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+			return fmt.Errorf("%w: circuit breaker %s is open", apperrors.ErrGatewayNotAvailable, e.name)
+		case attempt >= e.cfg.MaxRetries:
+			return unwrapMarkers(err)
+		}
+
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			return unwrapMarkers(err)
+		}
+		if after := retryAfterOf(err); after > wait {
+			wait = after
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return unwrapMarkers(err)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return unwrapMarkers(err)
+		}
+	}
+}
+
+func (e *Executor) attempt(ctx context.Context, call func(ctx context.Context) error) error {
+	if e.cfg.HTTPTimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(e.cfg.HTTPTimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	if e.breaker == nil {
+		return call(ctx)
+	}
+	_, err := e.breaker.Execute(func() (interface{}, error) {
+		return nil, call(ctx)
+	})
+	return err
+}
+
+// Available reports whether the executor would let a call through, i.e. its
+// circuit breaker is not open. A half-open breaker counts as available so
+// trial requests can close it again.
+func (e *Executor) Available() bool {
+	return e.breaker == nil || e.breaker.State() != gobreaker.StateOpen
+}
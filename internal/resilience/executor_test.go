@@ -0,0 +1,202 @@
+package resilience
+
+import (
+	"Payment-Gateway/internal/config"
+	apperrors "Payment-Gateway/pkg/error"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfig() config.ResilienceConfig {
+	return config.ResilienceConfig{
+		HTTPTimeoutSeconds:   1,
+		MaxRetries:           2,
+		InitialBackoffMillis: 1,
+		MaxBackoffMillis:     2,
+	}
+}
+
+func TestExecutor_RetriesUntilSuccess(t *testing.T) {
+	e := NewExecutor("test", testConfig())
+	var calls int32
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestExecutor_GivesUpAfterMaxRetries(t *testing.T) {
+	e := NewExecutor("test", testConfig())
+	var calls int32
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("down")
+	})
+	if err == nil || err.Error() != "down" {
+		t.Fatalf("expected last error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 1 call plus 2 retries, got %d", calls)
+	}
+}
+
+func TestExecutor_AttemptTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	e := NewExecutor("test", cfg)
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestExecutor_OpenBreakerFailsFast(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, MaxRequests: 1, Interval: 60, Timeout: 60, FailureRatio: 0.5}
+	e := NewExecutor("test", cfg)
+
+	for i := 0; i < 3; i++ {
+		e.Execute(context.Background(), func(ctx context.Context) error { return errors.New("down") })
+	}
+	var called bool
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if called {
+		t.Error("expected open breaker to skip the call")
+	}
+	if !errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+		t.Errorf("expected ErrGatewayNotAvailable, got %v", err)
+	}
+}
+
+func TestExecutor_BulkheadRejectsWhenFull(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConcurrent = 1
+	e := NewExecutor("test", cfg)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- e.Execute(context.Background(), func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	err := e.Execute(context.Background(), func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrBulkheadFull) || !errors.Is(err, apperrors.ErrGatewayNotAvailable) {
+		t.Errorf("expected ErrBulkheadFull, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("expected in-flight call to succeed, got %v", err)
+	}
+
+	if err := e.Execute(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("expected slot to be released, got %v", err)
+	}
+}
+
+func TestExecutor_StopsWhenContextDone(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 100
+	cfg.InitialBackoffMillis = 50
+	cfg.MaxBackoffMillis = 50
+	e := NewExecutor("test", cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	var calls int32
+	start := time.Now()
+	e.Execute(ctx, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("down")
+	})
+	if time.Since(start) > time.Second || calls > 5 {
+		t.Errorf("expected retries to stop with the context, got %d calls in %v", calls, time.Since(start))
+	}
+}
+
+func TestExecutor_PermanentErrorIsNotRetried(t *testing.T) {
+	e := NewExecutor("test", testConfig())
+	terminal := errors.New("bad request")
+	var calls int32
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return Permanent(terminal)
+	})
+	if err != terminal {
+		t.Fatalf("expected unwrapped terminal error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestExecutor_PermanentErrorDoesNotTripBreaker(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, MaxRequests: 1, Interval: 60, Timeout: 60, FailureRatio: 0.5}
+	e := NewExecutor("test", cfg)
+
+	for i := 0; i < 5; i++ {
+		e.Execute(context.Background(), func(ctx context.Context) error { return Permanent(errors.New("bad request")) })
+	}
+	if err := e.Execute(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("expected breaker to stay closed, got %v", err)
+	}
+}
+
+func TestExecutor_RetryAfterStretchesBackoff(t *testing.T) {
+	e := NewExecutor("test", testConfig())
+	var calls int32
+	start := time.Now()
+	err := e.Execute(context.Background(), func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return RetryAfter(errors.New("busy"), 100*time.Millisecond)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected to wait for Retry-After, took %v", elapsed)
+	}
+}
+
+func TestExecutor_AvailableReflectsBreaker(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, MaxRequests: 1, Interval: 60, Timeout: 60, FailureRatio: 0.5}
+	e := NewExecutor("test", cfg)
+	if !e.Available() {
+		t.Fatal("expected closed breaker to be available")
+	}
+	for i := 0; i < 3; i++ {
+		e.Execute(context.Background(), func(ctx context.Context) error { return errors.New("down") })
+	}
+	if e.Available() {
+		t.Error("expected open breaker to be unavailable")
+	}
+}
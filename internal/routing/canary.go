@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCanaryPercent      = 5
+	defaultCanaryMinSamples   = 20
+	defaultCanaryMaxErrorRate = 0.2
+	defaultCanaryWindow       = 5 * time.Minute
+)
+
+// Outcome is one finished gateway call as routing sees it.
+type Outcome struct {
+	// Authorized is true for approved and pending answers.
+	Authorized bool
+	// Failed is true when the call returned an error instead of an answer.
+	Failed  bool
+	Latency time.Duration
+}
+
+// Canary admits a gateway to a share of the traffic it is eligible for and
+// rolls it back, taking it out of rotation, once too many of its calls fail.
+// A rolled back canary stays out until Reset.
+type Canary struct {
+	Name         string
+	percent      float64
+	minSamples   int
+	maxErrorRate float64
+	window       time.Duration
+
+	mu           sync.Mutex
+	samples      []canarySample
+	rolledBack   bool
+	rolledBackAt time.Time
+
+	roll func() float64 // returns a value in [0, 100)
+	now  func() time.Time
+}
+
+type canarySample struct {
+	at     time.Time
+	failed bool
+}
+
+// CanaryStatus summarises a canary for operators.
+type CanaryStatus struct {
+	Gateway      string
+	Percent      float64
+	Samples      int
+	ErrorRate    float64
+	MaxErrorRate float64
+	RolledBack   bool
+	RolledBackAt time.Time
+}
+
+func NewCanary(name string, cfg config.CanaryConfig) *Canary {
+	c := &Canary{
+		Name:         name,
+		percent:      cfg.Percent,
+		minSamples:   cfg.MinSamples,
+		maxErrorRate: cfg.MaxErrorRate,
+		window:       time.Duration(cfg.WindowSeconds) * time.Second,
+		roll:         func() float64 { return rand.Float64() * 100 },
+		now:          time.Now,
+	}
+	if c.percent <= 0 {
+		c.percent = defaultCanaryPercent
+	}
+	if c.minSamples <= 0 {
+		c.minSamples = defaultCanaryMinSamples
+	}
+	if c.maxErrorRate <= 0 {
+		c.maxErrorRate = defaultCanaryMaxErrorRate
+	}
+	if c.window <= 0 {
+		c.window = defaultCanaryWindow
+	}
+	return c
+}
+
+// Admit decides whether one transaction goes to the canary.
+func (c *Canary) Admit() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.rolledBack && c.roll() < c.percent
+}
+
+// RolledBack reports whether the canary was taken out of rotation.
+func (c *Canary) RolledBack() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rolledBack
+}
+
+// Record adds one call outcome and reports whether it rolled the canary back.
+func (c *Canary) Record(failed bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rolledBack {
+		return false
+	}
+	c.samples = append(c.prune(), canarySample{at: c.now(), failed: failed})
+	if len(c.samples) < c.minSamples || c.errorRate() <= c.maxErrorRate {
+		return false
+	}
+	c.rolledBack, c.rolledBackAt = true, c.now()
+	return true
+}
+
+// Reset puts a rolled back canary back into rotation with a fresh window.
+func (c *Canary) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = nil
+	c.rolledBack, c.rolledBackAt = false, time.Time{}
+}
+
+func (c *Canary) Status() CanaryStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.samples = c.prune()
+	return CanaryStatus{
+		Gateway:      c.Name,
+		Percent:      c.percent,
+		Samples:      len(c.samples),
+		ErrorRate:    c.errorRate(),
+		MaxErrorRate: c.maxErrorRate,
+		RolledBack:   c.rolledBack,
+		RolledBackAt: c.rolledBackAt,
+	}
+}
+
+// prune drops samples that fell out of the window. Callers hold c.mu.
+func (c *Canary) prune() []canarySample {
+	cutoff := c.now().Add(-c.window)
+	i := 0
+	for i < len(c.samples) && c.samples[i].at.Before(cutoff) {
+		i++
+	}
+	return c.samples[i:]
+}
+
+// errorRate is the failed share of the samples. Callers hold c.mu.
+func (c *Canary) errorRate() float64 {
+	if len(c.samples) == 0 {
+		return 0
+	}
+	failed := 0
+	for _, s := range c.samples {
+		if s.failed {
+			failed++
+		}
+	}
+	return float64(failed) / float64(len(c.samples))
+}
+
+// SelectWithCanaries routes to an admitted canary among candidates, and
+// otherwise lets strategy choose among the other candidates. Canaries that
+// were not admitted are only used when no other candidate is left; rolled
+// back canaries never are.
+func (t Table) SelectWithCanaries(strategy Strategy, route Request, candidates []gateway.PaymentGateway) (gateway.PaymentGateway, *Canary) {
+	if len(t.Canaries) == 0 {
+		return strategy.Select(route, candidates), nil
+	}
+	var regular, standby []gateway.PaymentGateway
+	for _, gw := range candidates {
+		c, ok := t.Canaries[gw]
+		switch {
+		case !ok:
+			regular = append(regular, gw)
+		case c.Admit():
+			return gw, c
+		case !c.RolledBack():
+			standby = append(standby, gw)
+		}
+	}
+	if len(regular) > 0 {
+		return strategy.Select(route, regular), nil
+	}
+	if len(standby) > 0 {
+		gw := strategy.Select(route, standby)
+		return gw, t.Canaries[gw]
+	}
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"testing"
+	"time"
+)
+
+func TestCanary_Admit(t *testing.T) {
+	c := NewCanary("new", config.CanaryConfig{Percent: 10})
+	c.roll = func() float64 { return 9.9 }
+	if !c.Admit() {
+		t.Error("expected a roll below the percentage to be admitted")
+	}
+	c.roll = func() float64 { return 10 }
+	if c.Admit() {
+		t.Error("expected a roll at the percentage not to be admitted")
+	}
+}
+
+func TestCanary_RollsBackAboveMaxErrorRate(t *testing.T) {
+	c := NewCanary("new", config.CanaryConfig{Percent: 100, MinSamples: 4, MaxErrorRate: 0.5})
+	c.roll = func() float64 { return 0 }
+
+	// 3 failures out of 3 is not enough samples to judge
+	for i := 0; i < 3; i++ {
+		if c.Record(true) {
+			t.Fatalf("sample %d: rolled back before minSamples", i)
+		}
+	}
+	if !c.Record(false) {
+		t.Fatal("expected a 75% error rate to roll the canary back")
+	}
+	if c.Admit() || !c.RolledBack() {
+		t.Error("expected a rolled back canary to get no traffic")
+	}
+	if status := c.Status(); status.ErrorRate != 0.75 || status.RolledBackAt.IsZero() {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	c.Reset()
+	if !c.Admit() || c.Status().Samples != 0 {
+		t.Error("expected reset to restore traffic with a fresh window")
+	}
+}
+
+func TestCanary_StaysWithinMaxErrorRate(t *testing.T) {
+	c := NewCanary("new", config.CanaryConfig{MinSamples: 4, MaxErrorRate: 0.5})
+	for _, failed := range []bool{true, false, true, false, false} {
+		if c.Record(failed) {
+			t.Fatal("expected an error rate at the threshold not to roll back")
+		}
+	}
+}
+
+func TestCanary_ForgetsOutcomesOutsideWindow(t *testing.T) {
+	now := time.Now()
+	c := NewCanary("new", config.CanaryConfig{MinSamples: 2, MaxErrorRate: 0.5, WindowSeconds: 60})
+	c.now = func() time.Time { return now }
+	c.Record(true)
+	now = now.Add(2 * time.Minute)
+	if c.Record(true) || c.Status().Samples != 1 {
+		t.Error("expected the old failure to have left the window")
+	}
+}
+
+func TestTable_SelectWithCanaries(t *testing.T) {
+	stable, fresh := &testGateway{"stable"}, &testGateway{"fresh"}
+	canary := NewCanary("fresh", config.CanaryConfig{Percent: 50})
+	table := Table{Canaries: map[gateway.PaymentGateway]*Canary{fresh: canary}}
+	candidates := []gateway.PaymentGateway{fresh, stable}
+
+	canary.roll = func() float64 { return 10 }
+	if gw, c := table.SelectWithCanaries(NewRoundRobin(), Request{}, candidates); gw != fresh || c != canary {
+		t.Error("expected an admitted canary to be selected")
+	}
+	canary.roll = func() float64 { return 90 }
+	for i := 0; i < 3; i++ {
+		if gw, c := table.SelectWithCanaries(NewRoundRobin(), Request{}, candidates); gw != stable || c != nil {
+			t.Fatal("expected the strategy to skip a canary that was not admitted")
+		}
+	}
+	if gw, _ := table.SelectWithCanaries(NewRoundRobin(), Request{}, []gateway.PaymentGateway{fresh}); gw != fresh {
+		t.Error("expected the canary when no other gateway is left")
+	}
+	canary.rolledBack = true
+	if gw, _ := table.SelectWithCanaries(NewRoundRobin(), Request{}, []gateway.PaymentGateway{fresh}); gw != nil {
+		t.Error("expected a rolled back canary never to be selected")
+	}
+}
@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rule restricts the transactions it matches to an ordered list of gateways,
+// for instance the ones supporting a currency or an amount range.
+type Rule struct {
+	Name     string
+	Gateways []gateway.PaymentGateway
+	// Strategy replaces the merchant's strategy for matching transactions;
+	// nil keeps it. StrategyName is its configured name.
+	Strategy     Strategy
+	StrategyName string
+
+	match config.RoutingRuleMatch
+}
+
+// RuleResult records whether one rule matched a request and, if not, why.
+type RuleResult struct {
+	Rule    string
+	Matched bool
+	Reason  string
+}
+
+// Candidate is one gateway a request may be routed to and whether the pool
+// would currently consider it.
+type Candidate struct {
+	Gateway   string
+	Available bool
+	Reason    string
+}
+
+// Explanation describes how a request would be routed without routing it.
+// Rule is empty when no rule matched and every gateway is a candidate.
+type Explanation struct {
+	Rules      []RuleResult
+	Rule       string
+	Strategy   string
+	Candidates []Candidate
+}
+
+// NewRule validates cfg and resolves its gateway names through opts.
+// Currencies, countries and types are compared case-insensitively.
+func NewRule(cfg config.RoutingRule, opts Options) (*Rule, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("routing rule needs a name")
+	}
+	if len(cfg.Gateways) == 0 {
+		return nil, fmt.Errorf("routing rule %s lists no gateways", cfg.Name)
+	}
+
+	r := &Rule{Name: cfg.Name, match: cfg.Match}
+	for _, name := range cfg.Gateways {
+		gw, ok := opts.Gateways[name]
+		if !ok {
+			return nil, fmt.Errorf("routing rule %s: gateway %q is not configured or not enabled", cfg.Name, name)
+		}
+		if slices.Contains(r.Gateways, gw) {
+			return nil, fmt.Errorf("routing rule %s lists gateway %q twice", cfg.Name, name)
+		}
+		r.Gateways = append(r.Gateways, gw)
+	}
+
+	m := &r.match
+	m.Types = upper(m.Types)
+	m.Currencies = upper(m.Currencies)
+	m.Countries = upper(m.Countries)
+	for _, t := range m.Types {
+		if t != string(constants.TypeDeposit) && t != string(constants.TypeWithdrawal) {
+			return nil, fmt.Errorf("routing rule %s: unknown transaction type %q", cfg.Name, t)
+		}
+	}
+	if m.MinAmount < 0 || m.MaxAmount < 0 {
+		return nil, fmt.Errorf("routing rule %s: amount bounds must not be negative", cfg.Name)
+	}
+	if m.MaxAmount > 0 && m.MaxAmount < m.MinAmount {
+		return nil, fmt.Errorf("routing rule %s: maxAmount %v is below minAmount %v", cfg.Name, m.MaxAmount, m.MinAmount)
+	}
+
+	if cfg.Strategy != "" {
+		s, err := NewStrategy(cfg.Strategy, opts)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %s: %w", cfg.Name, err)
+		}
+		r.Strategy, r.StrategyName = s, cfg.Strategy
+	}
+	return r, nil
+}
+
+// Matches reports whether route meets every condition of the rule and, when
+// it does not, the first condition it fails.
+func (r *Rule) Matches(route Request) (bool, string) {
+	m := r.match
+	switch {
+	case !oneOf(m.Types, string(route.Type)):
+		return false, fmt.Sprintf("type %q is not one of %v", route.Type, m.Types)
+	case !oneOf(m.Currencies, route.Currency):
+		return false, fmt.Sprintf("currency %q is not one of %v", route.Currency, m.Currencies)
+	case route.Amount < m.MinAmount:
+		return false, fmt.Sprintf("amount %v is below %v", route.Amount, m.MinAmount)
+	case m.MaxAmount > 0 && route.Amount > m.MaxAmount:
+		return false, fmt.Sprintf("amount %v is above %v", route.Amount, m.MaxAmount)
+	case len(m.AccountPrefixes) > 0 && !slices.ContainsFunc(m.AccountPrefixes, func(p string) bool {
+		return strings.HasPrefix(route.Account, p)
+	}):
+		return false, fmt.Sprintf("account %q has none of the prefixes %v", route.Account, m.AccountPrefixes)
+	case !oneOf(m.Countries, route.Country):
+		return false, fmt.Sprintf("country %q is not one of %v", route.Country, m.Countries)
+	case !oneOf(m.Merchants, route.MerchantID):
+		return false, fmt.Sprintf("merchant %q is not one of %v", route.MerchantID, m.Merchants)
+	}
+	return true, ""
+}
+
+// RuleFor returns the first rule route matches, or nil when none does.
+func (t Table) RuleFor(route Request) *Rule {
+	for _, r := range t.Rules {
+		if ok, _ := r.Matches(route); ok {
+			return r
+		}
+	}
+	return nil
+}
+
+// EvaluateRules is RuleFor with the result of every rule it tried.
+func (t Table) EvaluateRules(route Request) (*Rule, []RuleResult) {
+	results := make([]RuleResult, 0, len(t.Rules))
+	for _, r := range t.Rules {
+		ok, reason := r.Matches(route)
+		results = append(results, RuleResult{Rule: r.Name, Matched: ok, Reason: reason})
+		if ok {
+			return r, results
+		}
+	}
+	return nil, results
+}
+
+// oneOf reports whether v is in values; empty values allow anything.
+func oneOf(values []string, v string) bool {
+	return len(values) == 0 || slices.Contains(values, v)
+}
+
+func upper(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToUpper(v)
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"testing"
+)
+
+func ruleOptions(gws ...*testGateway) Options {
+	var opts Options
+	for _, gw := range gws {
+		opts.AddGateway(gw.name, gw, config.GatewayRoutingConfig{})
+	}
+	return opts
+}
+
+func TestRule_Matches(t *testing.T) {
+	a := &testGateway{"a"}
+	rule, err := NewRule(config.RoutingRule{
+		Name: "eu-large",
+		Match: config.RoutingRuleMatch{
+			Types:           []string{"deposit"},
+			Currencies:      []string{"eur", "GBP"},
+			MinAmount:       100,
+			MaxAmount:       5000,
+			AccountPrefixes: []string{"EU-", "UK-"},
+			Countries:       []string{"de", "fr"},
+			Merchants:       []string{"m1"},
+		},
+		Gateways: []string{"a"},
+	}, ruleOptions(a))
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+
+	match := Request{Type: constants.TypeDeposit, MerchantID: "m1", Account: "EU-123", Amount: 100, Currency: "EUR", Country: "DE"}
+	tests := []struct {
+		name   string
+		modify func(r *Request)
+		want   bool
+	}{
+		{"all conditions met", func(r *Request) {}, true},
+		{"max amount is inclusive", func(r *Request) { r.Amount = 5000 }, true},
+		{"wrong type", func(r *Request) { r.Type = constants.TypeWithdrawal }, false},
+		{"wrong currency", func(r *Request) { r.Currency = "USD" }, false},
+		{"below minimum", func(r *Request) { r.Amount = 99.99 }, false},
+		{"above maximum", func(r *Request) { r.Amount = 5000.01 }, false},
+		{"account prefix", func(r *Request) { r.Account = "US-1" }, false},
+		{"missing country", func(r *Request) { r.Country = "" }, false},
+		{"other merchant", func(r *Request) { r.MerchantID = "m2" }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := match
+			tt.modify(&route)
+			got, reason := rule.Matches(route)
+			if got != tt.want {
+				t.Errorf("Matches = %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Error("expected a reason for the mismatch")
+			}
+		})
+	}
+}
+
+func TestRule_EmptyMatchAcceptsAnything(t *testing.T) {
+	a := &testGateway{"a"}
+	rule, err := NewRule(config.RoutingRule{Name: "catch-all", Gateways: []string{"a"}}, ruleOptions(a))
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+	if ok, reason := rule.Matches(Request{}); !ok {
+		t.Errorf("expected match, got %s", reason)
+	}
+}
+
+func TestNewRule_Invalid(t *testing.T) {
+	opts := ruleOptions(&testGateway{"a"})
+	tests := map[string]config.RoutingRule{
+		"no name":         {Gateways: []string{"a"}},
+		"no gateways":     {Name: "r"},
+		"unknown gateway": {Name: "r", Gateways: []string{"b"}},
+		"duplicate":       {Name: "r", Gateways: []string{"a", "a"}},
+		"unknown type":    {Name: "r", Gateways: []string{"a"}, Match: config.RoutingRuleMatch{Types: []string{"refund"}}},
+		"inverted amount": {Name: "r", Gateways: []string{"a"}, Match: config.RoutingRuleMatch{MinAmount: 10, MaxAmount: 5}},
+		"bad strategy":    {Name: "r", Gateways: []string{"a"}, Strategy: "random"},
+	}
+	for name, cfg := range tests {
+		if _, err := NewRule(cfg, opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestTable_EvaluateRules_FirstMatchWins(t *testing.T) {
+	a, b := &testGateway{"a"}, &testGateway{"b"}
+	opts := ruleOptions(a, b)
+	eur, _ := NewRule(config.RoutingRule{Name: "eur", Match: config.RoutingRuleMatch{Currencies: []string{"EUR"}}, Gateways: []string{"b"}}, opts)
+	large, _ := NewRule(config.RoutingRule{Name: "large", Match: config.RoutingRuleMatch{MinAmount: 1000}, Gateways: []string{"a", "b"}}, opts)
+	fallback, _ := NewRule(config.RoutingRule{Name: "fallback", Gateways: []string{"a"}}, opts)
+	table := Table{Rules: []*Rule{eur, large, fallback}}
+
+	rule, results := table.EvaluateRules(Request{Amount: 2000, Currency: "USD"})
+	if rule != large {
+		t.Fatalf("expected rule large, got %+v", rule)
+	}
+	if len(results) != 2 || results[0].Matched || !results[1].Matched {
+		t.Errorf("expected eur to fail and large to match, got %+v", results)
+	}
+	if table.RuleFor(Request{Amount: 2000, Currency: "EUR"}) != eur {
+		t.Error("expected the first matching rule")
+	}
+	if (Table{}).RuleFor(Request{}) != nil {
+		t.Error("expected no rule without rules")
+	}
+}
+
+func TestPriority(t *testing.T) {
+	a, b := &testGateway{"a"}, &testGateway{"b"}
+	s := NewPriority()
+	for i := 0; i < 3; i++ {
+		if s.Select(Request{}, []gateway.PaymentGateway{b, a}) != b {
+			t.Fatal("expected the first candidate")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/gateway"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStatsWindow     = 5 * time.Minute
+	defaultStatsMaxSamples = 500
+)
+
+// Stats keeps a sliding window of recent outcomes per gateway: samples
+// older than the window are dropped, and so are the oldest once a gateway has
+// maxSamples.
+type Stats struct {
+	mu         sync.Mutex
+	window     time.Duration
+	maxSamples int
+	samples    map[gateway.PaymentGateway][]outcomeSample
+	now        func() time.Time
+}
+
+type outcomeSample struct {
+	at         time.Time
+	authorized bool
+	latency    time.Duration
+}
+
+// Stat summarises a gateway's samples in the current window.
+type Stat struct {
+	Samples           int
+	AuthorizationRate float64
+	P95Latency        time.Duration
+}
+
+func NewStats(window time.Duration, maxSamples int) *Stats {
+	if window <= 0 {
+		window = defaultStatsWindow
+	}
+	if maxSamples <= 0 {
+		maxSamples = defaultStatsMaxSamples
+	}
+	return &Stats{
+		window:     window,
+		maxSamples: maxSamples,
+		samples:    make(map[gateway.PaymentGateway][]outcomeSample),
+		now:        time.Now,
+	}
+}
+
+// Record adds one outcome for gw.
+func (s *Stats) Record(gw gateway.PaymentGateway, authorized bool, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := append(s.prune(gw), outcomeSample{at: s.now(), authorized: authorized, latency: latency})
+	if len(samples) > s.maxSamples {
+		samples = samples[len(samples)-s.maxSamples:]
+	}
+	s.samples[gw] = samples
+}
+
+// Snapshot summarises gw's outcomes in the current window.
+func (s *Stats) Snapshot(gw gateway.PaymentGateway) Stat {
+	s.mu.Lock()
+	samples := s.prune(gw)
+	s.samples[gw] = samples
+	latencies := make([]time.Duration, len(samples))
+	authorized := 0
+	for i, sample := range samples {
+		latencies[i] = sample.latency
+		if sample.authorized {
+			authorized++
+		}
+	}
+	s.mu.Unlock()
+
+	if len(samples) == 0 {
+		return Stat{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	p95 := latencies[(len(latencies)*95+99)/100-1]
+	return Stat{
+		Samples:           len(samples),
+		AuthorizationRate: float64(authorized) / float64(len(samples)),
+		P95Latency:        p95,
+	}
+}
+
+// prune drops gw's samples that fell out of the window. Callers hold s.mu.
+func (s *Stats) prune(gw gateway.PaymentGateway) []outcomeSample {
+	samples := s.samples[gw]
+	cutoff := s.now().Add(-s.window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
@@ -0,0 +1,43 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats_Snapshot(t *testing.T) {
+	gw := &testGateway{"gw"}
+	stats := NewStats(time.Minute, 100)
+	for i := 1; i <= 20; i++ {
+		stats.Record(gw, i <= 15, time.Duration(i)*time.Millisecond)
+	}
+
+	got := stats.Snapshot(gw)
+	if got.Samples != 20 || got.AuthorizationRate != 0.75 || got.P95Latency != 19*time.Millisecond {
+		t.Errorf("unexpected snapshot: %+v", got)
+	}
+	if empty := stats.Snapshot(&testGateway{"other"}); empty != (Stat{}) {
+		t.Errorf("expected empty snapshot, got %+v", empty)
+	}
+}
+
+func TestStats_SlidingWindow(t *testing.T) {
+	gw := &testGateway{"gw"}
+	now := time.Now()
+	stats := NewStats(time.Minute, 3)
+	stats.now = func() time.Time { return now }
+
+	stats.Record(gw, false, time.Second)
+	now = now.Add(2 * time.Minute)
+	stats.Record(gw, true, time.Millisecond)
+	if got := stats.Snapshot(gw); got.Samples != 1 || got.AuthorizationRate != 1 {
+		t.Errorf("expected samples older than the window to be dropped, got %+v", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		stats.Record(gw, false, time.Millisecond)
+	}
+	if got := stats.Snapshot(gw); got.Samples != 3 || got.AuthorizationRate != 0 {
+		t.Errorf("expected only the newest maxSamples to be kept, got %+v", got)
+	}
+}
@@ -0,0 +1,235 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Routing strategy names used in configuration.
+const (
+	StrategyRoundRobin = "round_robin"
+	StrategyWeighted   = "weighted"
+	StrategyLowestCost = "lowest_cost"
+	StrategyAdaptive   = "adaptive"
+	StrategyPriority   = "priority"
+)
+
+// Request carries what rules and strategies may route on.
+type Request struct {
+	Type       constants.TransactionType
+	MerchantID string
+	Account    string
+	Amount     float64
+	Currency   string
+	Country    string
+}
+
+// Strategy picks one gateway among candidates, which are the pool's available
+// gateways in configuration order, or in the matched rule's order. candidates
+// is never empty.
+type Strategy interface {
+	Select(route Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway
+}
+
+// Table selects the strategy per merchant; merchants without their own
+// strategy use Default. Rules, when one matches, narrow the gateways the
+// strategy chooses from.
+type Table struct {
+	Default   Strategy
+	Merchants map[string]Strategy
+	Rules     []*Rule
+	// Canaries holds the gateways being rolled out gradually.
+	Canaries map[gateway.PaymentGateway]*Canary
+	// Stats receives every outcome reported to the pool; nil disables tracking.
+	Stats *Stats
+}
+
+// StrategyFor returns the strategy that routes merchantID's transactions.
+func (r Table) StrategyFor(merchantID string) Strategy {
+	if s, ok := r.Merchants[merchantID]; ok {
+		return s
+	}
+	return r.Default
+}
+
+// Options holds the per-gateway inputs strategies and rules are built from.
+type Options struct {
+	// Gateways maps configuration keys to gateways so rules can name them.
+	Gateways map[string]gateway.PaymentGateway
+	Weights  map[gateway.PaymentGateway]int
+	Fees     map[gateway.PaymentGateway]config.FeeSchedule
+	Stats    *Stats
+	Adaptive config.AdaptiveRoutingConfig
+}
+
+// AddGateway records gw under its configuration key, with its weight and fees.
+func (o *Options) AddGateway(name string, gw gateway.PaymentGateway, cfg config.GatewayRoutingConfig) {
+	if o.Gateways == nil {
+		o.Gateways = make(map[string]gateway.PaymentGateway)
+	}
+	if o.Weights == nil {
+		o.Weights = make(map[gateway.PaymentGateway]int)
+	}
+	if o.Fees == nil {
+		o.Fees = make(map[gateway.PaymentGateway]config.FeeSchedule)
+	}
+	o.Gateways[name] = gw
+	o.Weights[gw] = cfg.Weight
+	o.Fees[gw] = cfg.Fees
+}
+
+// NewStrategy builds the strategy registered under name.
+func NewStrategy(name string, opts Options) (Strategy, error) {
+	switch name {
+	case "", StrategyRoundRobin:
+		return NewRoundRobin(), nil
+	case StrategyWeighted:
+		return NewWeighted(opts.Weights), nil
+	case StrategyLowestCost:
+		return NewLowestCost(opts.Fees), nil
+	case StrategyAdaptive:
+		if opts.Stats == nil {
+			return nil, fmt.Errorf("routing strategy %s needs gateway stats", name)
+		}
+		return NewAdaptive(opts.Stats, opts.Adaptive.MinSamples, opts.Adaptive.RateTolerance), nil
+	case StrategyPriority:
+		return NewPriority(), nil
+	default:
+		return nil, fmt.Errorf("unknown routing strategy %q", name)
+	}
+}
+
+// roundRobinStrategy cycles through the candidates.
+type roundRobinStrategy struct {
+	mu   sync.Mutex
+	next int
+}
+
+func NewRoundRobin() Strategy {
+	return &roundRobinStrategy{}
+}
+
+func (s *roundRobinStrategy) Select(_ Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gw := candidates[s.next%len(candidates)]
+	s.next = (s.next + 1) % len(candidates)
+	return gw
+}
+
+// weightedStrategy spreads traffic in proportion to each gateway's weight
+// using smooth weighted round robin, so a 3:1 split interleaves as A A B A
+// rather than sending bursts to one gateway. Gateways without a positive
+// weight count as weight 1.
+type weightedStrategy struct {
+	mu      sync.Mutex
+	weights map[gateway.PaymentGateway]int
+	current map[gateway.PaymentGateway]int
+}
+
+func NewWeighted(weights map[gateway.PaymentGateway]int) Strategy {
+	return &weightedStrategy{weights: weights, current: make(map[gateway.PaymentGateway]int)}
+}
+
+func (s *weightedStrategy) Select(_ Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var best gateway.PaymentGateway
+	total := 0
+	for _, gw := range candidates {
+		weight := s.weights[gw]
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+		s.current[gw] += weight
+		if best == nil || s.current[gw] > s.current[best] {
+			best = gw
+		}
+	}
+	s.current[best] -= total
+	return best
+}
+
+// lowestCostStrategy picks the gateway with the smallest fee for the amount;
+// ties go to the gateway listed first.
+type lowestCostStrategy struct {
+	fees map[gateway.PaymentGateway]config.FeeSchedule
+}
+
+func NewLowestCost(fees map[gateway.PaymentGateway]config.FeeSchedule) Strategy {
+	return &lowestCostStrategy{fees: fees}
+}
+
+func (s *lowestCostStrategy) Select(route Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway {
+	best, bestFee := candidates[0], math.Inf(1)
+	for _, gw := range candidates {
+		if fee := s.fees[gw].Fee(route.Amount); fee < bestFee {
+			best, bestFee = gw, fee
+		}
+	}
+	return best
+}
+
+// adaptiveStrategy prefers the gateway with the best authorization rate over
+// the stats window, breaking near-ties (within rateTolerance) by lower p95
+// latency. Gateways with fewer than minSamples recent outcomes are tried
+// first so new or recovered gateways earn a track record.
+type adaptiveStrategy struct {
+	stats         *Stats
+	minSamples    int
+	rateTolerance float64
+}
+
+const (
+	defaultAdaptiveMinSamples    = 20
+	defaultAdaptiveRateTolerance = 0.02
+)
+
+func NewAdaptive(stats *Stats, minSamples int, rateTolerance float64) Strategy {
+	if minSamples <= 0 {
+		minSamples = defaultAdaptiveMinSamples
+	}
+	if rateTolerance <= 0 {
+		rateTolerance = defaultAdaptiveRateTolerance
+	}
+	return &adaptiveStrategy{stats: stats, minSamples: minSamples, rateTolerance: rateTolerance}
+}
+
+func (s *adaptiveStrategy) Select(_ Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway {
+	var best gateway.PaymentGateway
+	var bestStat Stat
+	for _, gw := range candidates {
+		stat := s.stats.Snapshot(gw)
+		if stat.Samples < s.minSamples {
+			return gw
+		}
+		if best == nil || s.better(stat, bestStat) {
+			best, bestStat = gw, stat
+		}
+	}
+	return best
+}
+
+func (s *adaptiveStrategy) better(a, b Stat) bool {
+	if diff := a.AuthorizationRate - b.AuthorizationRate; math.Abs(diff) > s.rateTolerance {
+		return diff > 0
+	}
+	return a.P95Latency < b.P95Latency
+}
+
+// priorityStrategy always picks the first candidate, so a rule's gateway list
+// reads as primary, then failover.
+type priorityStrategy struct{}
+
+func NewPriority() Strategy {
+	return priorityStrategy{}
+}
+
+func (priorityStrategy) Select(_ Request, candidates []gateway.PaymentGateway) gateway.PaymentGateway {
+	return candidates[0]
+}
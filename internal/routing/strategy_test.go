@@ -0,0 +1,127 @@
+package routing
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"context"
+	"testing"
+	"time"
+)
+
+// testGateway is a named PaymentGateway; it is not zero-sized, so distinct
+// instances compare unequal.
+type testGateway struct {
+	name string
+}
+
+func (g *testGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
+func (g *testGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
+
+func pick(s Strategy, route Request, candidates []gateway.PaymentGateway, n int) map[gateway.PaymentGateway]int {
+	counts := make(map[gateway.PaymentGateway]int)
+	for i := 0; i < n; i++ {
+		counts[s.Select(route, candidates)]++
+	}
+	return counts
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, b := &testGateway{"a"}, &testGateway{"b"}
+	s := NewRoundRobin()
+	candidates := []gateway.PaymentGateway{a, b}
+	if s.Select(Request{}, candidates) != a || s.Select(Request{}, candidates) != b || s.Select(Request{}, candidates) != a {
+		t.Error("expected candidates in turn")
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	a, b := &testGateway{"a"}, &testGateway{"b"}
+	s := NewWeighted(map[gateway.PaymentGateway]int{a: 3, b: 1})
+	candidates := []gateway.PaymentGateway{a, b}
+
+	counts := pick(s, Request{}, candidates, 8)
+	if counts[a] != 6 || counts[b] != 2 {
+		t.Errorf("expected a 3:1 split, got a=%d b=%d", counts[a], counts[b])
+	}
+	// Weights apply to the remaining candidates when one drops out
+	if got := s.Select(Request{}, []gateway.PaymentGateway{b}); got != b {
+		t.Error("expected the only candidate")
+	}
+}
+
+func TestWeighted_InterleavesSmoothly(t *testing.T) {
+	a, b := &testGateway{"a"}, &testGateway{"b"}
+	s := NewWeighted(map[gateway.PaymentGateway]int{a: 1, b: 1})
+	candidates := []gateway.PaymentGateway{a, b}
+	if s.Select(Request{}, candidates) == s.Select(Request{}, candidates) {
+		t.Error("expected equal weights to alternate")
+	}
+}
+
+func TestLowestCost(t *testing.T) {
+	flat, percent := &testGateway{"flat"}, &testGateway{"percent"}
+	s := NewLowestCost(map[gateway.PaymentGateway]config.FeeSchedule{
+		flat:    {Fixed: 1.00},
+		percent: {Percent: 2},
+	})
+	candidates := []gateway.PaymentGateway{flat, percent}
+
+	if got := s.Select(Request{Amount: 10}, candidates); got != percent {
+		t.Errorf("expected percentage fee to be cheaper for small amounts, got %s", got.(*testGateway).name)
+	}
+	if got := s.Select(Request{Amount: 1000}, candidates); got != flat {
+		t.Errorf("expected flat fee to be cheaper for large amounts, got %s", got.(*testGateway).name)
+	}
+	if got := s.Select(Request{Amount: 50}, candidates); got != flat {
+		t.Errorf("expected ties to go to the first candidate, got %s", got.(*testGateway).name)
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	fast, slow, flaky := &testGateway{"fast"}, &testGateway{"slow"}, &testGateway{"flaky"}
+	stats := NewStats(time.Minute, 100)
+	for i := 0; i < 10; i++ {
+		stats.Record(fast, true, 50*time.Millisecond)
+		stats.Record(slow, true, 500*time.Millisecond)
+		stats.Record(flaky, i%2 == 0, 10*time.Millisecond)
+	}
+	s := NewAdaptive(stats, 10, 0.02)
+
+	if got := s.Select(Request{}, []gateway.PaymentGateway{flaky, slow, fast}); got != fast {
+		t.Errorf("expected best rate then lowest p95, got %s", got.(*testGateway).name)
+	}
+	if got := s.Select(Request{}, []gateway.PaymentGateway{flaky, slow}); got != slow {
+		t.Errorf("expected authorization rate to outweigh latency, got %s", got.(*testGateway).name)
+	}
+
+	fresh := &testGateway{"fresh"}
+	if got := s.Select(Request{}, []gateway.PaymentGateway{fast, fresh}); got != fresh {
+		t.Errorf("expected a gateway without enough samples to be explored, got %s", got.(*testGateway).name)
+	}
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range []string{"", StrategyRoundRobin, StrategyWeighted, StrategyLowestCost, StrategyAdaptive} {
+		if _, err := NewStrategy(name, Options{Stats: NewStats(0, 0)}); err != nil {
+			t.Errorf("NewStrategy(%q): unexpected error %v", name, err)
+		}
+	}
+	if _, err := NewStrategy("fastest", Options{}); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+	if _, err := NewStrategy(StrategyAdaptive, Options{}); err == nil {
+		t.Error("expected error for adaptive strategy without stats")
+	}
+}
+
+func TestTable_StrategyFor(t *testing.T) {
+	def, own := NewRoundRobin(), NewRoundRobin()
+	table := Table{Default: def, Merchants: map[string]Strategy{"m1": own}}
+	if table.StrategyFor("m1") != own || table.StrategyFor("m2") != def {
+		t.Error("expected merchant strategy with default fallback")
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"Payment-Gateway/internal/dtos"
+	errors "Payment-Gateway/pkg/error"
+	"time"
+)
+
+// checkCallbackTimestamp parses the callback timestamp and rejects callbacks
+// whose timestamp is further than tolerance away from now. A non-positive
+// tolerance disables the window check and allows callbacks without a timestamp.
+func checkCallbackTimestamp(req dtos.HandleCallbackRequest, tolerance time.Duration, now time.Time) (time.Time, error) {
+	if tolerance <= 0 && req.Timestamp == "" {
+		return time.Time{}, nil
+	}
+	ts, err := req.ParseTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if tolerance > 0 {
+		skew := now.Sub(ts)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > tolerance {
+			return time.Time{}, errors.ErrCallbackOutsideWindow
+		}
+	}
+	return ts, nil
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"Payment-Gateway/internal/gateway"
-	"sync"
+	"Payment-Gateway/internal/routing"
+	"slices"
+	"strings"
 
 	errors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/logger"
@@ -11,15 +13,22 @@ import (
 )
 
 type GatewayPoolImpl struct {
-	gateways []gateway.PaymentGateway
-	mu       sync.Mutex
-	rrIndex  int
+	gateways    []gateway.PaymentGateway
+	health      GatewayHealth
+	maintenance *GatewayMaintenance
+	routing     routing.Table
 }
 
-func NewGatewayPool(gateways []gateway.PaymentGateway) GatewayPool {
+// NewGatewayPool builds a pool over gateways. health and maintenance may be
+// nil, in which case only circuit breaker state takes gateways out of
+// rotation; a zero table sends every merchant's traffic round robin.
+func NewGatewayPool(gateways []gateway.PaymentGateway, health GatewayHealth, maintenance *GatewayMaintenance, table routing.Table) GatewayPool {
 	log := logger.GetLogger().With(zap.String("func", "NewGatewayPool"))
 	log.Info("Initializing GatewayPool", zap.Int("num_gateways", len(gateways)))
-	return &GatewayPoolImpl{gateways: gateways}
+	if table.Default == nil {
+		table.Default = routing.NewRoundRobin()
+	}
+	return &GatewayPoolImpl{gateways: gateways, health: health, maintenance: maintenance, routing: table}
 }
 
 func (gp *GatewayPoolImpl) GetAllGateways() ([]gateway.PaymentGateway, error) {
@@ -32,16 +41,137 @@ func (gp *GatewayPoolImpl) GetAllGateways() ([]gateway.PaymentGateway, error) {
 	return gp.gateways, nil
 }
 
-func (gp *GatewayPoolImpl) GetRoundRobinGateway() (gateway.PaymentGateway, error) {
-	log := logger.GetLogger().With(zap.String("func", "GatewayPoolImpl.GetRoundRobinGateway"))
-	gp.mu.Lock()
-	defer gp.mu.Unlock()
+// GetGateway picks a gateway for route with the merchant's routing strategy,
+// skipping gateways that failed their health checks or whose breaker is open.
+// When a routing rule matches route, only the rule's gateways are considered.
+// The returned gateway counts as in flight until RecordOutcome is called for it.
+func (gp *GatewayPoolImpl) GetGateway(route routing.Request) (gateway.PaymentGateway, error) {
+	return gp.selectGateway("GatewayPoolImpl.GetGateway", route, nil)
+}
+
+// GetFailoverGateway picks the merchant strategy's choice among the available
+// gateways that are not in tried.
+func (gp *GatewayPoolImpl) GetFailoverGateway(route routing.Request, tried []gateway.PaymentGateway) (gateway.PaymentGateway, error) {
+	return gp.selectGateway("GatewayPoolImpl.GetFailoverGateway", route, tried)
+}
+
+// RecordOutcome ends a call to gw and feeds it to the routing stats and, for
+// a canary gateway, to its rollback check.
+func (gp *GatewayPoolImpl) RecordOutcome(gw gateway.PaymentGateway, outcome routing.Outcome) {
+	if gp.maintenance != nil {
+		gp.maintenance.End(gw)
+	}
+	if gp.routing.Stats != nil {
+		gp.routing.Stats.Record(gw, outcome.Authorized, outcome.Latency)
+	}
+	if c, ok := gp.routing.Canaries[gw]; ok && c.Record(outcome.Failed) {
+		status := c.Status()
+		logger.GetLogger().Warn("Canary gateway rolled back: error rate above threshold",
+			zap.String("func", "GatewayPoolImpl.RecordOutcome"),
+			zap.String("gateway", c.Name),
+			zap.Float64("error_rate", status.ErrorRate),
+			zap.Float64("max_error_rate", status.MaxErrorRate),
+			zap.Int("samples", status.Samples),
+		)
+	}
+}
+
+// Canaries reports the state of every canary gateway, ordered by name.
+func (gp *GatewayPoolImpl) Canaries() []routing.CanaryStatus {
+	statuses := make([]routing.CanaryStatus, 0, len(gp.routing.Canaries))
+	for _, c := range gp.routing.Canaries {
+		statuses = append(statuses, c.Status())
+	}
+	slices.SortFunc(statuses, func(a, b routing.CanaryStatus) int { return strings.Compare(a.Gateway, b.Gateway) })
+	return statuses
+}
+
+// ResetCanary puts a rolled back canary back into rotation.
+func (gp *GatewayPoolImpl) ResetCanary(name string) error {
+	for _, c := range gp.routing.Canaries {
+		if c.Name == name {
+			c.Reset()
+			logger.GetLogger().Info("Canary gateway reset", zap.String("func", "GatewayPoolImpl.ResetCanary"), zap.String("gateway", name))
+			return nil
+		}
+	}
+	return errors.WithMessage(errors.ErrGatewayNotFound, "no canary gateway named "+name)
+}
+
+func (gp *GatewayPoolImpl) selectGateway(funcName string, route routing.Request, tried []gateway.PaymentGateway) (gateway.PaymentGateway, error) {
+	log := logger.GetLogger().With(zap.String("func", funcName), zap.String("merchant_id", route.MerchantID))
 	if len(gp.gateways) == 0 {
 		log.Warn("No gateways available")
 		return nil, errors.ErrNoGatewayAvailable
 	}
-	gateway := gp.gateways[gp.rrIndex%len(gp.gateways)]
-	log.Info("Selected gateway", zap.Int("index", gp.rrIndex%len(gp.gateways)))
-	gp.rrIndex = (gp.rrIndex + 1) % len(gp.gateways)
-	return gateway, nil
+	eligible, strategy := gp.gateways, gp.routing.StrategyFor(route.MerchantID)
+	if rule := gp.routing.RuleFor(route); rule != nil {
+		log = log.With(zap.String("routing_rule", rule.Name))
+		eligible = rule.Gateways
+		if rule.Strategy != nil {
+			strategy = rule.Strategy
+		}
+	}
+	candidates := make([]gateway.PaymentGateway, 0, len(eligible))
+	for _, gw := range eligible {
+		if slices.Contains(tried, gw) || gp.unavailableReason(gw) != "" {
+			continue
+		}
+		candidates = append(candidates, gw)
+	}
+	if len(candidates) == 0 {
+		log.Warn("No eligible gateway: all are tried, unhealthy or have an open circuit breaker", zap.Int("tried", len(tried)))
+		return nil, errors.ErrNoGatewayAvailable
+	}
+	gw, canary := gp.routing.SelectWithCanaries(strategy, route, candidates)
+	if gw == nil {
+		log.Warn("No eligible gateway: only canaries that were not admitted remain")
+		return nil, errors.ErrNoGatewayAvailable
+	}
+	if gp.maintenance != nil {
+		gp.maintenance.Begin(gw)
+	}
+	log.Info("Selected gateway", zap.String("gateway", gateway.NameOf(gw)), zap.Int("candidates", len(candidates)), zap.Bool("canary", canary != nil))
+	return gw, nil
+}
+
+// Explain reports which routing rule route matches and the state of each
+// candidate gateway, without selecting one.
+func (gp *GatewayPoolImpl) Explain(route routing.Request) routing.Explanation {
+	rule, results := gp.routing.EvaluateRules(route)
+	exp := routing.Explanation{Rules: results}
+	eligible := gp.gateways
+	if rule != nil {
+		exp.Rule, exp.Strategy = rule.Name, rule.StrategyName
+		eligible = rule.Gateways
+	}
+	for _, gw := range eligible {
+		reason := gp.unavailableReason(gw)
+		exp.Candidates = append(exp.Candidates, routing.Candidate{
+			Gateway:   gateway.NameOf(gw),
+			Available: reason == "",
+			Reason:    reason,
+		})
+	}
+	return exp
+}
+
+// unavailableReason combines operator and scheduled maintenance, active health
+// checks and the gateway's breaker state; it is empty when gw may be routed to.
+func (gp *GatewayPoolImpl) unavailableReason(gw gateway.PaymentGateway) string {
+	if gp.maintenance != nil {
+		if reason := gp.maintenance.UnavailableReason(gw); reason != "" {
+			return reason
+		}
+	}
+	if gp.health != nil && !gp.health.Healthy(gw) {
+		return "failing health checks"
+	}
+	if !gateway.IsAvailable(gw) {
+		return "circuit breaker open"
+	}
+	if c, ok := gp.routing.Canaries[gw]; ok && c.RolledBack() {
+		return "canary rolled back"
+	}
+	return ""
 }
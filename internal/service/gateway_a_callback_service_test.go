@@ -3,9 +3,11 @@ package service
 import (
 	"Payment-Gateway/internal/constants"
 	"Payment-Gateway/internal/dtos"
+	apperrors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/mocks"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 )
@@ -16,10 +18,10 @@ func TestGatewayACallbackService_HandleCallback_Success(t *testing.T) {
 
 	mockTx := mocks.NewMockTransaction(ctrl)
 	mockTx.EXPECT().
-		UpdateStatus("tx1", constants.StatusSuccess).
-		Return(nil)
+		ApplyCallbackStatus("tx1", constants.StatusSuccess, time.Time{}).
+		Return(true, nil)
 
-	svc := NewGatewayACallbackService(mockTx)
+	svc := NewGatewayACallbackService(mockTx, 0)
 	req := dtos.HandleCallbackRequest{
 		TransactionID: "tx1",
 		Status:        string(constants.StatusSuccess),
@@ -42,7 +44,7 @@ func TestGatewayACallbackService_HandleCallback_ValidationError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockTx := mocks.NewMockTransaction(ctrl)
-	svc := NewGatewayACallbackService(mockTx)
+	svc := NewGatewayACallbackService(mockTx, 0)
 	req := dtos.HandleCallbackRequest{} // missing required fields
 
 	_, err := svc.HandleCallback(req)
@@ -57,10 +59,10 @@ func TestGatewayACallbackService_HandleCallback_UpdateStatusError(t *testing.T)
 
 	mockTx := mocks.NewMockTransaction(ctrl)
 	mockTx.EXPECT().
-		UpdateStatus("tx1", constants.StatusFailed).
-		Return(errors.New("update error"))
+		ApplyCallbackStatus("tx1", constants.StatusFailed, time.Time{}).
+		Return(false, errors.New("update error"))
 
-	svc := NewGatewayACallbackService(mockTx)
+	svc := NewGatewayACallbackService(mockTx, 0)
 	req := dtos.HandleCallbackRequest{
 		TransactionID: "tx1",
 		Status:        string(constants.StatusFailed),
@@ -74,3 +76,73 @@ func TestGatewayACallbackService_HandleCallback_UpdateStatusError(t *testing.T)
 		t.Fatal("expected update error, got nil")
 	}
 }
+
+func TestGatewayACallbackService_HandleCallback_OutsideWindow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := mocks.NewMockTransaction(ctrl)
+	svc := NewGatewayACallbackService(mockTx, 5*time.Minute)
+	req := dtos.HandleCallbackRequest{
+		TransactionID: "tx1",
+		Status:        string(constants.StatusSuccess),
+		GatewayRef:    "ref1",
+		Amount:        100,
+		Currency:      "USD",
+		Timestamp:     time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}
+
+	_, err := svc.HandleCallback(req)
+	if !errors.Is(err, apperrors.ErrCallbackOutsideWindow) {
+		t.Fatalf("expected ErrCallbackOutsideWindow, got %v", err)
+	}
+}
+
+func TestGatewayACallbackService_HandleCallback_MissingTimestamp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTx := mocks.NewMockTransaction(ctrl)
+	svc := NewGatewayACallbackService(mockTx, 5*time.Minute)
+	req := dtos.HandleCallbackRequest{
+		TransactionID: "tx1",
+		Status:        string(constants.StatusSuccess),
+		GatewayRef:    "ref1",
+		Amount:        100,
+		Currency:      "USD",
+	}
+
+	_, err := svc.HandleCallback(req)
+	if !errors.Is(err, apperrors.ErrMissingCallbackTimestamp) {
+		t.Fatalf("expected ErrMissingCallbackTimestamp, got %v", err)
+	}
+}
+
+func TestGatewayACallbackService_HandleCallback_StaleIgnored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	callbackAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	mockTx := mocks.NewMockTransaction(ctrl)
+	mockTx.EXPECT().
+		ApplyCallbackStatus("tx1", constants.StatusFailed, callbackAt).
+		Return(false, nil)
+
+	svc := NewGatewayACallbackService(mockTx, 5*time.Minute)
+	req := dtos.HandleCallbackRequest{
+		TransactionID: "tx1",
+		Status:        string(constants.StatusFailed),
+		GatewayRef:    "ref1",
+		Amount:        100,
+		Currency:      "USD",
+		Timestamp:     callbackAt.Format(time.RFC3339),
+	}
+
+	resp, err := svc.HandleCallback(req)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if resp.Status != "ignored" {
+		t.Errorf("expected status ignored, got %s", resp.Status)
+	}
+}
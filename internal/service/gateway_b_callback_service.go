@@ -5,17 +5,20 @@ import (
 	"Payment-Gateway/internal/dtos"
 	"Payment-Gateway/pkg/logger"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type GatewayBCallbackService struct {
 	transactionService Transaction
+	timestampTolerance time.Duration // zero disables the replay window check
 }
 
-func NewGatewayBCallbackService(transactionService Transaction) Callback {
+func NewGatewayBCallbackService(transactionService Transaction, timestampTolerance time.Duration) Callback {
 	return &GatewayBCallbackService{
 		transactionService: transactionService,
+		timestampTolerance: timestampTolerance,
 	}
 }
 
@@ -33,11 +36,25 @@ func (g *GatewayBCallbackService) HandleCallback(req dtos.HandleCallbackRequest)
 		return nil, err
 	}
 
+	callbackAt, err := checkCallbackTimestamp(req, g.timestampTolerance, time.Now())
+	if err != nil {
+		log.Warn("Rejected callback timestamp", zap.String("timestamp", req.Timestamp), zap.Error(err))
+		return nil, err
+	}
+
 	status := constants.TransactionStatus(req.Status)
-	if err := g.transactionService.UpdateStatus(req.TransactionID, status); err != nil {
+	applied, err := g.transactionService.ApplyCallbackStatus(req.TransactionID, status, callbackAt)
+	if err != nil {
 		log.Error("Failed to update transaction status", zap.Error(err))
 		return nil, fmt.Errorf("failed to update transaction status: %w", err)
 	}
+	if !applied {
+		log.Info("Ignored stale GatewayB callback")
+		return &dtos.HandleCallbackResponse{
+			Status:  "ignored",
+			Message: fmt.Sprintf("Ignored stale callback for transaction: %s", req.TransactionID),
+		}, nil
+	}
 
 	log.Info("GatewayB callback processed successfully")
 	return &dtos.HandleCallbackResponse{
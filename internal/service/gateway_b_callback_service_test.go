@@ -6,6 +6,7 @@ import (
 	"Payment-Gateway/pkg/mocks"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 )
@@ -16,8 +17,8 @@ func TestGatewayBCallbackService_HandleCallback_Success(t *testing.T) {
 
 	mockTx := mocks.NewMockTransaction(ctrl)
 	mockTx.EXPECT().
-		UpdateStatus("tx2", constants.StatusSuccess).
-		Return(nil)
+		ApplyCallbackStatus("tx2", constants.StatusSuccess, time.Time{}).
+		Return(true, nil)
 
 	svc := &GatewayBCallbackService{transactionService: mockTx}
 	req := dtos.HandleCallbackRequest{
@@ -57,8 +58,8 @@ func TestGatewayBCallbackService_HandleCallback_UpdateStatusError(t *testing.T)
 
 	mockTx := mocks.NewMockTransaction(ctrl)
 	mockTx.EXPECT().
-		UpdateStatus("tx2", constants.StatusFailed).
-		Return(errors.New("update error"))
+		ApplyCallbackStatus("tx2", constants.StatusFailed, time.Time{}).
+		Return(false, errors.New("update error"))
 
 	svc := &GatewayBCallbackService{transactionService: mockTx}
 	req := dtos.HandleCallbackRequest{
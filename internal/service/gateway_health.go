@@ -0,0 +1,156 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHealthInterval     = 10 * time.Second
+	defaultHealthTimeout      = 2 * time.Second
+	defaultHealthyThreshold   = 2
+	defaultUnhealthyThreshold = 3
+)
+
+// HealthMonitor probes gateways periodically and takes a gateway out of
+// rotation after UnhealthyThreshold consecutive failed probes. It is put back
+// after HealthyThreshold consecutive successful probes. Gateways are healthy
+// until proven otherwise, and gateways that are not watched are always healthy.
+type HealthMonitor struct {
+	mu      sync.RWMutex
+	targets map[gateway.PaymentGateway]*healthTarget
+	// ctx is cancelled by Stop, ending the probe loops and probes in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+	loops  sync.WaitGroup
+}
+
+type healthTarget struct {
+	name               string
+	probe              gateway.Probe
+	interval           time.Duration
+	timeout            time.Duration
+	healthyThreshold   int
+	unhealthyThreshold int
+
+	healthy   bool
+	successes int
+	failures  int
+}
+
+func NewHealthMonitor() *HealthMonitor {
+	m := &HealthMonitor{targets: make(map[gateway.PaymentGateway]*healthTarget)}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	return m
+}
+
+// Watch registers gw to be probed with probe once Start is called.
+func (m *HealthMonitor) Watch(name string, gw gateway.PaymentGateway, probe gateway.Probe, cfg config.HealthCheckConfig) {
+	t := &healthTarget{
+		name:               name,
+		probe:              probe,
+		interval:           time.Duration(cfg.IntervalSeconds) * time.Second,
+		timeout:            time.Duration(cfg.TimeoutSeconds) * time.Second,
+		healthyThreshold:   cfg.HealthyThreshold,
+		unhealthyThreshold: cfg.UnhealthyThreshold,
+		healthy:            true,
+	}
+	if t.interval <= 0 {
+		t.interval = defaultHealthInterval
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultHealthTimeout
+	}
+	if t.healthyThreshold <= 0 {
+		t.healthyThreshold = defaultHealthyThreshold
+	}
+	if t.unhealthyThreshold <= 0 {
+		t.unhealthyThreshold = defaultUnhealthyThreshold
+	}
+
+	m.mu.Lock()
+	m.targets[gw] = t
+	m.mu.Unlock()
+}
+
+// Start launches one probe loop per watched gateway and returns Stop, to be
+// called before the gateways are closed.
+func (m *HealthMonitor) Start() func() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for gw, t := range m.targets {
+		m.loops.Add(1)
+		go m.run(gw, t.interval)
+	}
+	return m.Stop
+}
+
+// Stop cancels the probes in flight and waits for all probe loops to end.
+func (m *HealthMonitor) Stop() {
+	m.cancel()
+	m.loops.Wait()
+}
+
+// Healthy reports whether gw passed its recent health checks.
+func (m *HealthMonitor) Healthy(gw gateway.PaymentGateway) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.targets[gw]
+	return !ok || t.healthy
+}
+
+func (m *HealthMonitor) run(gw gateway.PaymentGateway, interval time.Duration) {
+	defer m.loops.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.check(gw)
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// check probes gw once and updates its health state.
+func (m *HealthMonitor) check(gw gateway.PaymentGateway) {
+	m.mu.RLock()
+	t, ok := m.targets[gw]
+	m.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, t.timeout)
+	err := t.probe(ctx)
+	cancel()
+	if m.ctx.Err() != nil {
+		return // stopped mid-probe; the failure says nothing about the gateway
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	log := logger.GetLogger().With(zap.String("func", "HealthMonitor.check"), zap.String("gateway", t.name))
+	if err != nil {
+		t.successes = 0
+		t.failures++
+		if t.healthy && t.failures >= t.unhealthyThreshold {
+			t.healthy = false
+			log.Warn("Gateway marked unhealthy, removing from rotation", zap.Int("failures", t.failures), zap.Error(err))
+		}
+		return
+	}
+	t.failures = 0
+	t.successes++
+	if !t.healthy && t.successes >= t.healthyThreshold {
+		t.healthy = true
+		log.Info("Gateway healthy again, returning to rotation", zap.Int("successes", t.successes))
+	}
+}
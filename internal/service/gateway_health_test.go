@@ -0,0 +1,97 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor_RemovesAndReinstatesGateway(t *testing.T) {
+	gw := &dummyGateway{}
+	var probeErr error
+	monitor := NewHealthMonitor()
+	monitor.Watch("gw", gw, func(ctx context.Context) error { return probeErr }, config.HealthCheckConfig{
+		HealthyThreshold:   2,
+		UnhealthyThreshold: 2,
+	})
+
+	if !monitor.Healthy(gw) {
+		t.Fatal("expected gateway to start healthy")
+	}
+
+	probeErr = errors.New("down")
+	monitor.check(gw)
+	if !monitor.Healthy(gw) {
+		t.Error("expected gateway to stay healthy after a single failed probe")
+	}
+	monitor.check(gw)
+	if monitor.Healthy(gw) {
+		t.Error("expected gateway to be unhealthy after consecutive failed probes")
+	}
+
+	probeErr = nil
+	monitor.check(gw)
+	if monitor.Healthy(gw) {
+		t.Error("expected gateway to stay unhealthy after a single successful probe")
+	}
+	probeErr = errors.New("flap")
+	monitor.check(gw)
+	probeErr = nil
+	monitor.check(gw)
+	if monitor.Healthy(gw) {
+		t.Error("expected a failed probe to reset the success streak")
+	}
+	monitor.check(gw)
+	if !monitor.Healthy(gw) {
+		t.Error("expected gateway to be reinstated after consecutive successful probes")
+	}
+}
+
+func TestHealthMonitor_UnwatchedGatewayIsHealthy(t *testing.T) {
+	monitor := NewHealthMonitor()
+	if !monitor.Healthy(&dummyGateway{}) {
+		t.Error("expected unwatched gateway to be healthy")
+	}
+}
+
+func TestHealthMonitor_StopEndsProbes(t *testing.T) {
+	gw := &dummyGateway{}
+	var probes atomic.Int32
+	started := make(chan struct{}, 1)
+	monitor := NewHealthMonitor()
+	monitor.Watch("gw", gw, func(ctx context.Context) error {
+		probes.Add(1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done() // hangs until cancelled
+		return ctx.Err()
+	}, config.HealthCheckConfig{TimeoutSeconds: 60, UnhealthyThreshold: 1})
+	monitor.targets[gw].interval = time.Millisecond
+
+	stop := monitor.Start()
+	<-started
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected stop to cancel the probe in flight and return")
+	}
+
+	n := probes.Load()
+	time.Sleep(20 * time.Millisecond)
+	if probes.Load() != n {
+		t.Error("expected no probes after stop returned")
+	}
+	if !monitor.Healthy(gw) {
+		t.Error("expected a probe cancelled by stop not to count as a failure")
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/models"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// GatewayMaintenance takes gateways out of rotation during their scheduled
+// maintenance windows and when an operator drains or disables them. Calls
+// already in flight are never interrupted; a draining gateway becomes
+// disabled once its last in-flight call has finished.
+type GatewayMaintenance struct {
+	mu        sync.Mutex
+	targets   map[string]*maintenanceTarget
+	byGateway map[gateway.PaymentGateway]*maintenanceTarget
+	now       func() time.Time
+}
+
+type maintenanceTarget struct {
+	name      string
+	state     constants.GatewayState
+	reason    string
+	changedAt time.Time
+	inFlight  int
+	windows   []models.MaintenanceWindow
+	// skipUntil ends a window early: windows ending by then are ignored.
+	skipUntil time.Time
+}
+
+func NewGatewayMaintenance() *GatewayMaintenance {
+	return &GatewayMaintenance{
+		targets:   make(map[string]*maintenanceTarget),
+		byGateway: make(map[gateway.PaymentGateway]*maintenanceTarget),
+		now:       time.Now,
+	}
+}
+
+// Add registers gw under its configuration key with its maintenance windows.
+func (m *GatewayMaintenance) Add(name string, gw gateway.PaymentGateway, windows []config.MaintenanceWindowConfig) error {
+	t := &maintenanceTarget{name: name, state: constants.GatewayEnabled}
+	for i, w := range windows {
+		start, err := time.Parse(time.RFC3339, w.Start)
+		if err != nil {
+			return fmt.Errorf("gateway %s: maintenance window %d: invalid start: %w", name, i+1, err)
+		}
+		end, err := time.Parse(time.RFC3339, w.End)
+		if err != nil {
+			return fmt.Errorf("gateway %s: maintenance window %d: invalid end: %w", name, i+1, err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("gateway %s: maintenance window %d ends before it starts", name, i+1)
+		}
+		t.windows = append(t.windows, models.MaintenanceWindow{Start: start, End: end, Reason: w.Reason})
+	}
+	sort.Slice(t.windows, func(i, j int) bool { return t.windows[i].Start.Before(t.windows[j].Start) })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targets[name] = t
+	m.byGateway[gw] = t
+	return nil
+}
+
+// UnavailableReason says why gw must not receive new transactions, or is
+// empty when it may.
+func (m *GatewayMaintenance) UnavailableReason(gw gateway.PaymentGateway) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.byGateway[gw]
+	if !ok {
+		return ""
+	}
+	switch t.state {
+	case constants.GatewayDraining:
+		return "draining"
+	case constants.GatewayDisabled:
+		return "disabled"
+	}
+	if w := t.activeWindow(m.now()); w != nil {
+		return "in maintenance window until " + w.End.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+// Begin counts a call to gw as in flight.
+func (m *GatewayMaintenance) Begin(gw gateway.PaymentGateway) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.byGateway[gw]; ok {
+		t.inFlight++
+	}
+}
+
+// End marks a call to gw as finished, completing a drain after the last one.
+func (m *GatewayMaintenance) End(gw gateway.PaymentGateway) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.byGateway[gw]
+	if !ok || t.inFlight == 0 {
+		return
+	}
+	t.inFlight--
+	if t.state == constants.GatewayDraining && t.inFlight == 0 {
+		t.setState(constants.GatewayDisabled, t.reason, m.now())
+		logger.GetLogger().Info("Gateway drained and disabled",
+			zap.String("func", "GatewayMaintenance.End"), zap.String("gateway", t.name))
+	}
+}
+
+// Drain stops new transactions to the gateway and disables it once its
+// in-flight calls have finished.
+func (m *GatewayMaintenance) Drain(name, reason string) (models.GatewayStatus, error) {
+	return m.transition(name, func(t *maintenanceTarget, now time.Time) {
+		if t.state == constants.GatewayDisabled {
+			return
+		}
+		next := constants.GatewayDraining
+		if t.inFlight == 0 {
+			next = constants.GatewayDisabled
+		}
+		t.setState(next, reason, now)
+	})
+}
+
+// Disable stops new transactions to the gateway immediately; calls already
+// in flight still finish.
+func (m *GatewayMaintenance) Disable(name, reason string) (models.GatewayStatus, error) {
+	return m.transition(name, func(t *maintenanceTarget, now time.Time) {
+		t.setState(constants.GatewayDisabled, reason, now)
+	})
+}
+
+// Enable returns the gateway to rotation, ending any maintenance window in
+// progress early.
+func (m *GatewayMaintenance) Enable(name string) (models.GatewayStatus, error) {
+	return m.transition(name, func(t *maintenanceTarget, now time.Time) {
+		if w := t.activeWindow(now); w != nil {
+			t.skipUntil = w.End
+		}
+		t.setState(constants.GatewayEnabled, "", now)
+	})
+}
+
+// Status reports one gateway by configuration key.
+func (m *GatewayMaintenance) Status(name string) (models.GatewayStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.targets[name]
+	if !ok {
+		return models.GatewayStatus{}, errors.WithMessage(errors.ErrGatewayNotFound, "no gateway named "+name)
+	}
+	return t.status(m.now()), nil
+}
+
+// Statuses reports every gateway, ordered by configuration key.
+func (m *GatewayMaintenance) Statuses() []models.GatewayStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	statuses := make([]models.GatewayStatus, 0, len(m.targets))
+	for _, t := range m.targets {
+		statuses = append(statuses, t.status(now))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Gateway < statuses[j].Gateway })
+	return statuses
+}
+
+func (m *GatewayMaintenance) transition(name string, apply func(t *maintenanceTarget, now time.Time)) (models.GatewayStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.targets[name]
+	if !ok {
+		return models.GatewayStatus{}, errors.WithMessage(errors.ErrGatewayNotFound, "no gateway named "+name)
+	}
+	now := m.now()
+	apply(t, now)
+	logger.GetLogger().Info("Gateway state changed",
+		zap.String("func", "GatewayMaintenance.transition"),
+		zap.String("gateway", name),
+		zap.String("state", string(t.state)),
+		zap.String("reason", t.reason),
+		zap.Int("in_flight", t.inFlight),
+	)
+	return t.status(now), nil
+}
+
+func (t *maintenanceTarget) setState(state constants.GatewayState, reason string, now time.Time) {
+	t.state, t.reason, t.changedAt = state, reason, now
+}
+
+// activeWindow returns the window now falls in, if any.
+func (t *maintenanceTarget) activeWindow(now time.Time) *models.MaintenanceWindow {
+	for i := range t.windows {
+		w := &t.windows[i]
+		if !now.Before(w.Start) && now.Before(w.End) && w.End.After(t.skipUntil) {
+			return w
+		}
+	}
+	return nil
+}
+
+func (t *maintenanceTarget) status(now time.Time) models.GatewayStatus {
+	s := models.GatewayStatus{
+		Gateway:  t.name,
+		State:    t.state,
+		Reason:   t.reason,
+		InFlight: t.inFlight,
+	}
+	if !t.changedAt.IsZero() {
+		at := t.changedAt
+		s.ChangedAt = &at
+	}
+	if w := t.activeWindow(now); w != nil {
+		active := *w
+		s.ActiveWindow = &active
+	}
+	for _, w := range t.windows {
+		if w.Start.After(now) {
+			s.UpcomingWindows = append(s.UpcomingWindows, w)
+		}
+	}
+	s.Routable = t.state == constants.GatewayEnabled && s.ActiveWindow == nil
+	return s
+}
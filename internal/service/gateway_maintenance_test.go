@@ -0,0 +1,148 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/routing"
+	apperrors "Payment-Gateway/pkg/error"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGatewayMaintenance_DrainWaitsForInFlightCalls(t *testing.T) {
+	gw := &breakerGateway{available: true}
+	m := NewGatewayMaintenance()
+	if err := m.Add("gatewayA", gw, nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	m.Begin(gw)
+	m.Begin(gw)
+	status, err := m.Drain("gatewayA", "provider upgrade")
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if status.State != constants.GatewayDraining || status.InFlight != 2 || status.Routable {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if m.UnavailableReason(gw) == "" {
+		t.Error("expected a draining gateway to take no new transactions")
+	}
+
+	m.End(gw)
+	if status, _ := m.Status("gatewayA"); status.State != constants.GatewayDraining {
+		t.Errorf("expected draining while a call is in flight, got %s", status.State)
+	}
+	m.End(gw)
+	status, _ = m.Status("gatewayA")
+	if status.State != constants.GatewayDisabled || status.Reason != "provider upgrade" {
+		t.Errorf("expected disabled after the last call, got %+v", status)
+	}
+
+	if status, _ := m.Enable("gatewayA"); status.State != constants.GatewayEnabled || !status.Routable {
+		t.Errorf("expected enabled, got %+v", status)
+	}
+	if m.UnavailableReason(gw) != "" {
+		t.Error("expected an enabled gateway to be routable")
+	}
+}
+
+func TestGatewayMaintenance_DrainWithoutInFlightDisables(t *testing.T) {
+	m := NewGatewayMaintenance()
+	m.Add("gatewayA", &breakerGateway{}, nil)
+	if status, _ := m.Drain("gatewayA", ""); status.State != constants.GatewayDisabled {
+		t.Errorf("expected disabled, got %s", status.State)
+	}
+}
+
+func TestGatewayMaintenance_UnknownGateway(t *testing.T) {
+	m := NewGatewayMaintenance()
+	if _, err := m.Disable("nope", ""); !errors.Is(err, apperrors.ErrGatewayNotFound) {
+		t.Errorf("expected ErrGatewayNotFound, got %v", err)
+	}
+}
+
+func TestGatewayMaintenance_ScheduledWindow(t *testing.T) {
+	gw := &breakerGateway{available: true}
+	m := NewGatewayMaintenance()
+	err := m.Add("gatewayA", gw, []config.MaintenanceWindowConfig{
+		{Start: "2025-01-01T02:00:00Z", End: "2025-01-01T04:00:00Z", Reason: "database upgrade"},
+		{Start: "2025-02-01T02:00:00Z", End: "2025-02-01T03:00:00Z"},
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 1, 59, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	if status, _ := m.Status("gatewayA"); !status.Routable || len(status.UpcomingWindows) != 2 {
+		t.Errorf("expected routable with two upcoming windows, got %+v", status)
+	}
+
+	now = now.Add(time.Minute)
+	status, _ := m.Status("gatewayA")
+	if status.Routable || status.ActiveWindow == nil || status.ActiveWindow.Reason != "database upgrade" {
+		t.Errorf("expected the window to be active, got %+v", status)
+	}
+	if m.UnavailableReason(gw) == "" {
+		t.Error("expected no transactions during the window")
+	}
+
+	// Enabling ends the window early
+	m.Enable("gatewayA")
+	if m.UnavailableReason(gw) != "" {
+		t.Error("expected enable to end the window in progress")
+	}
+
+	now = time.Date(2025, 2, 1, 2, 30, 0, 0, time.UTC)
+	if m.UnavailableReason(gw) == "" {
+		t.Error("expected later windows to still apply")
+	}
+	now = time.Date(2025, 2, 1, 3, 0, 0, 0, time.UTC)
+	if m.UnavailableReason(gw) != "" {
+		t.Error("expected the window end to be exclusive")
+	}
+}
+
+func TestGatewayMaintenance_InvalidWindow(t *testing.T) {
+	tests := map[string]config.MaintenanceWindowConfig{
+		"bad start":    {Start: "tomorrow", End: "2025-01-01T04:00:00Z"},
+		"bad end":      {Start: "2025-01-01T02:00:00Z", End: "later"},
+		"ends earlier": {Start: "2025-01-01T04:00:00Z", End: "2025-01-01T02:00:00Z"},
+	}
+	for name, w := range tests {
+		if err := NewGatewayMaintenance().Add("gatewayA", &breakerGateway{}, []config.MaintenanceWindowConfig{w}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestGatewayPoolImpl_MaintenanceTracksInFlightCalls(t *testing.T) {
+	a := &breakerGateway{available: true}
+	b := &breakerGateway{available: true}
+	m := NewGatewayMaintenance()
+	m.Add("a", a, nil)
+	m.Add("b", b, nil)
+	pool := NewGatewayPool([]gateway.PaymentGateway{a, b}, nil, m, routing.Table{Default: routing.NewPriority()})
+
+	gw, _ := pool.GetGateway(routing.Request{})
+	if gw != a {
+		t.Fatal("expected the first gateway")
+	}
+	m.Drain("a", "")
+	for i := 0; i < 2; i++ {
+		if next, _ := pool.GetGateway(routing.Request{}); next != b {
+			t.Fatalf("call %d: expected the draining gateway to be skipped", i)
+		}
+		pool.RecordOutcome(b, routing.Outcome{})
+	}
+	if status, _ := m.Status("a"); status.State != constants.GatewayDraining || status.InFlight != 1 {
+		t.Fatalf("expected a to drain its in-flight call, got %+v", status)
+	}
+	pool.RecordOutcome(a, routing.Outcome{})
+	if status, _ := m.Status("a"); status.State != constants.GatewayDisabled {
+		t.Errorf("expected a disabled once drained, got %s", status.State)
+	}
+}
@@ -1,21 +1,28 @@
 package service
 
 import (
+	"Payment-Gateway/internal/config"
 	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/routing"
 	errors "Payment-Gateway/pkg/error"
-	"net/http"
+	"context"
+	stderrors "errors"
 	"testing"
 )
 
 type dummyGateway struct{}
 
-func (d *dummyGateway) ProcessDeposit(r *http.Request) (interface{}, error)    { return nil, nil }
-func (d *dummyGateway) ProcessWithdrawal(r *http.Request) (interface{}, error) { return nil, nil }
+func (d *dummyGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
+func (d *dummyGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	return nil, nil
+}
 
 func TestGatewayPoolImpl_GetAllGateways(t *testing.T) {
 	g1 := &dummyGateway{}
 	g2 := &dummyGateway{}
-	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2})
+	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2}, nil, nil, routing.Table{})
 
 	gws, err := pool.GetAllGateways()
 	if err != nil {
@@ -27,31 +34,189 @@ func TestGatewayPoolImpl_GetAllGateways(t *testing.T) {
 }
 
 func TestGatewayPoolImpl_GetAllGateways_Empty(t *testing.T) {
-	pool := NewGatewayPool([]gateway.PaymentGateway{})
+	pool := NewGatewayPool([]gateway.PaymentGateway{}, nil, nil, routing.Table{})
 	_, err := pool.GetAllGateways()
 	if err != errors.ErrNoGatewayAvailable {
 		t.Errorf("expected ErrNoGatewayAvailable, got %v", err)
 	}
 }
 
-func TestGatewayPoolImpl_GetRoundRobinGateway(t *testing.T) {
+func TestGatewayPoolImpl_GetGateway_RoundRobin(t *testing.T) {
 	g1 := &dummyGateway{}
 	g2 := &dummyGateway{}
-	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2})
+	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2}, nil, nil, routing.Table{})
 
-	gw1, _ := pool.GetRoundRobinGateway()
-	gw2, _ := pool.GetRoundRobinGateway()
-	gw3, _ := pool.GetRoundRobinGateway()
+	gw1, _ := pool.GetGateway(routing.Request{})
+	gw2, _ := pool.GetGateway(routing.Request{})
+	gw3, _ := pool.GetGateway(routing.Request{})
 
 	if gw1 != g1 || gw2 != g2 || gw3 != g1 {
 		t.Errorf("round robin logic failed")
 	}
 }
 
-func TestGatewayPoolImpl_GetRoundRobinGateway_Empty(t *testing.T) {
-	pool := NewGatewayPool([]gateway.PaymentGateway{})
-	_, err := pool.GetRoundRobinGateway()
+func TestGatewayPoolImpl_GetGateway_Empty(t *testing.T) {
+	pool := NewGatewayPool([]gateway.PaymentGateway{}, nil, nil, routing.Table{})
+	_, err := pool.GetGateway(routing.Request{})
 	if err != errors.ErrNoGatewayAvailable {
 		t.Errorf("expected ErrNoGatewayAvailable, got %v", err)
 	}
 }
+
+// breakerGateway reports a fixed circuit breaker state. Unlike dummyGateway it
+// is not zero-sized, so distinct instances compare unequal.
+type breakerGateway struct {
+	dummyGateway
+	available bool
+}
+
+func (b *breakerGateway) Available() bool { return b.available }
+
+// staticHealth marks the listed gateways unhealthy.
+type staticHealth map[gateway.PaymentGateway]bool
+
+func (h staticHealth) Healthy(gw gateway.PaymentGateway) bool { return !h[gw] }
+
+func TestGatewayPoolImpl_GetGateway_SkipsUnavailable(t *testing.T) {
+	g1 := &breakerGateway{available: false}
+	g2 := &breakerGateway{available: true}
+	g3 := &breakerGateway{available: true}
+	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2, g3}, staticHealth{g3: true}, nil, routing.Table{})
+
+	for i := 0; i < 3; i++ {
+		gw, err := pool.GetGateway(routing.Request{})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if gw != g2 {
+			t.Errorf("call %d: expected the only available gateway", i)
+		}
+	}
+}
+
+func TestGatewayPoolImpl_GetGateway_NoneAvailable(t *testing.T) {
+	g1 := &breakerGateway{available: false}
+	g2 := &breakerGateway{available: true}
+	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2}, staticHealth{g2: true}, nil, routing.Table{})
+
+	_, err := pool.GetGateway(routing.Request{})
+	if err != errors.ErrNoGatewayAvailable {
+		t.Errorf("expected ErrNoGatewayAvailable, got %v", err)
+	}
+}
+
+func TestGatewayPoolImpl_GetFailoverGateway(t *testing.T) {
+	g1 := &breakerGateway{available: true}
+	g2 := &breakerGateway{available: false}
+	g3 := &breakerGateway{available: true}
+	pool := NewGatewayPool([]gateway.PaymentGateway{g1, g2, g3}, nil, nil, routing.Table{})
+
+	gw, err := pool.GetFailoverGateway(routing.Request{}, []gateway.PaymentGateway{g1})
+	if err != nil || gw != g3 {
+		t.Fatalf("expected the untried available gateway, got %v, %v", gw, err)
+	}
+	if _, err := pool.GetFailoverGateway(routing.Request{}, []gateway.PaymentGateway{g1, g3}); err != errors.ErrNoGatewayAvailable {
+		t.Errorf("expected ErrNoGatewayAvailable, got %v", err)
+	}
+}
+
+func TestGatewayPoolImpl_GetGateway_PerMerchantStrategy(t *testing.T) {
+	cheap := &breakerGateway{available: true}
+	pricey := &breakerGateway{available: true}
+	lowestCost := routing.NewLowestCost(map[gateway.PaymentGateway]config.FeeSchedule{
+		cheap:  {Fixed: 0.10},
+		pricey: {Fixed: 1.00},
+	})
+	pool := NewGatewayPool([]gateway.PaymentGateway{pricey, cheap}, nil, nil, routing.Table{
+		Merchants: map[string]routing.Strategy{"thrifty": lowestCost},
+	})
+
+	for i := 0; i < 2; i++ {
+		if gw, _ := pool.GetGateway(routing.Request{MerchantID: "thrifty", Amount: 10}); gw != cheap {
+			t.Errorf("call %d: expected the merchant's lowest cost gateway", i)
+		}
+	}
+	first, _ := pool.GetGateway(routing.Request{MerchantID: "other"})
+	second, _ := pool.GetGateway(routing.Request{MerchantID: "other"})
+	if first == second {
+		t.Error("expected other merchants to use the round robin default")
+	}
+}
+
+func TestGatewayPoolImpl_GetGateway_RoutingRule(t *testing.T) {
+	primary := &breakerGateway{available: true}
+	backup := &breakerGateway{available: true}
+	other := &breakerGateway{available: true}
+	var opts routing.Options
+	opts.AddGateway("primary", primary, config.GatewayRoutingConfig{})
+	opts.AddGateway("backup", backup, config.GatewayRoutingConfig{})
+	opts.AddGateway("other", other, config.GatewayRoutingConfig{})
+	rule, err := routing.NewRule(config.RoutingRule{
+		Name:     "eur",
+		Match:    config.RoutingRuleMatch{Currencies: []string{"EUR"}},
+		Gateways: []string{"primary", "backup"},
+		Strategy: routing.StrategyPriority,
+	}, opts)
+	if err != nil {
+		t.Fatalf("NewRule: %v", err)
+	}
+	pool := NewGatewayPool([]gateway.PaymentGateway{other, backup, primary}, nil, nil, routing.Table{Rules: []*routing.Rule{rule}})
+
+	eur := routing.Request{Currency: "EUR"}
+	for i := 0; i < 2; i++ {
+		if gw, _ := pool.GetGateway(eur); gw != primary {
+			t.Errorf("call %d: expected the rule's first gateway", i)
+		}
+	}
+	if gw, _ := pool.GetFailoverGateway(eur, []gateway.PaymentGateway{primary}); gw != backup {
+		t.Error("expected failover to the rule's next gateway")
+	}
+	if _, err := pool.GetFailoverGateway(eur, []gateway.PaymentGateway{primary, backup}); err != errors.ErrNoGatewayAvailable {
+		t.Errorf("expected no gateway outside the rule, got %v", err)
+	}
+
+	primary.available = false
+	exp := pool.Explain(eur)
+	if exp.Rule != "eur" || exp.Strategy != routing.StrategyPriority || len(exp.Candidates) != 2 {
+		t.Fatalf("unexpected explanation %+v", exp)
+	}
+	if exp.Candidates[0].Available || exp.Candidates[0].Reason == "" || !exp.Candidates[1].Available {
+		t.Errorf("expected the primary to be reported unavailable, got %+v", exp.Candidates)
+	}
+	if exp := pool.Explain(routing.Request{Currency: "USD"}); exp.Rule != "" || len(exp.Candidates) != 3 {
+		t.Errorf("expected every gateway without a matching rule, got %+v", exp)
+	}
+}
+
+func TestGatewayPoolImpl_CanaryRollback(t *testing.T) {
+	stable := &breakerGateway{available: true}
+	fresh := &breakerGateway{available: true}
+	canary := routing.NewCanary("fresh", config.CanaryConfig{Percent: 100, MinSamples: 2, MaxErrorRate: 0.4})
+	pool := NewGatewayPool([]gateway.PaymentGateway{stable, fresh}, nil, nil, routing.Table{
+		Canaries: map[gateway.PaymentGateway]*routing.Canary{fresh: canary},
+	})
+
+	if gw, _ := pool.GetGateway(routing.Request{}); gw != fresh {
+		t.Fatal("expected the canary to take its full share")
+	}
+	pool.RecordOutcome(fresh, routing.Outcome{Failed: true})
+	pool.RecordOutcome(fresh, routing.Outcome{Failed: true})
+	for i := 0; i < 3; i++ {
+		if gw, _ := pool.GetGateway(routing.Request{}); gw != stable {
+			t.Fatalf("call %d: expected the rolled back canary to get no traffic", i)
+		}
+	}
+	if statuses := pool.Canaries(); len(statuses) != 1 || !statuses[0].RolledBack {
+		t.Errorf("expected the canary to be reported rolled back, got %+v", statuses)
+	}
+
+	if err := pool.ResetCanary("missing"); !stderrors.Is(err, errors.ErrGatewayNotFound) {
+		t.Errorf("expected ErrGatewayNotFound, got %v", err)
+	}
+	if err := pool.ResetCanary("fresh"); err != nil {
+		t.Fatalf("ResetCanary: %v", err)
+	}
+	if gw, _ := pool.GetGateway(routing.Request{}); gw != fresh {
+		t.Error("expected the reset canary back in rotation")
+	}
+}
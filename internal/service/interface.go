@@ -5,6 +5,8 @@ import (
 	"Payment-Gateway/internal/dtos"
 	"Payment-Gateway/internal/gateway"
 	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/routing"
+	"time"
 )
 
 type Callback interface {
@@ -21,11 +23,43 @@ type Withdrawal interface {
 
 type GatewayPool interface {
 	GetAllGateways() ([]gateway.PaymentGateway, error)
-	GetRoundRobinGateway() (gateway.PaymentGateway, error)
+	GetGateway(route routing.Request) (gateway.PaymentGateway, error)
+	GetFailoverGateway(route routing.Request, tried []gateway.PaymentGateway) (gateway.PaymentGateway, error)
+	RecordOutcome(gw gateway.PaymentGateway, outcome routing.Outcome)
+	Explain(route routing.Request) routing.Explanation
+	Canaries() []routing.CanaryStatus
+	ResetCanary(name string) error
+}
+
+// GatewayAdmin reports and changes gateways' runtime state, by configuration key.
+type GatewayAdmin interface {
+	Statuses() []models.GatewayStatus
+	Status(name string) (models.GatewayStatus, error)
+	Drain(name, reason string) (models.GatewayStatus, error)
+	Disable(name, reason string) (models.GatewayStatus, error)
+	Enable(name string) (models.GatewayStatus, error)
+}
+
+// ShadowReporter reports how shadow gateways answered mirrored transactions.
+type ShadowReporter interface {
+	ShadowReports() []models.ShadowReport
+}
+
+// GatewayHealth reports whether a gateway passes its active health checks.
+type GatewayHealth interface {
+	Healthy(gw gateway.PaymentGateway) bool
 }
 
 type Transaction interface {
 	UpdateStatus(id string, status constants.TransactionStatus) error
+	ApplyCallbackStatus(id string, status constants.TransactionStatus, at time.Time) (bool, error)
 	Deposit
 	Withdrawal
 }
+
+type WebhookDeliveries interface {
+	ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	GetDelivery(eventID string) (*models.WebhookDelivery, []*models.WebhookDeliveryAttempt, error)
+	Replay(eventID string) error
+	ReplayMatching(filter models.WebhookDeliveryFilter) ([]string, error)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/pkg/logger"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultShadowPercent      = 10
+	defaultShadowMaxInFlight  = 10
+	defaultShadowTimeout      = 5 * time.Second
+	maxRecentShadowMismatches = 20
+	// shadowTransactionPrefix keeps sandbox callbacks for mirrored calls from
+	// matching, and so changing, the real transaction.
+	shadowTransactionPrefix = "shadow-"
+)
+
+const outcomeError = "error"
+
+// ShadowTraffic mirrors a sample of transactions to gateways that are being
+// evaluated and compares their answers with the real ones. Mirrored calls run
+// in the background and never affect the transaction.
+type ShadowTraffic struct {
+	mu      sync.Mutex
+	targets []*shadowTarget
+	wg      sync.WaitGroup
+	roll    func() float64 // returns a value in [0, 100)
+}
+
+type shadowTarget struct {
+	gw      gateway.PaymentGateway
+	percent float64
+	timeout time.Duration
+	slots   chan struct{}
+
+	// Guarded by ShadowTraffic.mu
+	report       models.ShadowReport
+	totalLatency time.Duration
+}
+
+func NewShadowTraffic() *ShadowTraffic {
+	return &ShadowTraffic{roll: func() float64 { return rand.Float64() * 100 }}
+}
+
+// Add mirrors cfg.Percent of transactions to gw, which should point at the
+// gateway's sandbox.
+func (s *ShadowTraffic) Add(name string, gw gateway.PaymentGateway, cfg config.ShadowConfig) {
+	t := &shadowTarget{
+		gw:      gw,
+		percent: cfg.Percent,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+	if t.percent <= 0 {
+		t.percent = defaultShadowPercent
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultShadowTimeout
+	}
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultShadowMaxInFlight
+	}
+	t.slots = make(chan struct{}, maxInFlight)
+	t.report = models.ShadowReport{Gateway: name, Percent: t.percent, Comparisons: make(map[string]int)}
+
+	s.mu.Lock()
+	s.targets = append(s.targets, t)
+	s.mu.Unlock()
+}
+
+// Mirror sends req to each shadow gateway whose sample it falls in, once
+// primary has answered it with result or err. It does not wait for them.
+func (s *ShadowTraffic) Mirror(call gatewayCall, primary gateway.PaymentGateway, req gateway.PaymentRequest, result *gateway.PaymentResult, err error) {
+	s.mu.Lock()
+	targets := s.targets
+	s.mu.Unlock()
+
+	for _, t := range targets {
+		if s.roll() >= t.percent {
+			continue
+		}
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			s.mu.Lock()
+			t.report.Dropped++
+			s.mu.Unlock()
+			continue
+		}
+		s.wg.Add(1)
+		go func(t *shadowTarget) {
+			defer s.wg.Done()
+			defer func() { <-t.slots }()
+			s.mirror(t, call, primary, req, result, err)
+		}(t)
+	}
+}
+
+// Wait blocks until every mirrored call has finished.
+func (s *ShadowTraffic) Wait() {
+	s.wg.Wait()
+}
+
+// ShadowReports returns a copy of every shadow gateway's report.
+func (s *ShadowTraffic) ShadowReports() []models.ShadowReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := make([]models.ShadowReport, 0, len(s.targets))
+	for _, t := range s.targets {
+		r := t.report
+		r.Comparisons = make(map[string]int, len(t.report.Comparisons))
+		for k, v := range t.report.Comparisons {
+			r.Comparisons[k] = v
+		}
+		r.RecentMismatches = append([]models.ShadowMismatch{}, t.report.RecentMismatches...)
+		if r.Mirrored > 0 {
+			r.AvgLatencyMillis = (t.totalLatency / time.Duration(r.Mirrored)).Milliseconds()
+		}
+		reports = append(reports, r)
+	}
+	return reports
+}
+
+func (s *ShadowTraffic) mirror(t *shadowTarget, call gatewayCall, primary gateway.PaymentGateway, req gateway.PaymentRequest, result *gateway.PaymentResult, err error) {
+	shadowReq := req
+	shadowReq.TransactionID = shadowTransactionPrefix + req.TransactionID
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	started := time.Now()
+	shadowResult, shadowErr := call(t.gw, ctx, shadowReq)
+	latency := time.Since(started)
+	cancel()
+
+	primaryOutcome, primaryReason := shadowOutcome(result, err)
+	outcome, reason := shadowOutcome(shadowResult, shadowErr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &t.report
+	r.Mirrored++
+	t.totalLatency += latency
+	r.Comparisons[primaryOutcome+"/"+outcome]++
+	if shadowErr != nil {
+		r.ShadowErrors++
+	}
+	if outcome == primaryOutcome {
+		r.Matched++
+		return
+	}
+	r.Mismatched++
+	mismatch := models.ShadowMismatch{
+		TransactionID:  req.TransactionID,
+		PrimaryGateway: gateway.NameOf(primary),
+		Primary:        primaryOutcome,
+		PrimaryReason:  primaryReason,
+		Shadow:         outcome,
+		ShadowReason:   reason,
+		At:             time.Now(),
+	}
+	if shadowErr != nil {
+		mismatch.ShadowError = shadowErr.Error()
+	}
+	r.RecentMismatches = append(r.RecentMismatches, mismatch)
+	if len(r.RecentMismatches) > maxRecentShadowMismatches {
+		r.RecentMismatches = r.RecentMismatches[1:]
+	}
+	logger.GetLogger().Info("Shadow gateway answered differently",
+		zap.String("func", "ShadowTraffic.mirror"),
+		zap.String("transaction_id", req.TransactionID),
+		zap.String("shadow_gateway", r.Gateway),
+		zap.String("primary", primaryOutcome),
+		zap.String("shadow", outcome),
+	)
+}
+
+// shadowOutcome reduces a gateway answer to the outcome compared in reports.
+func shadowOutcome(result *gateway.PaymentResult, err error) (string, constants.ReasonCode) {
+	if err != nil || result == nil {
+		return outcomeError, gateway.ReasonForError(err)
+	}
+	return string(result.Outcome), result.Reason
+}
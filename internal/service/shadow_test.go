@@ -0,0 +1,89 @@
+package service
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
+	apperrors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/mocks"
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestShadowTraffic_ComparesOutcomes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	shadowGateway := mocks.NewMockPaymentGateway(ctrl)
+	shadowGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+			if req.TransactionID != "shadow-tx-1" {
+				t.Errorf("expected the mirrored call to use a shadow transaction ID, got %q", req.TransactionID)
+			}
+			return &gateway.PaymentResult{Outcome: gateway.OutcomeDeclined, Reason: constants.ReasonDoNotHonor}, nil
+		})
+	shadowGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+		Return(&gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil)
+	shadowGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+		Return(nil, apperrors.ErrGatewayTimeout)
+
+	shadow := NewShadowTraffic()
+	shadow.roll = func() float64 { return 0 }
+	shadow.Add("gatewayC", shadowGateway, config.ShadowConfig{Percent: 100, MaxInFlight: 1})
+
+	approved := &gateway.PaymentResult{Outcome: gateway.OutcomeApproved}
+	primary := &dummyGateway{}
+	for _, id := range []string{"tx-1", "tx-2", "tx-3"} {
+		shadow.Mirror(gateway.PaymentGateway.ProcessDeposit, primary, gateway.PaymentRequest{TransactionID: id}, approved, nil)
+		shadow.Wait()
+	}
+
+	reports := shadow.ShadowReports()
+	if len(reports) != 1 {
+		t.Fatalf("expected one report, got %d", len(reports))
+	}
+	r := reports[0]
+	if r.Gateway != "gatewayC" || r.Mirrored != 3 || r.Matched != 1 || r.Mismatched != 2 || r.ShadowErrors != 1 {
+		t.Errorf("unexpected report %+v", r)
+	}
+	if r.Comparisons["approved/declined"] != 1 || r.Comparisons["approved/approved"] != 1 || r.Comparisons["approved/error"] != 1 {
+		t.Errorf("unexpected comparisons %v", r.Comparisons)
+	}
+	if len(r.RecentMismatches) != 2 || r.RecentMismatches[0].TransactionID != "tx-1" || r.RecentMismatches[0].ShadowReason != constants.ReasonDoNotHonor {
+		t.Errorf("unexpected mismatches %+v", r.RecentMismatches)
+	}
+}
+
+func TestShadowTraffic_SamplesAndDrops(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	release := make(chan struct{})
+	shadowGateway := mocks.NewMockPaymentGateway(ctrl)
+	shadowGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+			<-release
+			return &gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil
+		})
+
+	shadow := NewShadowTraffic()
+	shadow.Add("gatewayC", shadowGateway, config.ShadowConfig{Percent: 50, MaxInFlight: 1})
+	req := gateway.PaymentRequest{TransactionID: "tx-1"}
+	approved := &gateway.PaymentResult{Outcome: gateway.OutcomeApproved}
+
+	shadow.roll = func() float64 { return 50 }
+	shadow.Mirror(gateway.PaymentGateway.ProcessDeposit, &dummyGateway{}, req, approved, nil)
+	shadow.roll = func() float64 { return 0 }
+	shadow.Mirror(gateway.PaymentGateway.ProcessDeposit, &dummyGateway{}, req, approved, nil)
+	// The only slot is taken, so this sample is dropped rather than queued
+	shadow.Mirror(gateway.PaymentGateway.ProcessDeposit, &dummyGateway{}, req, approved, nil)
+	close(release)
+	shadow.Wait()
+
+	r := shadow.ShadowReports()[0]
+	if r.Mirrored != 1 || r.Dropped != 1 {
+		t.Errorf("expected one mirrored and one dropped call, got %+v", r)
+	}
+}
@@ -1,14 +1,16 @@
 package service
 
 import (
+	"Payment-Gateway/internal/config"
 	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
 	"Payment-Gateway/internal/models"
 	"Payment-Gateway/internal/repository"
+	"Payment-Gateway/internal/routing"
+	"Payment-Gateway/internal/webhook"
+	errors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/logger"
-	"bytes"
 	"context"
-	"encoding/json"
-	"net/http"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,16 +22,87 @@ type TransactionService struct {
 	repository      repository.TransactionRepository
 	Gateway         GatewayPool
 	WorkerPool      *WorkerPool
-	TimeoutDuration time.Duration // Injected timeout for context
+	TimeoutDuration time.Duration      // Injected timeout for context, shared by all gateway attempts
+	Webhooks        webhook.Dispatcher // Optional; nil disables merchant notifications
+	// MaxGatewayAttempts caps the gateways one transaction is sent to; 1 disables failover.
+	MaxGatewayAttempts int
+	Shadow             *ShadowTraffic // Optional; nil disables shadow traffic
 }
 
-func NewTransactionService(repo repository.TransactionRepository, gateway GatewayPool, workerPool *WorkerPool, timeout time.Duration) Transaction {
+const defaultMaxGatewayAttempts = 2
+
+func NewTransactionService(repo repository.TransactionRepository, gateway GatewayPool, workerPool *WorkerPool, timeout time.Duration, webhooks webhook.Dispatcher, failover config.FailoverConfig, shadow *ShadowTraffic) Transaction {
+	maxAttempts := 1
+	if failover.Enabled {
+		maxAttempts = failover.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = defaultMaxGatewayAttempts
+		}
+	}
 	return &TransactionService{
-		repository:      repo,
-		Gateway:         gateway,
-		WorkerPool:      workerPool,
-		TimeoutDuration: timeout,
+		repository:         repo,
+		Gateway:            gateway,
+		WorkerPool:         workerPool,
+		TimeoutDuration:    timeout,
+		Webhooks:           webhooks,
+		MaxGatewayAttempts: maxAttempts,
+		Shadow:             shadow,
+	}
+}
+
+// notify publishes a webhook event for the transaction snapshot.
+func (s *TransactionService) notify(eventType constants.WebhookEventType, tx models.Transaction) {
+	if s.Webhooks == nil {
+		return
+	}
+	s.Webhooks.Dispatch(webhook.NewTransactionEvent(eventType, tx))
+}
+
+// notifyStatusChanged loads the stored transaction and publishes its new status.
+func (s *TransactionService) notifyStatusChanged(id string) {
+	if s.Webhooks == nil {
+		return
+	}
+	if tx, found := s.repository.GetTransactionByID(id); found {
+		s.notify(constants.EventTransactionStatusChanged, *tx)
+	}
+}
+
+// updateAndNotify persists the status the gateway answered for a request sent
+// at sentAt and notifies the merchant once it is stored. A callback the gateway
+// sent since then is newer than the answer, which is then dropped.
+// tx is the caller's own copy, never the stored transaction, so it is updated
+// to match.
+func (s *TransactionService) updateAndNotify(tx *models.Transaction, status constants.TransactionStatus, sentAt time.Time) error {
+	applied, err := s.repository.UpdateTransactionStatusUnlessCallbackSince(tx.ID, status, sentAt)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		if stored, found := s.repository.GetTransactionByID(tx.ID); found {
+			tx.Status = stored.Status
+		}
+		return nil
+	}
+	tx.Status = status
+	s.notify(constants.EventTransactionStatusChanged, *tx)
+	return nil
+}
+
+// merchantOrDefault falls back to the default merchant when none is given.
+func merchantOrDefault(merchantID string) string {
+	if merchantID == "" {
+		return constants.DefaultMerchantID
+	}
+	return merchantID
+}
+
+// currencyOrDefault falls back to the default currency when none is given.
+func currencyOrDefault(currency string) string {
+	if currency == "" {
+		return constants.DefaultCurrency
 	}
+	return currency
 }
 
 func (s *TransactionService) processWithWorkerPool(ctx context.Context, task Task) (interface{}, error) {
@@ -45,50 +118,17 @@ func (s *TransactionService) CreateAndProcessDeposit(req *models.DepositRequest)
 
 	log.Info("Creating deposit transaction")
 	tx := &models.Transaction{
-		ID:        uuid.NewString(),
-		Type:      constants.TypeDeposit,
-		Amount:    req.Amount,
-		Status:    constants.StatusPending,
-		Timestamp: time.Now(),
-		Account:   req.Account,
-	}
-
-	if err := s.repository.CreateTransaction(tx); err != nil {
-		log.Error("Failed to create transaction", zap.Error(err))
-		return nil, err
-	}
-
-	gateway, err := s.Gateway.GetRoundRobinGateway()
-	if err != nil {
-		log.Error("No gateway available", zap.Error(err))
-		return nil, err
-	}
-
-	log.Info("Processing deposit with gateway")
-
-	// Use injected timeout duration
-	ctx, cancel := context.WithTimeout(context.Background(), s.TimeoutDuration)
-	defer cancel()
-
-	resp, err := s.processWithWorkerPool(ctx, func(ctx context.Context) (interface{}, error) {
-		bodyBytes, err := json.Marshal(req)
-		if err != nil {
-			log.Error("Failed to marshal deposit request", zap.Error(err))
-			return nil, err
-		}
-		httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, "", bytes.NewReader(bodyBytes))
-		httpReq.Header.Set("Content-Type", "application/json")
-		return gateway.ProcessDeposit(httpReq)
-	})
-
-	if err != nil {
-		log.Error("Gateway deposit failed", zap.Error(err))
-		s.repository.UpdateTransactionStatus(tx.ID, constants.StatusFailed)
-		return tx, err
+		ID:         uuid.NewString(),
+		Type:       constants.TypeDeposit,
+		Amount:     req.Amount,
+		Currency:   currencyOrDefault(req.Currency),
+		Status:     constants.StatusPending,
+		Timestamp:  time.Now(),
+		Account:    req.Account,
+		MerchantID: merchantOrDefault(req.MerchantID),
+		Country:    req.Country,
 	}
-	s.repository.UpdateTransactionStatus(tx.ID, constants.StatusSuccess)
-	log.Info("Deposit processed successfully", zap.Any("gateway_response", resp))
-	return tx, nil
+	return s.process(log, tx, gateway.PaymentGateway.ProcessDeposit)
 }
 
 func (s *TransactionService) CreateAndProcessWithdrawal(req *models.WithdrawalRequest) (*models.Transaction, error) {
@@ -100,55 +140,181 @@ func (s *TransactionService) CreateAndProcessWithdrawal(req *models.WithdrawalRe
 
 	log.Info("Creating withdrawal transaction")
 	tx := &models.Transaction{
-		ID:        uuid.NewString(),
-		Type:      constants.TypeWithdrawal,
-		Amount:    req.Amount,
-		Status:    constants.StatusPending,
-		Timestamp: time.Now(),
-		Account:   req.Account,
+		ID:         uuid.NewString(),
+		Type:       constants.TypeWithdrawal,
+		Amount:     req.Amount,
+		Currency:   currencyOrDefault(req.Currency),
+		Status:     constants.StatusPending,
+		Timestamp:  time.Now(),
+		Account:    req.Account,
+		MerchantID: merchantOrDefault(req.MerchantID),
+		Country:    req.Country,
 	}
+	return s.process(log, tx, gateway.PaymentGateway.ProcessWithdrawal)
+}
+
+// gatewayCall is a PaymentGateway method value such as PaymentGateway.ProcessDeposit.
+type gatewayCall func(gw gateway.PaymentGateway, ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error)
 
+// process stores tx, sends it to a gateway through the worker pool and applies
+// the gateway outcome to the transaction status. Failures that prove the
+// gateway did not process the payment fail over to the next eligible gateway,
+// up to MaxGatewayAttempts; ambiguous failures such as timeouts never do.
+func (s *TransactionService) process(log *zap.Logger, tx *models.Transaction, call gatewayCall) (*models.Transaction, error) {
 	if err := s.repository.CreateTransaction(tx); err != nil {
 		log.Error("Failed to create transaction", zap.Error(err))
 		return nil, err
 	}
+	s.notify(constants.EventTransactionCreated, *tx)
 
-	gateway, err := s.Gateway.GetRoundRobinGateway()
+	route := routing.Request{
+		Type:       tx.Type,
+		MerchantID: tx.MerchantID,
+		Account:    tx.Account,
+		Amount:     tx.Amount,
+		Currency:   tx.Currency,
+		Country:    tx.Country,
+	}
+	gw, err := s.Gateway.GetGateway(route)
 	if err != nil {
+		// The transaction is stored and announced, so it must still end
 		log.Error("No gateway available", zap.Error(err))
-		return nil, err
+		s.recordGatewayResult(log, tx, models.GatewayResult{ReasonCode: gateway.ReasonForError(err)})
+		s.updateAndNotify(tx, constants.StatusFailed, time.Now())
+		return tx, err
 	}
 
-	log.Info("Processing withdrawal with gateway")
+	log = log.With(zap.String("transaction_id", tx.ID))
+	log.Info("Processing transaction with gateway")
 
 	// Use injected timeout duration
 	ctx, cancel := context.WithTimeout(context.Background(), s.TimeoutDuration)
 	defer cancel()
 
-	resp, err := s.processWithWorkerPool(ctx, func(ctx context.Context) (interface{}, error) {
-		bodyBytes, err := json.Marshal(req)
-		if err != nil {
-			log.Error("Failed to marshal withdrawal request", zap.Error(err))
-			return nil, err
+	req := gateway.PaymentRequest{
+		TransactionID: tx.ID,
+		Account:       tx.Account,
+		Amount:        tx.Amount,
+		Currency:      route.Currency,
+		MerchantID:    tx.MerchantID,
+	}
+	var result *gateway.PaymentResult
+	var tried []gateway.PaymentGateway
+	var started time.Time
+	for {
+		started = time.Now()
+		result, err = s.callGateway(ctx, gw, call, req)
+		s.Gateway.RecordOutcome(gw, routing.Outcome{
+			Authorized: err == nil && result.Outcome != gateway.OutcomeDeclined,
+			Failed:     err != nil,
+			Latency:    time.Since(started),
+		})
+		s.recordGatewayAttempt(log, tx, gw, result, err)
+		tried = append(tried, gw)
+		if err == nil || !gateway.SafeToFailover(err) || len(tried) >= s.MaxGatewayAttempts || ctx.Err() != nil {
+			break
+		}
+		next, nextErr := s.Gateway.GetFailoverGateway(route, tried)
+		if nextErr != nil {
+			break
 		}
-		httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, "", bytes.NewReader(bodyBytes))
-		httpReq.Header.Set("Content-Type", "application/json")
+		log.Warn("Gateway did not process the transaction, failing over",
+			zap.String("from_gateway", gateway.NameOf(gw)),
+			zap.String("to_gateway", gateway.NameOf(next)),
+			zap.Error(err),
+		)
+		gw = next
+	}
+	if s.Shadow != nil {
+		s.Shadow.Mirror(call, gw, req, result, err)
+	}
+	if err != nil {
+		log.Error("Gateway request failed", zap.Error(err))
+		s.recordGatewayResult(log, tx, models.GatewayResult{ReasonCode: gateway.ReasonForError(err)})
+		s.updateAndNotify(tx, constants.StatusFailed, started)
+		return tx, err
+	}
 
-		return gateway.ProcessWithdrawal(httpReq)
+	log = log.With(zap.String("outcome", string(result.Outcome)), zap.String("gateway_ref", result.GatewayRef))
+	s.recordGatewayResult(log, tx, models.GatewayResult{
+		Reference:  result.GatewayRef,
+		ReasonCode: result.Reason,
+		Code:       result.GatewayCode,
+		Message:    result.Message,
 	})
+	switch result.Outcome {
+	case gateway.OutcomeApproved:
+		if err := s.updateAndNotify(tx, constants.StatusSuccess, started); err != nil {
+			log.Error("Failed to update transaction status", zap.Error(err))
+			return tx, err
+		}
+		log.Info("Transaction approved by gateway")
+		return tx, nil
+	case gateway.OutcomePending:
+		log.Info("Transaction pending at gateway; awaiting callback")
+		return tx, nil
+	default:
+		log.Warn("Transaction declined by gateway",
+			zap.String("reason_code", string(result.Reason)),
+			zap.String("gateway_code", result.GatewayCode),
+			zap.String("gateway_message", result.Message),
+		)
+		if err := s.updateAndNotify(tx, constants.StatusFailed, started); err != nil {
+			log.Error("Failed to update transaction status", zap.Error(err))
+			return tx, err
+		}
+		return tx, errors.WithMessage(errors.ErrPaymentDeclined, declineMessage(result))
+	}
+}
 
+// callGateway runs one gateway call on the worker pool.
+func (s *TransactionService) callGateway(ctx context.Context, gw gateway.PaymentGateway, call gatewayCall, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+	resp, err := s.processWithWorkerPool(ctx, func(ctx context.Context) (interface{}, error) {
+		return call(gw, ctx, req)
+	})
 	if err != nil {
-		log.Error("Gateway withdrawal failed", zap.Error(err))
-		s.repository.UpdateTransactionStatus(tx.ID, constants.StatusFailed)
-		return tx, err
+		return nil, err
 	}
-	err = s.repository.UpdateTransactionStatus(tx.ID, constants.StatusSuccess)
+	result, _ := resp.(*gateway.PaymentResult)
+	if result == nil {
+		return nil, errors.ErrProcessingFailed
+	}
+	return result, nil
+}
+
+// recordGatewayAttempt appends the call to the transaction's attempt history;
+// failing to store it does not change the outcome of the transaction.
+func (s *TransactionService) recordGatewayAttempt(log *zap.Logger, tx *models.Transaction, gw gateway.PaymentGateway, result *gateway.PaymentResult, err error) {
+	attempt := models.GatewayAttempt{Gateway: gateway.NameOf(gw), At: time.Now()}
 	if err != nil {
-		log.Error("Failed to update transaction status", zap.Error(err))
-		return tx, err
+		attempt.ReasonCode = gateway.ReasonForError(err)
+		attempt.Error = err.Error()
+	} else {
+		attempt.Outcome = string(result.Outcome)
+		attempt.ReasonCode = result.Reason
+		attempt.Reference = result.GatewayRef
+	}
+	if err := s.repository.AddGatewayAttempt(tx.ID, attempt); err != nil {
+		log.Warn("Failed to store gateway attempt", zap.Error(err))
+	}
+	tx.GatewayAttempts = append(tx.GatewayAttempts, attempt)
+}
+
+// recordGatewayResult stores the gateway's answer; failing to store it does
+// not change the outcome of the transaction.
+func (s *TransactionService) recordGatewayResult(log *zap.Logger, tx *models.Transaction, result models.GatewayResult) {
+	if err := s.repository.SetGatewayResult(tx.ID, result); err != nil {
+		log.Warn("Failed to store gateway result", zap.Error(err))
+	}
+	tx.GatewayResult = &result
+}
+
+// declineMessage prefers the gateway's own explanation of a decline.
+func declineMessage(result *gateway.PaymentResult) string {
+	if result.Message != "" {
+		return result.Message
 	}
-	log.Info("Withdrawal processed successfully", zap.Any("gateway_response", resp))
-	return tx, nil
+	return errors.ErrPaymentDeclined.Error()
 }
 
 func (s *TransactionService) GetTransaction(id string) (*models.Transaction, bool) {
@@ -176,6 +342,32 @@ func (s *TransactionService) UpdateStatus(id string, status constants.Transactio
 	err := s.repository.UpdateTransactionStatus(id, status)
 	if err != nil {
 		log.Error("Failed to update transaction status", zap.Error(err))
+		return err
+	}
+	s.notifyStatusChanged(id)
+	return nil
+}
+
+// ApplyCallbackStatus updates the transaction status from a gateway callback,
+// ignoring callbacks older than the last one applied. It reports whether the
+// status was changed.
+func (s *TransactionService) ApplyCallbackStatus(id string, status constants.TransactionStatus, at time.Time) (bool, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "TransactionService.ApplyCallbackStatus"),
+		zap.String("transaction_id", id),
+		zap.String("status", string(status)),
+		zap.Time("callback_at", at),
+	)
+	log.Info("Applying callback status")
+	applied, err := s.repository.UpdateTransactionStatusIfNewer(id, status, at)
+	if err != nil {
+		log.Error("Failed to apply callback status", zap.Error(err))
+		return false, err
+	}
+	if !applied {
+		log.Warn("Stale callback ignored")
+		return false, nil
 	}
-	return err
+	s.notifyStatusChanged(id)
+	return true, nil
 }
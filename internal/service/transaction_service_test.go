@@ -1,10 +1,18 @@
 package service
 
 import (
+	"Payment-Gateway/internal/config"
 	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/gateway"
 	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/repository"
+	"Payment-Gateway/internal/routing"
+	apperrors "Payment-Gateway/pkg/error"
 	"Payment-Gateway/pkg/mocks"
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,11 +32,14 @@ func TestCreateAndProcessDeposit_Success(t *testing.T) {
 	depositReq := &models.DepositRequest{Account: "acc1", Amount: 100}
 
 	mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
-	mockGatewayPool.EXPECT().GetRoundRobinGateway().Return(mockGateway, nil)
-	mockGateway.EXPECT().ProcessDeposit(gomock.Any()).Return(nil, nil)
-	mockRepo.EXPECT().UpdateTransactionStatus(gomock.Any(), constants.StatusSuccess).Return(nil)
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+	mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+	mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).Return(&gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil)
+	mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusSuccess, gomock.Any()).Return(true, nil)
 
-	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second)
+	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, config.FailoverConfig{}, nil)
 	_, err := svc.CreateAndProcessDeposit(depositReq)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -46,11 +57,14 @@ func TestCreateAndProcessDeposit_GatewayError(t *testing.T) {
 	depositReq := &models.DepositRequest{Account: "acc1", Amount: 100}
 
 	mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
-	mockGatewayPool.EXPECT().GetRoundRobinGateway().Return(mockGateway, nil)
-	mockGateway.EXPECT().ProcessDeposit(gomock.Any()).Return(nil, errors.New("gateway error"))
-	mockRepo.EXPECT().UpdateTransactionStatus(gomock.Any(), constants.StatusFailed).Return(nil)
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+	mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+	mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).Return(nil, errors.New("gateway error"))
+	mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusFailed, gomock.Any()).Return(true, nil)
 
-	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second)
+	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, config.FailoverConfig{}, nil)
 	_, err := svc.CreateAndProcessDeposit(depositReq)
 	if err == nil {
 		t.Fatal("expected error, got nil")
@@ -68,11 +82,14 @@ func TestCreateAndProcessWithdrawal_Success(t *testing.T) {
 	withdrawalReq := &models.WithdrawalRequest{Account: "acc2", Amount: 50}
 
 	mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
-	mockGatewayPool.EXPECT().GetRoundRobinGateway().Return(mockGateway, nil)
-	mockGateway.EXPECT().ProcessWithdrawal(gomock.Any()).Return(nil, nil)
-	mockRepo.EXPECT().UpdateTransactionStatus(gomock.Any(), constants.StatusSuccess).Return(nil)
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+	mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+	mockGateway.EXPECT().ProcessWithdrawal(gomock.Any(), gomock.Any()).Return(&gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil)
+	mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusSuccess, gomock.Any()).Return(true, nil)
 
-	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second)
+	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, config.FailoverConfig{}, nil)
 	_, err := svc.CreateAndProcessWithdrawal(withdrawalReq)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
@@ -90,13 +107,312 @@ func TestCreateAndProcessWithdrawal_GatewayError(t *testing.T) {
 	withdrawalReq := &models.WithdrawalRequest{Account: "acc2", Amount: 50}
 
 	mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
-	mockGatewayPool.EXPECT().GetRoundRobinGateway().Return(mockGateway, nil)
-	mockGateway.EXPECT().ProcessWithdrawal(gomock.Any()).Return(nil, errors.New("gateway error"))
-	mockRepo.EXPECT().UpdateTransactionStatus(gomock.Any(), constants.StatusFailed).Return(nil)
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+	mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+	mockGateway.EXPECT().ProcessWithdrawal(gomock.Any(), gomock.Any()).Return(nil, errors.New("gateway error"))
+	mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusFailed, gomock.Any()).Return(true, nil)
 
-	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second)
+	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, config.FailoverConfig{}, nil)
 	_, err := svc.CreateAndProcessWithdrawal(withdrawalReq)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
 }
+
+func TestCreateAndProcessDeposit_NotifiesMerchant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockTransactionRepository(ctrl)
+	mockGatewayPool := mocks.NewMockGatewayPool(ctrl)
+	mockGateway := mocks.NewMockPaymentGateway(ctrl)
+	mockWebhooks := mocks.NewMockDispatcher(ctrl)
+
+	depositReq := &models.DepositRequest{Account: "acc1", Amount: 100}
+
+	mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+	mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+	mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+	mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).Return(&gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil)
+	mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusSuccess, gomock.Any()).Return(true, nil)
+
+	var events []models.WebhookEvent
+	mockWebhooks.EXPECT().Dispatch(gomock.Any()).Do(func(e models.WebhookEvent) {
+		events = append(events, e)
+	}).Times(2)
+
+	svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, mockWebhooks, config.FailoverConfig{}, nil)
+	if _, err := svc.CreateAndProcessDeposit(depositReq); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if events[0].Type != constants.EventTransactionCreated || events[0].Data.Status != constants.StatusPending {
+		t.Errorf("unexpected first event: %+v", events[0])
+	}
+	if events[1].Type != constants.EventTransactionStatusChanged || events[1].Data.Status != constants.StatusSuccess {
+		t.Errorf("unexpected second event: %+v", events[1])
+	}
+	if events[0].Data.MerchantID != constants.DefaultMerchantID {
+		t.Errorf("expected default merchant, got %q", events[0].Data.MerchantID)
+	}
+}
+
+func TestCreateAndProcessDeposit_Outcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     *gateway.PaymentResult
+		wantStatus constants.TransactionStatus
+		wantErr    error
+		wantReason constants.ReasonCode
+	}{
+		{"approved", &gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, constants.StatusSuccess, nil, ""},
+		{
+			"declined",
+			&gateway.PaymentResult{Outcome: gateway.OutcomeDeclined, Reason: constants.ReasonInsufficientFunds, GatewayCode: "NSF", Message: "insufficient funds"},
+			constants.StatusFailed, apperrors.ErrPaymentDeclined, constants.ReasonInsufficientFunds,
+		},
+		{"pending", &gateway.PaymentResult{Outcome: gateway.OutcomePending}, constants.StatusPending, nil, ""},
+		{"no result", nil, constants.StatusFailed, apperrors.ErrProcessingFailed, constants.ReasonGatewayError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var stored models.GatewayResult
+			mockRepo := mocks.NewMockTransactionRepository(ctrl)
+			mockGatewayPool := mocks.NewMockGatewayPool(ctrl)
+			mockGateway := mocks.NewMockPaymentGateway(ctrl)
+
+			mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
+			mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+			mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+			mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).Return(nil)
+			mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).
+				Do(func(_ string, result models.GatewayResult) { stored = result }).Return(nil)
+			mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+					if req.TransactionID == "" || req.Account != "acc1" || req.Currency != constants.DefaultCurrency {
+						t.Errorf("unexpected payment request: %+v", req)
+					}
+					return tt.result, nil
+				})
+			if tt.wantStatus != constants.StatusPending {
+				mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), tt.wantStatus, gomock.Any()).Return(true, nil)
+			}
+
+			svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, config.FailoverConfig{}, nil)
+			tx, err := svc.CreateAndProcessDeposit(&models.DepositRequest{Account: "acc1", Amount: 100})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tx == nil {
+				t.Fatal("expected transaction")
+			}
+			if stored.ReasonCode != tt.wantReason {
+				t.Errorf("expected stored reason %q, got %q", tt.wantReason, stored.ReasonCode)
+			}
+		})
+	}
+}
+
+func TestCreateAndProcessDeposit_Failover(t *testing.T) {
+	notProcessed := fmt.Errorf("%w: %w", apperrors.ErrGatewayNotAvailable, gateway.ErrNotProcessed)
+	tests := []struct {
+		name         string
+		failover     config.FailoverConfig
+		firstErr     error
+		wantFailover bool
+	}{
+		{"not processed fails over", config.FailoverConfig{Enabled: true, MaxAttempts: 2}, notProcessed, true},
+		{"timeout never fails over", config.FailoverConfig{Enabled: true, MaxAttempts: 2}, apperrors.WithMessage(apperrors.ErrGatewayTimeout, "gateway A timeout"), false},
+		{"processing failure never fails over", config.FailoverConfig{Enabled: true, MaxAttempts: 2}, apperrors.ErrProcessingFailed, false},
+		{"disabled", config.FailoverConfig{}, notProcessed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockTransactionRepository(ctrl)
+			mockGatewayPool := mocks.NewMockGatewayPool(ctrl)
+			first := mocks.NewMockPaymentGateway(ctrl)
+			second := mocks.NewMockPaymentGateway(ctrl)
+
+			var attempts []models.GatewayAttempt
+			mockRepo.EXPECT().CreateTransaction(gomock.Any()).Return(nil)
+			mockRepo.EXPECT().AddGatewayAttempt(gomock.Any(), gomock.Any()).
+				Do(func(_ string, a models.GatewayAttempt) { attempts = append(attempts, a) }).Return(nil).AnyTimes()
+			mockRepo.EXPECT().SetGatewayResult(gomock.Any(), gomock.Any()).Return(nil)
+			mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(first, nil)
+			mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+			first.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).Return(nil, tt.firstErr)
+
+			var firstReq gateway.PaymentRequest
+			if tt.wantFailover {
+				mockGatewayPool.EXPECT().GetFailoverGateway(gomock.Any(), []gateway.PaymentGateway{first}).Return(second, nil)
+				second.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+						firstReq = req
+						return &gateway.PaymentResult{Outcome: gateway.OutcomeApproved, GatewayRef: "ref-2"}, nil
+					})
+				mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusSuccess, gomock.Any()).Return(true, nil)
+			} else {
+				mockRepo.EXPECT().UpdateTransactionStatusUnlessCallbackSince(gomock.Any(), constants.StatusFailed, gomock.Any()).Return(true, nil)
+			}
+
+			svc := NewTransactionService(mockRepo, mockGatewayPool, workerPool, 1*time.Second, nil, tt.failover, nil)
+			tx, err := svc.CreateAndProcessDeposit(&models.DepositRequest{Account: "acc1", Amount: 100})
+			if tt.wantFailover {
+				if err != nil {
+					t.Fatalf("expected failover to succeed, got %v", err)
+				}
+				if firstReq.TransactionID != tx.ID {
+					t.Errorf("expected the same transaction to be re-sent, got %q want %q", firstReq.TransactionID, tx.ID)
+				}
+				if len(attempts) != 2 || attempts[0].Error == "" || attempts[1].Outcome != string(gateway.OutcomeApproved) || attempts[1].Reference != "ref-2" {
+					t.Errorf("unexpected attempts: %+v", attempts)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if len(attempts) != 1 {
+				t.Errorf("expected a single attempt, got %+v", attempts)
+			}
+		})
+	}
+}
+
+// Run with -race: callbacks and readers race the synchronous gateway answer.
+func TestCreateAndProcessDeposit_ConcurrentCallbacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := repository.NewInMemoryTransactionRepository()
+	mockGatewayPool := mocks.NewMockGatewayPool(ctrl)
+	mockGateway := mocks.NewMockPaymentGateway(ctrl)
+	mockWebhooks := mocks.NewMockDispatcher(ctrl)
+	mockWebhooks.EXPECT().Dispatch(gomock.Any()).Do(func(e models.WebhookEvent) {
+		_ = fmt.Sprintf("%+v", e) // reads the whole snapshot
+	}).AnyTimes()
+	mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+	mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+
+	svc := NewTransactionService(repo, mockGatewayPool, workerPool, time.Second, mockWebhooks, config.FailoverConfig{}, nil)
+	var wg sync.WaitGroup
+	mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+			for i := 0; i < 10; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					svc.ApplyCallbackStatus(req.TransactionID, constants.StatusSuccess, time.Now())
+				}()
+				go func() {
+					defer wg.Done()
+					if tx, ok := repo.GetTransactionByID(req.TransactionID); ok {
+						_ = fmt.Sprintf("%+v", *tx)
+					}
+				}()
+			}
+			return &gateway.PaymentResult{Outcome: gateway.OutcomeApproved, GatewayRef: "ref-1"}, nil
+		})
+
+	tx, err := svc.CreateAndProcessDeposit(&models.DepositRequest{Account: "acc1", Amount: 100})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = fmt.Sprintf("%+v", *tx)
+	wg.Wait()
+
+	stored, _ := repo.GetTransactionByID(tx.ID)
+	if stored.Status != constants.StatusSuccess || stored.GatewayResult == nil || len(stored.GatewayAttempts) != 1 {
+		t.Errorf("unexpected stored transaction %+v", stored)
+	}
+}
+
+func TestCreateAndProcessDeposit_CallbackBeforeAnswer(t *testing.T) {
+	tests := []struct {
+		name string
+		skew time.Duration
+	}{
+		{"gateway clock in sync", 0},
+		{"gateway clock behind", -2 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := repository.NewInMemoryTransactionRepository()
+			mockGatewayPool := mocks.NewMockGatewayPool(ctrl)
+			mockGateway := mocks.NewMockPaymentGateway(ctrl)
+			mockGatewayPool.EXPECT().GetGateway(gomock.Any()).Return(mockGateway, nil)
+			mockGatewayPool.EXPECT().RecordOutcome(gomock.Any(), gomock.Any()).AnyTimes()
+
+			svc := NewTransactionService(repo, mockGatewayPool, workerPool, time.Second, nil, config.FailoverConfig{}, nil)
+			// The gateway's callback lands before its synchronous answer returns
+			mockGateway.EXPECT().ProcessDeposit(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
+					at := time.Now().Add(tt.skew).UTC().Truncate(time.Second)
+					if _, err := svc.ApplyCallbackStatus(req.TransactionID, constants.StatusFailed, at); err != nil {
+						t.Errorf("ApplyCallbackStatus: %v", err)
+					}
+					return &gateway.PaymentResult{Outcome: gateway.OutcomeApproved}, nil
+				})
+
+			tx, err := svc.CreateAndProcessDeposit(&models.DepositRequest{Account: "acc1", Amount: 100})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			stored, _ := repo.GetTransactionByID(tx.ID)
+			if stored.Status != constants.StatusFailed || tx.Status != constants.StatusFailed {
+				t.Errorf("expected the callback's status to stand, got stored %s and returned %s", stored.Status, tx.Status)
+			}
+		})
+	}
+}
+
+func TestCreateAndProcessDeposit_NoGatewayAvailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gw := mocks.NewMockPaymentGateway(ctrl) // never called
+	maintenance := NewGatewayMaintenance()
+	if err := maintenance.Add("gw", gw, nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := maintenance.Disable("gw", "provider outage"); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	pool := NewGatewayPool([]gateway.PaymentGateway{gw}, nil, maintenance, routing.Table{})
+
+	var events []models.WebhookEvent
+	mockWebhooks := mocks.NewMockDispatcher(ctrl)
+	mockWebhooks.EXPECT().Dispatch(gomock.Any()).Do(func(e models.WebhookEvent) { events = append(events, e) }).Times(2)
+
+	repo := repository.NewInMemoryTransactionRepository()
+	svc := NewTransactionService(repo, pool, workerPool, time.Second, mockWebhooks, config.FailoverConfig{}, nil)
+	tx, err := svc.CreateAndProcessDeposit(&models.DepositRequest{Account: "acc1", Amount: 100})
+	if !errors.Is(err, apperrors.ErrNoGatewayAvailable) {
+		t.Fatalf("expected ErrNoGatewayAvailable, got %v", err)
+	}
+	if tx == nil {
+		t.Fatal("expected the failed transaction to be returned")
+	}
+
+	stored, _ := repo.GetTransactionByID(tx.ID)
+	if stored.Status != constants.StatusFailed || stored.GatewayResult == nil || stored.GatewayResult.ReasonCode != constants.ReasonGatewayUnavailable {
+		t.Errorf("expected a FAILED gateway_unavailable transaction, got %+v", stored)
+	}
+	if len(events) != 2 || events[1].Type != constants.EventTransactionStatusChanged || events[1].Data.Status != constants.StatusFailed {
+		t.Errorf("expected a created and a FAILED status_changed webhook, got %+v", events)
+	}
+}
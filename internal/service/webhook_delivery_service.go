@@ -0,0 +1,98 @@
+package service
+
+import (
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/repository"
+	"Payment-Gateway/internal/webhook"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+type WebhookDeliveryService struct {
+	repository repository.WebhookDeliveryRepository
+	dispatcher webhook.Dispatcher // nil when webhook delivery is disabled
+}
+
+func NewWebhookDeliveryService(repo repository.WebhookDeliveryRepository, dispatcher webhook.Dispatcher) WebhookDeliveries {
+	return &WebhookDeliveryService{
+		repository: repo,
+		dispatcher: dispatcher,
+	}
+}
+
+func (s *WebhookDeliveryService) ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "WebhookDeliveryService.ListDeliveries"),
+		zap.String("state", string(filter.State)),
+		zap.String("merchant_id", filter.MerchantID),
+	)
+	deliveries, err := s.repository.ListDeliveries(filter)
+	if err != nil {
+		log.Error("Failed to list webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+	log.Info("Listed webhook deliveries", zap.Int("count", len(deliveries)))
+	return deliveries, nil
+}
+
+func (s *WebhookDeliveryService) GetDelivery(eventID string) (*models.WebhookDelivery, []*models.WebhookDeliveryAttempt, error) {
+	delivery, err := s.repository.GetDelivery(eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attempts, err := s.repository.ListAttemptsByEvent(eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return delivery, attempts, nil
+}
+
+func (s *WebhookDeliveryService) Replay(eventID string) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "WebhookDeliveryService.Replay"),
+		zap.String("event_id", eventID),
+	)
+	if s.dispatcher == nil {
+		return errors.ErrWebhooksDisabled
+	}
+	if err := s.dispatcher.Replay(eventID); err != nil {
+		log.Warn("Webhook replay rejected", zap.Error(err))
+		return err
+	}
+	log.Info("Webhook replay queued")
+	return nil
+}
+
+// ReplayMatching replays every delivery matching the filter and returns the
+// IDs of the events that were queued. It stops at the first error other than
+// an in-flight delivery, returning the IDs queued so far.
+func (s *WebhookDeliveryService) ReplayMatching(filter models.WebhookDeliveryFilter) ([]string, error) {
+	log := logger.GetLogger().With(
+		zap.String("func", "WebhookDeliveryService.ReplayMatching"),
+		zap.String("state", string(filter.State)),
+		zap.String("merchant_id", filter.MerchantID),
+	)
+	if s.dispatcher == nil {
+		return nil, errors.ErrWebhooksDisabled
+	}
+	deliveries, err := s.repository.ListDeliveries(filter)
+	if err != nil {
+		return nil, err
+	}
+	replayed := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		err := s.dispatcher.Replay(d.EventID)
+		if err == errors.ErrWebhookDeliveryInProgress {
+			continue
+		}
+		if err != nil {
+			log.Warn("Webhook bulk replay stopped", zap.String("event_id", d.EventID), zap.Error(err))
+			return replayed, err
+		}
+		replayed = append(replayed, d.EventID)
+	}
+	log.Info("Webhook bulk replay queued", zap.Int("count", len(replayed)))
+	return replayed, nil
+}
@@ -0,0 +1,307 @@
+package webhook
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/repository"
+	errors "Payment-Gateway/pkg/error"
+	"Payment-Gateway/pkg/logger"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// NewTransactionEvent builds a webhook event carrying a snapshot of the transaction.
+func NewTransactionEvent(eventType constants.WebhookEventType, tx models.Transaction) models.WebhookEvent {
+	return models.WebhookEvent{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      tx,
+	}
+}
+
+// HTTPDispatcher POSTs signed JSON events to merchant endpoints from a pool of
+// background workers, retrying failed deliveries with exponential backoff and
+// dead-lettering them once retries are exhausted.
+type HTTPDispatcher struct {
+	cfg        *config.WebhookConfig
+	merchants  map[string]config.MerchantConfig
+	client     *http.Client
+	deliveries repository.WebhookDeliveryRepository
+	queue      chan string // event IDs; the delivery record is the source of truth
+
+	mu       sync.RWMutex // guards closed and sends on queue, which Shutdown closes
+	closed   bool
+	replayMu sync.Mutex // makes Replay's check and change of a delivery's state atomic
+	workers  sync.WaitGroup
+	// abort is cancelled when Shutdown gives up waiting, cutting short the
+	// deliveries still in flight or waiting to retry.
+	abort       context.Context
+	cancelAbort context.CancelFunc
+}
+
+func NewHTTPDispatcher(cfg *config.WebhookConfig, merchants map[string]config.MerchantConfig, deliveries repository.WebhookDeliveryRepository) *HTTPDispatcher {
+	d := &HTTPDispatcher{
+		cfg:        cfg,
+		merchants:  merchants,
+		client:     &http.Client{Timeout: time.Duration(cfg.HTTPTimeoutSeconds) * time.Second},
+		deliveries: deliveries,
+		queue:      make(chan string, cfg.BufferSize),
+	}
+	d.abort, d.cancelAbort = context.WithCancel(context.Background())
+	d.workers.Add(cfg.NumWorkers)
+	for i := 0; i < cfg.NumWorkers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+// Dispatch queues the event for delivery without blocking the caller. Events
+// for merchants without an endpoint are dropped; events that do not fit in the
+// queue are dead-lettered so they can be replayed.
+func (d *HTTPDispatcher) Dispatch(event models.WebhookEvent) {
+	log := logger.GetLogger().With(
+		zap.String("func", "HTTPDispatcher.Dispatch"),
+		zap.String("event_id", event.ID),
+		zap.String("merchant_id", event.Data.MerchantID),
+	)
+	merchant, ok := d.merchants[event.Data.MerchantID]
+	if !ok || merchant.WebhookURL == "" {
+		log.Debug("No webhook endpoint configured for merchant")
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery := &models.WebhookDelivery{
+		EventID:    event.ID,
+		Event:      event,
+		MerchantID: event.Data.MerchantID,
+		URL:        merchant.WebhookURL,
+		State:      constants.DeliveryPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := d.deliveries.SaveDelivery(delivery); err != nil {
+		log.Error("Failed to save webhook delivery", zap.Error(err))
+	}
+	if err := d.enqueue(delivery); err != nil {
+		log.Error("Webhook event not queued, event dead-lettered", zap.Error(err))
+		d.finish(delivery, err)
+	}
+}
+
+// Replay redelivers a stored event with its original ID so merchants can
+// deduplicate. Deliveries that are still in flight cannot be replayed, so of
+// two concurrent replays of an event only one queues it.
+func (d *HTTPDispatcher) Replay(eventID string) error {
+	log := logger.GetLogger().With(
+		zap.String("func", "HTTPDispatcher.Replay"),
+		zap.String("event_id", eventID),
+	)
+	d.replayMu.Lock()
+	defer d.replayMu.Unlock()
+	delivery, err := d.deliveries.GetDelivery(eventID)
+	if err != nil {
+		return err
+	}
+	if delivery.State == constants.DeliveryPending {
+		return errors.ErrWebhookDeliveryInProgress
+	}
+	// Pick up endpoint changes made since the original delivery.
+	if merchant, ok := d.merchants[delivery.MerchantID]; ok && merchant.WebhookURL != "" {
+		delivery.URL = merchant.WebhookURL
+	}
+	delivery.State = constants.DeliveryPending
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := d.deliveries.SaveDelivery(delivery); err != nil {
+		return err
+	}
+	if err := d.enqueue(delivery); err != nil {
+		d.finish(delivery, err)
+		return err
+	}
+	log.Info("Webhook delivery queued for replay", zap.Int("previous_attempts", delivery.Attempts))
+	return nil
+}
+
+func (d *HTTPDispatcher) enqueue(delivery *models.WebhookDelivery) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return errors.ErrWebhookDispatcherClosed
+	}
+	select {
+	case d.queue <- delivery.EventID:
+		return nil
+	default:
+		return errors.ErrWebhookQueueFull
+	}
+}
+
+// Shutdown stops accepting events, which are dead-lettered from then on, and
+// waits for the workers to deliver the events already queued. Once ctx is done
+// it stops waiting: deliveries still in flight or queued are dead-lettered so
+// they can be replayed, and ctx's error is returned.
+func (d *HTTPDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		d.cancelAbort()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+// Close shuts the dispatcher down once every queued event is delivered.
+func (d *HTTPDispatcher) Close() {
+	d.Shutdown(context.Background())
+}
+
+func (d *HTTPDispatcher) worker() {
+	defer d.workers.Done()
+	for eventID := range d.queue {
+		d.deliver(eventID)
+	}
+}
+
+func (d *HTTPDispatcher) deliver(eventID string) {
+	log := logger.GetLogger().With(
+		zap.String("func", "HTTPDispatcher.deliver"),
+		zap.String("event_id", eventID),
+	)
+	delivery, err := d.deliveries.GetDelivery(eventID)
+	if err != nil {
+		log.Error("Failed to load webhook delivery", zap.Error(err))
+		return
+	}
+	event := delivery.Event
+	log = log.With(
+		zap.String("event_type", string(event.Type)),
+		zap.String("transaction_id", event.Data.ID),
+		zap.String("merchant_id", delivery.MerchantID),
+	)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error("Failed to marshal webhook event", zap.Error(err))
+		d.finish(delivery, err)
+		return
+	}
+	secret := d.merchants[delivery.MerchantID].WebhookSecret
+	if err := d.abort.Err(); err != nil {
+		log.Error("Webhook delivery dead-lettered at shutdown")
+		d.finish(delivery, errors.ErrWebhookDispatcherClosed)
+		return
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Duration(d.cfg.InitialBackoffMillis) * time.Millisecond
+	b.MaxInterval = time.Duration(d.cfg.MaxBackoffMillis) * time.Millisecond
+	b.MaxElapsedTime = 0 // bounded by MaxRetries instead
+
+	err = backoff.Retry(func() error {
+		delivery.Attempts++
+		start := time.Now()
+		statusCode, err := d.post(delivery.URL, secret, event.ID, body)
+		delivery.LastStatusCode = statusCode
+		d.record(delivery, body, statusCode, time.Since(start), err)
+		if err != nil {
+			log.Warn("Webhook delivery attempt failed", zap.Int("attempt", delivery.Attempts), zap.Error(err))
+		}
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(b, uint64(d.cfg.MaxRetries)), d.abort))
+	d.finish(delivery, err)
+	if err != nil {
+		log.Error("Webhook delivery dead-lettered after retries", zap.Int("attempts", delivery.Attempts), zap.Error(err))
+		return
+	}
+	log.Info("Webhook delivered", zap.Int("attempts", delivery.Attempts))
+}
+
+// finish moves the delivery to its terminal state.
+func (d *HTTPDispatcher) finish(delivery *models.WebhookDelivery, err error) {
+	delivery.State = constants.DeliveryDelivered
+	delivery.LastError = ""
+	if err != nil {
+		delivery.State = constants.DeliveryDeadLettered
+		delivery.LastError = err.Error()
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+	if saveErr := d.deliveries.SaveDelivery(delivery); saveErr != nil {
+		logger.GetLogger().Error("Failed to save webhook delivery",
+			zap.String("event_id", delivery.EventID), zap.Error(saveErr))
+	}
+}
+
+// post sends one signed delivery and treats any non-2xx response as a failure.
+func (d *HTTPDispatcher) post(url, secret, eventID string, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(d.abort, d.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, backoff.Permanent(err)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, eventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("merchant endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *HTTPDispatcher) record(delivery *models.WebhookDelivery, body []byte, statusCode int, latency time.Duration, err error) {
+	rec := &models.WebhookDeliveryAttempt{
+		ID:            uuid.NewString(),
+		EventID:       delivery.EventID,
+		EventType:     delivery.Event.Type,
+		TransactionID: delivery.Event.Data.ID,
+		MerchantID:    delivery.MerchantID,
+		URL:           delivery.URL,
+		Attempt:       delivery.Attempts,
+		StatusCode:    statusCode,
+		RequestBody:   string(body),
+		LatencyMillis: latency.Milliseconds(),
+		AttemptedAt:   time.Now().UTC(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if recErr := d.deliveries.RecordAttempt(rec); recErr != nil {
+		logger.GetLogger().Error("Failed to record webhook delivery attempt",
+			zap.String("event_id", delivery.EventID), zap.Error(recErr))
+	}
+}
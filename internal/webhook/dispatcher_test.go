@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"Payment-Gateway/internal/config"
+	"Payment-Gateway/internal/constants"
+	"Payment-Gateway/internal/models"
+	"Payment-Gateway/internal/repository"
+	errors "Payment-Gateway/pkg/error"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func getTestWebhookConfig() *config.WebhookConfig {
+	return &config.WebhookConfig{
+		Enabled:              true,
+		NumWorkers:           1,
+		BufferSize:           10,
+		HTTPTimeoutSeconds:   1,
+		MaxRetries:           3,
+		InitialBackoffMillis: 10,
+		MaxBackoffMillis:     20,
+	}
+}
+
+func waitForAttempts(t *testing.T, repo repository.WebhookDeliveryRepository, eventID string, n int) []*models.WebhookDeliveryAttempt {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		attempts, _ := repo.ListAttemptsByEvent(eventID)
+		if len(attempts) >= n {
+			return attempts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d delivery attempts", n)
+	return nil
+}
+
+func TestHTTPDispatcher_DeliversSignedEvent(t *testing.T) {
+	var gotSignature, gotTimestamp string
+	var gotBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(HeaderSignature)
+		gotTimestamp = r.Header.Get(HeaderTimestamp)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	merchants := map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL, WebhookSecret: "s3cret"}}
+	d := NewHTTPDispatcher(getTestWebhookConfig(), merchants, repo)
+	defer d.Close()
+
+	event := NewTransactionEvent(constants.EventTransactionCreated, models.Transaction{ID: "tx1", MerchantID: "m1"})
+	d.Dispatch(event)
+
+	attempts := waitForAttempts(t, repo, event.ID, 1)
+	if attempts[0].StatusCode != http.StatusNoContent || attempts[0].Error != "" {
+		t.Errorf("unexpected attempt: %+v", attempts[0])
+	}
+	timestamp, _ := strconv.ParseInt(gotTimestamp, 10, 64)
+	if want := Sign("s3cret", timestamp, gotBody); gotSignature != want {
+		t.Errorf("expected signature %s, got %s", want, gotSignature)
+	}
+}
+
+func TestHTTPDispatcher_RetriesUntilSuccess(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	merchants := map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL}}
+	d := NewHTTPDispatcher(getTestWebhookConfig(), merchants, repo)
+	defer d.Close()
+
+	event := NewTransactionEvent(constants.EventTransactionStatusChanged, models.Transaction{ID: "tx1", MerchantID: "m1"})
+	d.Dispatch(event)
+
+	attempts := waitForAttempts(t, repo, event.ID, 3)
+	if attempts[0].StatusCode != http.StatusServiceUnavailable || attempts[0].Attempt != 1 {
+		t.Errorf("unexpected first attempt: %+v", attempts[0])
+	}
+	if attempts[2].StatusCode != http.StatusOK || attempts[2].Attempt != 3 {
+		t.Errorf("unexpected final attempt: %+v", attempts[2])
+	}
+}
+
+func waitForState(t *testing.T, repo repository.WebhookDeliveryRepository, eventID string, state constants.WebhookDeliveryState) *models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if d, err := repo.GetDelivery(eventID); err == nil && d.State == state {
+			return d
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for delivery state %s", state)
+	return nil
+}
+
+func TestHTTPDispatcher_DeadLettersAndReplays(t *testing.T) {
+	var healthy atomic.Bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	merchants := map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL}}
+	cfg := getTestWebhookConfig()
+	cfg.MaxRetries = 1
+	d := NewHTTPDispatcher(cfg, merchants, repo)
+	defer d.Close()
+
+	event := NewTransactionEvent(constants.EventTransactionStatusChanged, models.Transaction{ID: "tx1", MerchantID: "m1"})
+	d.Dispatch(event)
+
+	dead := waitForState(t, repo, event.ID, constants.DeliveryDeadLettered)
+	if dead.Attempts != 2 || dead.LastStatusCode != http.StatusInternalServerError {
+		t.Errorf("unexpected dead-lettered delivery: %+v", dead)
+	}
+
+	healthy.Store(true)
+	if err := d.Replay(event.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	delivered := waitForState(t, repo, event.ID, constants.DeliveryDelivered)
+	if delivered.Attempts != 3 {
+		t.Errorf("expected attempt numbering to continue to 3, got %d", delivered.Attempts)
+	}
+	attempts, _ := repo.ListAttemptsByEvent(event.ID)
+	if len(attempts) != 3 || attempts[2].StatusCode != http.StatusOK || attempts[2].RequestBody == "" {
+		t.Errorf("unexpected attempt log: %+v", attempts)
+	}
+}
+
+func TestHTTPDispatcher_ReplayUnknownEvent(t *testing.T) {
+	d := NewHTTPDispatcher(getTestWebhookConfig(), nil, repository.NewInMemoryWebhookDeliveryRepository())
+	defer d.Close()
+	if err := d.Replay("missing"); err != errors.ErrWebhookDeliveryNotFound {
+		t.Errorf("expected ErrWebhookDeliveryNotFound, got %v", err)
+	}
+}
+
+// slowDeliveries widens the window between reading a delivery and saving it.
+type slowDeliveries struct {
+	repository.WebhookDeliveryRepository
+}
+
+func (r slowDeliveries) GetDelivery(eventID string) (*models.WebhookDelivery, error) {
+	delivery, err := r.WebhookDeliveryRepository.GetDelivery(eventID)
+	time.Sleep(5 * time.Millisecond)
+	return delivery, err
+}
+
+func TestHTTPDispatcher_ConcurrentReplaysDeliverOnce(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError) // dead-letters the first delivery
+			return
+		}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	cfg := getTestWebhookConfig()
+	cfg.NumWorkers, cfg.MaxRetries = 2, 0
+	d := NewHTTPDispatcher(cfg, map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL}}, slowDeliveries{repo})
+	defer d.Close()
+	defer close(release)
+
+	event := NewTransactionEvent(constants.EventTransactionStatusChanged, models.Transaction{ID: "tx1", MerchantID: "m1"})
+	d.Dispatch(event)
+	waitForState(t, repo, event.ID, constants.DeliveryDeadLettered)
+
+	var wg sync.WaitGroup
+	var queued atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if d.Replay(event.ID) == nil {
+				queued.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if queued.Load() != 1 {
+		t.Errorf("expected exactly one replay to be queued, got %d", queued.Load())
+	}
+}
+
+func TestHTTPDispatcher_ShutdownDrainsQueue(t *testing.T) {
+	var delivered atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		delivered.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	d := NewHTTPDispatcher(getTestWebhookConfig(), map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL}}, repo)
+	for i := 0; i < 5; i++ {
+		d.Dispatch(NewTransactionEvent(constants.EventTransactionCreated, models.Transaction{ID: "tx1", MerchantID: "m1"}))
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if delivered.Load() != 5 {
+		t.Errorf("expected the queued events to be delivered before shutdown returned, got %d", delivered.Load())
+	}
+
+	// Events dispatched or replayed after shutdown are dead-lettered, not sent
+	late := NewTransactionEvent(constants.EventTransactionCreated, models.Transaction{ID: "tx2", MerchantID: "m1"})
+	d.Dispatch(late)
+	if got, _ := repo.GetDelivery(late.ID); got.State != constants.DeliveryDeadLettered {
+		t.Errorf("expected a dead-lettered delivery, got %+v", got)
+	}
+	if err := d.Replay(late.ID); err != errors.ErrWebhookDispatcherClosed {
+		t.Errorf("expected ErrWebhookDispatcherClosed, got %v", err)
+	}
+}
+
+func TestHTTPDispatcher_ShutdownDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	repo := repository.NewInMemoryWebhookDeliveryRepository()
+	cfg := getTestWebhookConfig()
+	cfg.InitialBackoffMillis, cfg.MaxBackoffMillis = 60000, 60000
+	d := NewHTTPDispatcher(cfg, map[string]config.MerchantConfig{"m1": {WebhookURL: ts.URL}}, repo)
+	event := NewTransactionEvent(constants.EventTransactionCreated, models.Transaction{ID: "tx1", MerchantID: "m1"})
+	d.Dispatch(event)
+	waitForAttempts(t, repo, event.ID, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline to cut the retries short, got %v", err)
+	}
+	if got, _ := repo.GetDelivery(event.ID); got.State != constants.DeliveryDeadLettered {
+		t.Errorf("expected the retrying delivery to be dead-lettered, got %+v", got)
+	}
+}
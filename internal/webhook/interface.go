@@ -0,0 +1,9 @@
+package webhook
+
+import "Payment-Gateway/internal/models"
+
+// Dispatcher delivers transaction events to merchant webhook endpoints.
+type Dispatcher interface {
+	Dispatch(event models.WebhookEvent)
+	Replay(eventID string) error
+}
@@ -14,7 +14,7 @@ import (
 
 // --- Load Test Parameters ---
 const (
-	baseURL             = "http://localhost:8000"
+	baseURL             = "http://localhost:8000/v1"
 	numTransactions     = 1000
 	concurrentClients   = 10
 	callbackDelayMillis = 100 // max random delay in milliseconds
@@ -32,13 +32,14 @@ type CallbackRequest struct {
 	Amount        float64 `json:"amount" xml:"Amount"`
 	Currency      string  `json:"currency" xml:"Currency"`
 	Status        string  `json:"status" xml:"Status"`
+	Timestamp     string  `json:"timestamp" xml:"Timestamp"`
 }
 
 // --- Helper Functions ---
 func sendTransaction(t *testing.T) (string, error) {
 	payload := TransactionRequest{
 		AccountID: fmt.Sprintf("acc-%d", rand.Intn(1000)),
-		Amount:    rand.Float64() * 100,
+		Amount:    float64(rand.Intn(10000)+1) / 100,
 	}
 	body, _ := json.Marshal(payload)
 	resp, err := http.Post(fmt.Sprintf("%s/deposit", baseURL), "application/json", bytes.NewReader(body))
@@ -67,6 +68,7 @@ func sendCallback(t *testing.T, gateway string, transactionID string) {
 		Amount:        rand.Float64() * 100,
 		Currency:      "USD",
 		Status:        "SUCCESS",
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
 	}
 	if gateway == "gatewayA" {
 		body, _ := json.Marshal(payload)
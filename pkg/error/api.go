@@ -0,0 +1,123 @@
+package error
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// APIError is the error body returned by every HTTP endpoint. Code is stable
+// and safe for clients to branch on; Message is human readable.
+type APIError struct {
+	Code      string        `json:"code" xml:"Code"`
+	Message   string        `json:"message" xml:"Message"`
+	Retryable bool          `json:"retryable" xml:"Retryable"`
+	Details   []ErrorDetail `json:"details,omitempty" xml:"Details>Detail,omitempty"`
+	RequestID string        `json:"request_id,omitempty" xml:"RequestID,omitempty"`
+	Status    int           `json:"-" xml:"-"`
+}
+
+// ErrorDetail describes a problem with one request field.
+type ErrorDetail struct {
+	Field  string `json:"field" xml:"Field"`
+	Reason string `json:"reason" xml:"Reason"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+type errorSpec struct {
+	code      string
+	status    int
+	retryable bool
+}
+
+// errorSpecs maps sentinel errors to their API code and HTTP status. Order
+// matters: the first sentinel matched with errors.Is wins.
+var errorSpecs = []struct {
+	err  error
+	spec errorSpec
+}{
+	{ErrInvalidRequest, errorSpec{"invalid_request", http.StatusBadRequest, false}},
+	{ErrRequestTooLarge, errorSpec{"request_too_large", http.StatusRequestEntityTooLarge, false}},
+	{ErrValidationFailed, errorSpec{"validation_failed", http.StatusUnprocessableEntity, false}},
+	{ErrCallbackInvalid, errorSpec{"invalid_callback", http.StatusBadRequest, false}},
+	{ErrMissingCallbackTimestamp, errorSpec{"missing_callback_timestamp", http.StatusBadRequest, false}},
+	{ErrInvalidCallbackTimestamp, errorSpec{"invalid_callback_timestamp", http.StatusBadRequest, false}},
+	{ErrCallbackOutsideWindow, errorSpec{"callback_outside_window", http.StatusUnprocessableEntity, false}},
+	{ErrMissingRequiredFields, errorSpec{"missing_required_fields", http.StatusUnprocessableEntity, false}},
+	{ErrMissingTransactionID, errorSpec{"missing_transaction_id", http.StatusUnprocessableEntity, false}},
+	{ErrMissingGatewayRef, errorSpec{"missing_gateway_ref", http.StatusUnprocessableEntity, false}},
+	{ErrMissingAmount, errorSpec{"missing_amount", http.StatusUnprocessableEntity, false}},
+	{ErrMissingCurrency, errorSpec{"missing_currency", http.StatusUnprocessableEntity, false}},
+	{ErrInvalidAmount, errorSpec{"invalid_amount", http.StatusUnprocessableEntity, false}},
+	{ErrAmountMustBePositive, errorSpec{"invalid_amount", http.StatusUnprocessableEntity, false}},
+	{ErrInvalidAccount, errorSpec{"invalid_account", http.StatusUnprocessableEntity, false}},
+	{ErrAccountRequired, errorSpec{"invalid_account", http.StatusUnprocessableEntity, false}},
+	{ErrInvalidTransactionData, errorSpec{"invalid_transaction", http.StatusUnprocessableEntity, false}},
+	{ErrUnauthorized, errorSpec{"unauthorized", http.StatusUnauthorized, false}},
+	{ErrForbidden, errorSpec{"forbidden", http.StatusForbidden, false}},
+	{ErrTransactionNotFound, errorSpec{"transaction_not_found", http.StatusNotFound, false}},
+	{ErrWebhookDeliveryNotFound, errorSpec{"webhook_delivery_not_found", http.StatusNotFound, false}},
+	{ErrGatewayNotFound, errorSpec{"gateway_not_found", http.StatusNotFound, false}},
+	{ErrTransactionExists, errorSpec{"transaction_exists", http.StatusConflict, false}},
+	{ErrWebhookDeliveryInProgress, errorSpec{"webhook_delivery_in_progress", http.StatusConflict, true}},
+	{ErrRateLimited, errorSpec{"rate_limited", http.StatusTooManyRequests, true}},
+	{ErrPaymentDeclined, errorSpec{"payment_declined", http.StatusPaymentRequired, false}},
+	{ErrProcessingFailed, errorSpec{"gateway_error", http.StatusBadGateway, true}},
+	{ErrGatewayTimeout, errorSpec{"gateway_timeout", http.StatusGatewayTimeout, true}},
+	{context.DeadlineExceeded, errorSpec{"gateway_timeout", http.StatusGatewayTimeout, true}},
+	{ErrGatewayNotAvailable, errorSpec{"gateway_unavailable", http.StatusServiceUnavailable, true}},
+	{ErrNoGatewayAvailable, errorSpec{"no_gateway_available", http.StatusServiceUnavailable, true}},
+	{ErrUnsupportedGateway, errorSpec{"unsupported_gateway", http.StatusServiceUnavailable, false}},
+	{ErrWebhookQueueFull, errorSpec{"webhook_queue_full", http.StatusServiceUnavailable, true}},
+	{ErrWebhookDispatcherClosed, errorSpec{"webhook_dispatcher_closed", http.StatusServiceUnavailable, true}},
+	{ErrWebhooksDisabled, errorSpec{"webhooks_disabled", http.StatusServiceUnavailable, false}},
+}
+
+// FromError converts err into an APIError. Errors that match no sentinel are
+// reported as an opaque internal error so internal details are not leaked.
+func FromError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, m := range errorSpecs {
+		if errors.Is(err, m.err) {
+			return &APIError{
+				Code:      m.spec.code,
+				Message:   m.err.Error(),
+				Retryable: m.spec.retryable,
+				Status:    m.spec.status,
+			}
+		}
+	}
+	return &APIError{
+		Code:    "internal_error",
+		Message: "internal server error",
+		Status:  http.StatusInternalServerError,
+	}
+}
+
+// WithDetails converts err into an APIError carrying field-level details.
+func WithDetails(err error, details ...ErrorDetail) *APIError {
+	apiErr := *FromError(err)
+	apiErr.Details = append(apiErr.Details, details...)
+	return &apiErr
+}
+
+// messageError keeps an adapter-specific message while still matching its
+// sentinel with errors.Is.
+type messageError struct {
+	msg string
+	err error
+}
+
+func (e *messageError) Error() string { return e.msg }
+func (e *messageError) Unwrap() error { return e.err }
+
+// WithMessage returns an error whose text is msg and which wraps sentinel.
+func WithMessage(sentinel error, msg string) error {
+	return &messageError{msg: msg, err: sentinel}
+}
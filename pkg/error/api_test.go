@@ -0,0 +1,49 @@
+package error
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		code      string
+		status    int
+		retryable bool
+	}{
+		{"sentinel", ErrInvalidRequest, "invalid_request", http.StatusBadRequest, false},
+		{"wrapped sentinel", fmt.Errorf("update failed: %w", ErrTransactionNotFound), "transaction_not_found", http.StatusNotFound, false},
+		{"message error", WithMessage(ErrGatewayTimeout, "gateway A timeout"), "gateway_timeout", http.StatusGatewayTimeout, true},
+		{"context deadline", context.DeadlineExceeded, "gateway_timeout", http.StatusGatewayTimeout, true},
+		{"validation", ErrAmountMustBePositive, "invalid_amount", http.StatusUnprocessableEntity, false},
+		{"unavailable", fmt.Errorf("%w: %w", ErrGatewayNotAvailable, errors.New("connection refused")), "gateway_unavailable", http.StatusServiceUnavailable, true},
+		{"unknown", errors.New("boom"), "internal_error", http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromError(tt.err)
+			if got.Code != tt.code || got.Status != tt.status || got.Retryable != tt.retryable {
+				t.Errorf("got %+v, want code=%s status=%d retryable=%v", got, tt.code, tt.status, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestFromError_UnknownDoesNotLeakMessage(t *testing.T) {
+	got := FromError(errors.New("dial tcp 10.0.0.1:5432: secret detail"))
+	if got.Message != "internal server error" {
+		t.Errorf("expected generic message, got %q", got.Message)
+	}
+}
+
+func TestWithMessage_KeepsText(t *testing.T) {
+	err := WithMessage(ErrProcessingFailed, "gateway B failure")
+	if err.Error() != "gateway B failure" || !errors.Is(err, ErrProcessingFailed) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ var (
 	ErrProcessingFailed        = errors.New("gateway processing failed")
 	ErrGatewayNotAvailable     = errors.New("gateway service not available")
 	ErrGatewayTimeout          = errors.New("gateway request timed out")
+	ErrPaymentDeclined         = errors.New("payment declined by gateway")
 	ErrInvalidGatewayConfig    = errors.New("invalid gateway configuration")
 	ErrTransactionNotFound     = errors.New("transaction not found")
 	ErrTransactionExists       = errors.New("transaction already exists")
@@ -26,6 +27,30 @@ var (
 	ErrMissingCurrency       = errors.New("invalid transaction: missing currency")
 	ErrMissingRequiredFields = errors.New("invalid callback: missing required fields")
 
+	// Callback Replay Protection Errors
+	ErrMissingCallbackTimestamp = errors.New("invalid callback: missing timestamp")
+	ErrInvalidCallbackTimestamp = errors.New("invalid callback: malformed timestamp")
+	ErrCallbackOutsideWindow    = errors.New("invalid callback: timestamp outside tolerance window")
+
+	// Request Validation Errors
+	ErrValidationFailed = errors.New("request validation failed")
+	ErrRequestTooLarge  = errors.New("request body too large")
+
+	// Access Control Errors
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+
+	// Webhook Delivery Errors
+	ErrWebhooksDisabled          = errors.New("webhook delivery is disabled")
+	ErrWebhookDeliveryNotFound   = errors.New("webhook delivery not found")
+	ErrWebhookDeliveryInProgress = errors.New("webhook delivery already in progress")
+	ErrWebhookQueueFull          = errors.New("webhook delivery queue is full")
+	ErrWebhookDispatcherClosed   = errors.New("webhook dispatcher is shutting down")
+
+	// Routing Admin Errors
+	ErrGatewayNotFound = errors.New("gateway not found")
+
 	ErrAccountRequired      = errors.New("account is required")
 	ErrAmountMustBePositive = errors.New("amount must be positive")
 )
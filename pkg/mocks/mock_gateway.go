@@ -5,7 +5,8 @@
 package mocks
 
 import (
-	http "net/http"
+	gateway "Payment-Gateway/internal/gateway"
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -35,31 +36,31 @@ func (m *MockPaymentGateway) EXPECT() *MockPaymentGatewayMockRecorder {
 }
 
 // ProcessDeposit mocks base method.
-func (m *MockPaymentGateway) ProcessDeposit(r *http.Request) (interface{}, error) {
+func (m *MockPaymentGateway) ProcessDeposit(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessDeposit", r)
-	ret0, _ := ret[0].(interface{})
+	ret := m.ctrl.Call(m, "ProcessDeposit", ctx, req)
+	ret0, _ := ret[0].(*gateway.PaymentResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProcessDeposit indicates an expected call of ProcessDeposit.
-func (mr *MockPaymentGatewayMockRecorder) ProcessDeposit(r interface{}) *gomock.Call {
+func (mr *MockPaymentGatewayMockRecorder) ProcessDeposit(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessDeposit", reflect.TypeOf((*MockPaymentGateway)(nil).ProcessDeposit), r)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessDeposit", reflect.TypeOf((*MockPaymentGateway)(nil).ProcessDeposit), ctx, req)
 }
 
 // ProcessWithdrawal mocks base method.
-func (m *MockPaymentGateway) ProcessWithdrawal(r *http.Request) (interface{}, error) {
+func (m *MockPaymentGateway) ProcessWithdrawal(ctx context.Context, req gateway.PaymentRequest) (*gateway.PaymentResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessWithdrawal", r)
-	ret0, _ := ret[0].(interface{})
+	ret := m.ctrl.Call(m, "ProcessWithdrawal", ctx, req)
+	ret0, _ := ret[0].(*gateway.PaymentResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ProcessWithdrawal indicates an expected call of ProcessWithdrawal.
-func (mr *MockPaymentGatewayMockRecorder) ProcessWithdrawal(r interface{}) *gomock.Call {
+func (mr *MockPaymentGatewayMockRecorder) ProcessWithdrawal(ctx, req interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessWithdrawal", reflect.TypeOf((*MockPaymentGateway)(nil).ProcessWithdrawal), r)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessWithdrawal", reflect.TypeOf((*MockPaymentGateway)(nil).ProcessWithdrawal), ctx, req)
 }
@@ -9,7 +9,9 @@ import (
 	dtos "Payment-Gateway/internal/dtos"
 	gateway "Payment-Gateway/internal/gateway"
 	models "Payment-Gateway/internal/models"
+	routing "Payment-Gateway/internal/routing"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -151,6 +153,34 @@ func (m *MockGatewayPool) EXPECT() *MockGatewayPoolMockRecorder {
 	return m.recorder
 }
 
+// Canaries mocks base method.
+func (m *MockGatewayPool) Canaries() []routing.CanaryStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Canaries")
+	ret0, _ := ret[0].([]routing.CanaryStatus)
+	return ret0
+}
+
+// Canaries indicates an expected call of Canaries.
+func (mr *MockGatewayPoolMockRecorder) Canaries() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Canaries", reflect.TypeOf((*MockGatewayPool)(nil).Canaries))
+}
+
+// Explain mocks base method.
+func (m *MockGatewayPool) Explain(route routing.Request) routing.Explanation {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Explain", route)
+	ret0, _ := ret[0].(routing.Explanation)
+	return ret0
+}
+
+// Explain indicates an expected call of Explain.
+func (mr *MockGatewayPoolMockRecorder) Explain(route interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Explain", reflect.TypeOf((*MockGatewayPool)(nil).Explain), route)
+}
+
 // GetAllGateways mocks base method.
 func (m *MockGatewayPool) GetAllGateways() ([]gateway.PaymentGateway, error) {
 	m.ctrl.T.Helper()
@@ -166,19 +196,231 @@ func (mr *MockGatewayPoolMockRecorder) GetAllGateways() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllGateways", reflect.TypeOf((*MockGatewayPool)(nil).GetAllGateways))
 }
 
-// GetRoundRobinGateway mocks base method.
-func (m *MockGatewayPool) GetRoundRobinGateway() (gateway.PaymentGateway, error) {
+// GetFailoverGateway mocks base method.
+func (m *MockGatewayPool) GetFailoverGateway(route routing.Request, tried []gateway.PaymentGateway) (gateway.PaymentGateway, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFailoverGateway", route, tried)
+	ret0, _ := ret[0].(gateway.PaymentGateway)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFailoverGateway indicates an expected call of GetFailoverGateway.
+func (mr *MockGatewayPoolMockRecorder) GetFailoverGateway(route, tried interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFailoverGateway", reflect.TypeOf((*MockGatewayPool)(nil).GetFailoverGateway), route, tried)
+}
+
+// GetGateway mocks base method.
+func (m *MockGatewayPool) GetGateway(route routing.Request) (gateway.PaymentGateway, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetRoundRobinGateway")
+	ret := m.ctrl.Call(m, "GetGateway", route)
 	ret0, _ := ret[0].(gateway.PaymentGateway)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetRoundRobinGateway indicates an expected call of GetRoundRobinGateway.
-func (mr *MockGatewayPoolMockRecorder) GetRoundRobinGateway() *gomock.Call {
+// GetGateway indicates an expected call of GetGateway.
+func (mr *MockGatewayPoolMockRecorder) GetGateway(route interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGateway", reflect.TypeOf((*MockGatewayPool)(nil).GetGateway), route)
+}
+
+// RecordOutcome mocks base method.
+func (m *MockGatewayPool) RecordOutcome(gw gateway.PaymentGateway, outcome routing.Outcome) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RecordOutcome", gw, outcome)
+}
+
+// RecordOutcome indicates an expected call of RecordOutcome.
+func (mr *MockGatewayPoolMockRecorder) RecordOutcome(gw, outcome interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordOutcome", reflect.TypeOf((*MockGatewayPool)(nil).RecordOutcome), gw, outcome)
+}
+
+// ResetCanary mocks base method.
+func (m *MockGatewayPool) ResetCanary(name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetCanary", name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetCanary indicates an expected call of ResetCanary.
+func (mr *MockGatewayPoolMockRecorder) ResetCanary(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetCanary", reflect.TypeOf((*MockGatewayPool)(nil).ResetCanary), name)
+}
+
+// MockGatewayAdmin is a mock of GatewayAdmin interface.
+type MockGatewayAdmin struct {
+	ctrl     *gomock.Controller
+	recorder *MockGatewayAdminMockRecorder
+}
+
+// MockGatewayAdminMockRecorder is the mock recorder for MockGatewayAdmin.
+type MockGatewayAdminMockRecorder struct {
+	mock *MockGatewayAdmin
+}
+
+// NewMockGatewayAdmin creates a new mock instance.
+func NewMockGatewayAdmin(ctrl *gomock.Controller) *MockGatewayAdmin {
+	mock := &MockGatewayAdmin{ctrl: ctrl}
+	mock.recorder = &MockGatewayAdminMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGatewayAdmin) EXPECT() *MockGatewayAdminMockRecorder {
+	return m.recorder
+}
+
+// Disable mocks base method.
+func (m *MockGatewayAdmin) Disable(name, reason string) (models.GatewayStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Disable", name, reason)
+	ret0, _ := ret[0].(models.GatewayStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Disable indicates an expected call of Disable.
+func (mr *MockGatewayAdminMockRecorder) Disable(name, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disable", reflect.TypeOf((*MockGatewayAdmin)(nil).Disable), name, reason)
+}
+
+// Drain mocks base method.
+func (m *MockGatewayAdmin) Drain(name, reason string) (models.GatewayStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drain", name, reason)
+	ret0, _ := ret[0].(models.GatewayStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Drain indicates an expected call of Drain.
+func (mr *MockGatewayAdminMockRecorder) Drain(name, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockGatewayAdmin)(nil).Drain), name, reason)
+}
+
+// Enable mocks base method.
+func (m *MockGatewayAdmin) Enable(name string) (models.GatewayStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Enable", name)
+	ret0, _ := ret[0].(models.GatewayStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Enable indicates an expected call of Enable.
+func (mr *MockGatewayAdminMockRecorder) Enable(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enable", reflect.TypeOf((*MockGatewayAdmin)(nil).Enable), name)
+}
+
+// Status mocks base method.
+func (m *MockGatewayAdmin) Status(name string) (models.GatewayStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status", name)
+	ret0, _ := ret[0].(models.GatewayStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockGatewayAdminMockRecorder) Status(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockGatewayAdmin)(nil).Status), name)
+}
+
+// Statuses mocks base method.
+func (m *MockGatewayAdmin) Statuses() []models.GatewayStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Statuses")
+	ret0, _ := ret[0].([]models.GatewayStatus)
+	return ret0
+}
+
+// Statuses indicates an expected call of Statuses.
+func (mr *MockGatewayAdminMockRecorder) Statuses() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRoundRobinGateway", reflect.TypeOf((*MockGatewayPool)(nil).GetRoundRobinGateway))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Statuses", reflect.TypeOf((*MockGatewayAdmin)(nil).Statuses))
+}
+
+// MockShadowReporter is a mock of ShadowReporter interface.
+type MockShadowReporter struct {
+	ctrl     *gomock.Controller
+	recorder *MockShadowReporterMockRecorder
+}
+
+// MockShadowReporterMockRecorder is the mock recorder for MockShadowReporter.
+type MockShadowReporterMockRecorder struct {
+	mock *MockShadowReporter
+}
+
+// NewMockShadowReporter creates a new mock instance.
+func NewMockShadowReporter(ctrl *gomock.Controller) *MockShadowReporter {
+	mock := &MockShadowReporter{ctrl: ctrl}
+	mock.recorder = &MockShadowReporterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockShadowReporter) EXPECT() *MockShadowReporterMockRecorder {
+	return m.recorder
+}
+
+// ShadowReports mocks base method.
+func (m *MockShadowReporter) ShadowReports() []models.ShadowReport {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ShadowReports")
+	ret0, _ := ret[0].([]models.ShadowReport)
+	return ret0
+}
+
+// ShadowReports indicates an expected call of ShadowReports.
+func (mr *MockShadowReporterMockRecorder) ShadowReports() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ShadowReports", reflect.TypeOf((*MockShadowReporter)(nil).ShadowReports))
+}
+
+// MockGatewayHealth is a mock of GatewayHealth interface.
+type MockGatewayHealth struct {
+	ctrl     *gomock.Controller
+	recorder *MockGatewayHealthMockRecorder
+}
+
+// MockGatewayHealthMockRecorder is the mock recorder for MockGatewayHealth.
+type MockGatewayHealthMockRecorder struct {
+	mock *MockGatewayHealth
+}
+
+// NewMockGatewayHealth creates a new mock instance.
+func NewMockGatewayHealth(ctrl *gomock.Controller) *MockGatewayHealth {
+	mock := &MockGatewayHealth{ctrl: ctrl}
+	mock.recorder = &MockGatewayHealthMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockGatewayHealth) EXPECT() *MockGatewayHealthMockRecorder {
+	return m.recorder
+}
+
+// Healthy mocks base method.
+func (m *MockGatewayHealth) Healthy(gw gateway.PaymentGateway) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Healthy", gw)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Healthy indicates an expected call of Healthy.
+func (mr *MockGatewayHealthMockRecorder) Healthy(gw interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthy", reflect.TypeOf((*MockGatewayHealth)(nil).Healthy), gw)
 }
 
 // MockTransaction is a mock of Transaction interface.
@@ -204,6 +446,21 @@ func (m *MockTransaction) EXPECT() *MockTransactionMockRecorder {
 	return m.recorder
 }
 
+// ApplyCallbackStatus mocks base method.
+func (m *MockTransaction) ApplyCallbackStatus(id string, status constants.TransactionStatus, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyCallbackStatus", id, status, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyCallbackStatus indicates an expected call of ApplyCallbackStatus.
+func (mr *MockTransactionMockRecorder) ApplyCallbackStatus(id, status, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyCallbackStatus", reflect.TypeOf((*MockTransaction)(nil).ApplyCallbackStatus), id, status, at)
+}
+
 // CreateAndProcessDeposit mocks base method.
 func (m *MockTransaction) CreateAndProcessDeposit(req *models.DepositRequest) (*models.Transaction, error) {
 	m.ctrl.T.Helper()
@@ -247,3 +504,86 @@ func (mr *MockTransactionMockRecorder) UpdateStatus(id, status interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockTransaction)(nil).UpdateStatus), id, status)
 }
+
+// MockWebhookDeliveries is a mock of WebhookDeliveries interface.
+type MockWebhookDeliveries struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookDeliveriesMockRecorder
+}
+
+// MockWebhookDeliveriesMockRecorder is the mock recorder for MockWebhookDeliveries.
+type MockWebhookDeliveriesMockRecorder struct {
+	mock *MockWebhookDeliveries
+}
+
+// NewMockWebhookDeliveries creates a new mock instance.
+func NewMockWebhookDeliveries(ctrl *gomock.Controller) *MockWebhookDeliveries {
+	mock := &MockWebhookDeliveries{ctrl: ctrl}
+	mock.recorder = &MockWebhookDeliveriesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookDeliveries) EXPECT() *MockWebhookDeliveriesMockRecorder {
+	return m.recorder
+}
+
+// GetDelivery mocks base method.
+func (m *MockWebhookDeliveries) GetDelivery(eventID string) (*models.WebhookDelivery, []*models.WebhookDeliveryAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelivery", eventID)
+	ret0, _ := ret[0].(*models.WebhookDelivery)
+	ret1, _ := ret[1].([]*models.WebhookDeliveryAttempt)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetDelivery indicates an expected call of GetDelivery.
+func (mr *MockWebhookDeliveriesMockRecorder) GetDelivery(eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockWebhookDeliveries)(nil).GetDelivery), eventID)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhookDeliveries) ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", filter)
+	ret0, _ := ret[0].([]*models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhookDeliveriesMockRecorder) ListDeliveries(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhookDeliveries)(nil).ListDeliveries), filter)
+}
+
+// Replay mocks base method.
+func (m *MockWebhookDeliveries) Replay(eventID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", eventID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockWebhookDeliveriesMockRecorder) Replay(eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockWebhookDeliveries)(nil).Replay), eventID)
+}
+
+// ReplayMatching mocks base method.
+func (m *MockWebhookDeliveries) ReplayMatching(filter models.WebhookDeliveryFilter) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplayMatching", filter)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReplayMatching indicates an expected call of ReplayMatching.
+func (mr *MockWebhookDeliveriesMockRecorder) ReplayMatching(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayMatching", reflect.TypeOf((*MockWebhookDeliveries)(nil).ReplayMatching), filter)
+}
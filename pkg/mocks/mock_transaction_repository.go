@@ -8,6 +8,7 @@ import (
 	constants "Payment-Gateway/internal/constants"
 	models "Payment-Gateway/internal/models"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -35,6 +36,20 @@ func (m *MockTransactionRepository) EXPECT() *MockTransactionRepositoryMockRecor
 	return m.recorder
 }
 
+// AddGatewayAttempt mocks base method.
+func (m *MockTransactionRepository) AddGatewayAttempt(id string, attempt models.GatewayAttempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddGatewayAttempt", id, attempt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddGatewayAttempt indicates an expected call of AddGatewayAttempt.
+func (mr *MockTransactionRepositoryMockRecorder) AddGatewayAttempt(id, attempt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGatewayAttempt", reflect.TypeOf((*MockTransactionRepository)(nil).AddGatewayAttempt), id, attempt)
+}
+
 // CreateTransaction mocks base method.
 func (m *MockTransactionRepository) CreateTransaction(tx *models.Transaction) error {
 	m.ctrl.T.Helper()
@@ -64,6 +79,20 @@ func (mr *MockTransactionRepositoryMockRecorder) GetTransactionByID(id interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionByID", reflect.TypeOf((*MockTransactionRepository)(nil).GetTransactionByID), id)
 }
 
+// SetGatewayResult mocks base method.
+func (m *MockTransactionRepository) SetGatewayResult(id string, result models.GatewayResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGatewayResult", id, result)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetGatewayResult indicates an expected call of SetGatewayResult.
+func (mr *MockTransactionRepositoryMockRecorder) SetGatewayResult(id, result interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGatewayResult", reflect.TypeOf((*MockTransactionRepository)(nil).SetGatewayResult), id, result)
+}
+
 // UpdateTransactionStatus mocks base method.
 func (m *MockTransactionRepository) UpdateTransactionStatus(id string, status constants.TransactionStatus) error {
 	m.ctrl.T.Helper()
@@ -77,3 +106,33 @@ func (mr *MockTransactionRepositoryMockRecorder) UpdateTransactionStatus(id, sta
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransactionStatus", reflect.TypeOf((*MockTransactionRepository)(nil).UpdateTransactionStatus), id, status)
 }
+
+// UpdateTransactionStatusIfNewer mocks base method.
+func (m *MockTransactionRepository) UpdateTransactionStatusIfNewer(id string, status constants.TransactionStatus, at time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTransactionStatusIfNewer", id, status, at)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTransactionStatusIfNewer indicates an expected call of UpdateTransactionStatusIfNewer.
+func (mr *MockTransactionRepositoryMockRecorder) UpdateTransactionStatusIfNewer(id, status, at interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransactionStatusIfNewer", reflect.TypeOf((*MockTransactionRepository)(nil).UpdateTransactionStatusIfNewer), id, status, at)
+}
+
+// UpdateTransactionStatusUnlessCallbackSince mocks base method.
+func (m *MockTransactionRepository) UpdateTransactionStatusUnlessCallbackSince(id string, status constants.TransactionStatus, sentAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTransactionStatusUnlessCallbackSince", id, status, sentAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTransactionStatusUnlessCallbackSince indicates an expected call of UpdateTransactionStatusUnlessCallbackSince.
+func (mr *MockTransactionRepositoryMockRecorder) UpdateTransactionStatusUnlessCallbackSince(id, status, sentAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTransactionStatusUnlessCallbackSince", reflect.TypeOf((*MockTransactionRepository)(nil).UpdateTransactionStatusUnlessCallbackSince), id, status, sentAt)
+}
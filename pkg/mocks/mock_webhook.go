@@ -0,0 +1,61 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/webhook/interface.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	models "Payment-Gateway/internal/models"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDispatcher is a mock of Dispatcher interface.
+type MockDispatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockDispatcherMockRecorder
+}
+
+// MockDispatcherMockRecorder is the mock recorder for MockDispatcher.
+type MockDispatcherMockRecorder struct {
+	mock *MockDispatcher
+}
+
+// NewMockDispatcher creates a new mock instance.
+func NewMockDispatcher(ctrl *gomock.Controller) *MockDispatcher {
+	mock := &MockDispatcher{ctrl: ctrl}
+	mock.recorder = &MockDispatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDispatcher) EXPECT() *MockDispatcherMockRecorder {
+	return m.recorder
+}
+
+// Dispatch mocks base method.
+func (m *MockDispatcher) Dispatch(event models.WebhookEvent) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Dispatch", event)
+}
+
+// Dispatch indicates an expected call of Dispatch.
+func (mr *MockDispatcherMockRecorder) Dispatch(event interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dispatch", reflect.TypeOf((*MockDispatcher)(nil).Dispatch), event)
+}
+
+// Replay mocks base method.
+func (m *MockDispatcher) Replay(eventID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", eventID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockDispatcherMockRecorder) Replay(eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockDispatcher)(nil).Replay), eventID)
+}
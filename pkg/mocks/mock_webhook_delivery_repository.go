@@ -0,0 +1,108 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/repository/webhook_delivery_repository.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	models "Payment-Gateway/internal/models"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockWebhookDeliveryRepository is a mock of WebhookDeliveryRepository interface.
+type MockWebhookDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWebhookDeliveryRepositoryMockRecorder
+}
+
+// MockWebhookDeliveryRepositoryMockRecorder is the mock recorder for MockWebhookDeliveryRepository.
+type MockWebhookDeliveryRepositoryMockRecorder struct {
+	mock *MockWebhookDeliveryRepository
+}
+
+// NewMockWebhookDeliveryRepository creates a new mock instance.
+func NewMockWebhookDeliveryRepository(ctrl *gomock.Controller) *MockWebhookDeliveryRepository {
+	mock := &MockWebhookDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockWebhookDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWebhookDeliveryRepository) EXPECT() *MockWebhookDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetDelivery mocks base method.
+func (m *MockWebhookDeliveryRepository) GetDelivery(eventID string) (*models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelivery", eventID)
+	ret0, _ := ret[0].(*models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelivery indicates an expected call of GetDelivery.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) GetDelivery(eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).GetDelivery), eventID)
+}
+
+// ListAttemptsByEvent mocks base method.
+func (m *MockWebhookDeliveryRepository) ListAttemptsByEvent(eventID string) ([]*models.WebhookDeliveryAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAttemptsByEvent", eventID)
+	ret0, _ := ret[0].([]*models.WebhookDeliveryAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAttemptsByEvent indicates an expected call of ListAttemptsByEvent.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) ListAttemptsByEvent(eventID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAttemptsByEvent", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).ListAttemptsByEvent), eventID)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockWebhookDeliveryRepository) ListDeliveries(filter models.WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", filter)
+	ret0, _ := ret[0].([]*models.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) ListDeliveries(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).ListDeliveries), filter)
+}
+
+// RecordAttempt mocks base method.
+func (m *MockWebhookDeliveryRepository) RecordAttempt(attempt *models.WebhookDeliveryAttempt) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAttempt", attempt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAttempt indicates an expected call of RecordAttempt.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) RecordAttempt(attempt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAttempt", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).RecordAttempt), attempt)
+}
+
+// SaveDelivery mocks base method.
+func (m *MockWebhookDeliveryRepository) SaveDelivery(delivery *models.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDelivery", delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDelivery indicates an expected call of SaveDelivery.
+func (mr *MockWebhookDeliveryRepositoryMockRecorder) SaveDelivery(delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDelivery", reflect.TypeOf((*MockWebhookDeliveryRepository)(nil).SaveDelivery), delivery)
+}